@@ -0,0 +1,78 @@
+package oauth2
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"io"
+)
+
+// deriveKey will derive a key for the specified purpose from the secret. The
+// label separates keys derived from the same secret for different purposes.
+func deriveKey(secret []byte, label string) []byte {
+	// compute hmac
+	h := hmac.New(sha256.New, secret)
+	_, _ = h.Write([]byte(label))
+
+	return h.Sum(nil)
+}
+
+func newAEAD(secret []byte) (cipher.AEAD, error) {
+	// derive key
+	key := sha256.Sum256(secret)
+
+	// create block cipher
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// seal will encrypt and authenticate the data using a key derived from the
+// specified secret. The returned data is prefixed with the random nonce.
+func seal(secret, data []byte) ([]byte, error) {
+	// create aead
+	aead, err := newAEAD(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	// generate nonce
+	nonce := make([]byte, aead.NonceSize())
+	_, err = io.ReadFull(randSource, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, data, nil), nil
+}
+
+// open will authenticate and decrypt data that has been sealed using the
+// specified secret. It returns the nonce and the plaintext.
+func open(secret, data []byte) ([]byte, []byte, error) {
+	// create aead
+	aead, err := newAEAD(secret)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// check length
+	if len(data) < aead.NonceSize() {
+		return nil, nil, errors.New("ciphertext too short")
+	}
+
+	// split nonce
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+
+	// decrypt data
+	plaintext, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return nonce, plaintext, nil
+}
@@ -0,0 +1,36 @@
+package oauth2
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSealOpen(t *testing.T) {
+	data, err := seal(testSecret, []byte("foo"))
+	assert.NoError(t, err)
+	assert.NotEqual(t, []byte("foo"), data)
+
+	nonce, plaintext, err := open(testSecret, data)
+	assert.NoError(t, err)
+	assert.Len(t, nonce, 12)
+	assert.Equal(t, []byte("foo"), plaintext)
+
+	_, _, err = open([]byte("foo"), data)
+	assert.Error(t, err)
+
+	_, _, err = open(testSecret, data[:4])
+	assert.Error(t, err)
+}
+
+func TestSealError(t *testing.T) {
+	currentSource := randSource
+	randSource = strings.NewReader("")
+
+	data, err := seal(testSecret, []byte("foo"))
+	assert.Error(t, err)
+	assert.Nil(t, data)
+
+	randSource = currentSource
+}
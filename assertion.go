@@ -0,0 +1,79 @@
+package oauth2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// JWTBearerGrantType is the extension grant type used to obtain access tokens
+// by presenting a signed JWT assertion as defined by RFC 7523 section 2.1.
+const JWTBearerGrantType = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+
+// AssertionVerifier verifies JWT bearer assertions issued by a set of trusted
+// issuers. Every trust anchor is a validator that is configured with the
+// issuer, the expected audience (usually the token endpoint) and the accepted
+// algorithms.
+type AssertionVerifier struct {
+	anchors map[string]*Validator
+}
+
+// NewAssertionVerifier creates and returns a new assertion verifier that
+// trusts the issuers of the provided validators.
+func NewAssertionVerifier(anchors ...*Validator) *AssertionVerifier {
+	// prepare verifier
+	verifier := &AssertionVerifier{
+		anchors: map[string]*Validator{},
+	}
+
+	// add anchors
+	for _, anchor := range anchors {
+		verifier.anchors[anchor.config.Issuer] = anchor
+	}
+
+	return verifier
+}
+
+// Verify will verify the specified assertion using the validator of its
+// issuer and return the claims if it is valid. Besides the checks performed
+// by the validator, the assertion must identify its subject.
+func (v *AssertionVerifier) Verify(ctx context.Context, assertion string) (Claims, error) {
+	// split segments
+	s := strings.Split(assertion, ".")
+	if len(s) != 3 {
+		return nil, errors.New("a JWT must have three segments separated by a dot")
+	}
+
+	// decode payload
+	payload, err := b64.DecodeString(s[1])
+	if err != nil {
+		return nil, errors.New("JWT payload is not base64 encoded")
+	}
+
+	// parse claims
+	var unverified Claims
+	err = json.Unmarshal(payload, &unverified)
+	if err != nil {
+		return nil, errors.New("JWT payload is not valid json")
+	}
+
+	// get anchor
+	anchor, ok := v.anchors[unverified.GetString("iss")]
+	if !ok {
+		return nil, errors.New("untrusted JWT issuer")
+	}
+
+	// validate assertion
+	claims, err := anchor.Validate(ctx, assertion)
+	if err != nil {
+		return nil, err
+	}
+
+	// check subject
+	if claims.GetString("sub") == "" {
+		return nil, errors.New("JWT subject missing")
+	}
+
+	return claims, nil
+}
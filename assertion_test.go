@@ -0,0 +1,191 @@
+package oauth2
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/256dpi/oauth2/v2/oauth2test"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestIssuer(key *rsa.PrivateKey) *httptest.Server {
+	var issuer string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":   issuer,
+			"jwks_uri": issuer + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{
+				{
+					"kty": "RSA",
+					"kid": "rsa",
+					"n":   b64.EncodeToString(key.N.Bytes()),
+					"e":   b64.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+				},
+			},
+		})
+	})
+
+	server := httptest.NewServer(mux)
+	issuer = server.URL
+
+	return server
+}
+
+func TestAssertionVerifier(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	issuer := newTestIssuer(key)
+	defer issuer.Close()
+
+	verifier := NewAssertionVerifier(NewValidator(ValidatorConfig{
+		Issuer:   issuer.URL,
+		Audience: "http://auth.server/token",
+	}))
+
+	claims, err := verifier.Verify(context.Background(), signTestJWT(t, "RS256", "rsa", key, Claims{
+		"iss": issuer.URL,
+		"sub": "service1",
+		"aud": "http://auth.server/token",
+		"exp": time.Now().Add(time.Minute).Unix(),
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, "service1", claims.GetString("sub"))
+
+	for _, item := range []struct {
+		assertion string
+		err       string
+	}{
+		{"foo", "a JWT must have three segments separated by a dot"},
+		{"foo.%.bar", "JWT payload is not base64 encoded"},
+		{signTestJWT(t, "RS256", "rsa", key, Claims{
+			"iss": "http://other.issuer",
+			"sub": "service1",
+			"aud": "http://auth.server/token",
+			"exp": time.Now().Add(time.Minute).Unix(),
+		}), "untrusted JWT issuer"},
+		{signTestJWT(t, "RS256", "rsa", key, Claims{
+			"iss": issuer.URL,
+			"sub": "service1",
+			"aud": "http://other.server/token",
+			"exp": time.Now().Add(time.Minute).Unix(),
+		}), "JWT audience mismatch"},
+		{signTestJWT(t, "RS256", "rsa", key, Claims{
+			"iss": issuer.URL,
+			"sub": "service1",
+			"aud": "http://auth.server/token",
+			"exp": time.Now().Add(-time.Minute).Unix(),
+		}), "JWT expired"},
+		{signTestJWT(t, "RS256", "rsa", key, Claims{
+			"iss": issuer.URL,
+			"aud": "http://auth.server/token",
+			"exp": time.Now().Add(time.Minute).Unix(),
+		}), "JWT subject missing"},
+	} {
+		_, err = verifier.Verify(context.Background(), item.assertion)
+		assert.EqualError(t, err, item.err)
+	}
+}
+
+func TestServerJWTBearerGrant(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	issuer := newTestIssuer(key)
+	defer issuer.Close()
+
+	server := newTestServer()
+
+	assertion := signTestJWT(t, "RS256", "rsa", key, Claims{
+		"iss": issuer.URL,
+		"sub": "service1",
+		"aud": "http://auth.server/token",
+		"exp": time.Now().Add(time.Minute).Unix(),
+	})
+
+	tokenRequest := &oauth2test.Request{
+		Method:   "POST",
+		Path:     "/oauth2/token",
+		Username: "client1",
+		Password: "foo",
+		Form: map[string]string{
+			"grant_type": JWTBearerGrantType,
+			"scope":      "foo",
+			"assertion":  assertion,
+		},
+	}
+
+	res := oauth2test.Do(server, tokenRequest)
+	assert.Equal(t, http.StatusBadRequest, res.Status)
+	assert.Equal(t, "invalid_request", res.String("error"))
+
+	server.Config.AssertionVerifier = NewAssertionVerifier(NewValidator(ValidatorConfig{
+		Issuer:   issuer.URL,
+		Audience: "http://auth.server/token",
+	}))
+
+	res = oauth2test.Do(server, tokenRequest)
+	assert.Equal(t, http.StatusOK, res.Status)
+	assert.Equal(t, "bearer", res.String("token_type"))
+	assert.NotEmpty(t, res.String("access_token"))
+	assert.Empty(t, res.String("refresh_token"))
+	assert.Equal(t, "foo", res.String("scope"))
+
+	signature, err := server.tokenKey(res.String("access_token"))
+	assert.NoError(t, err)
+	assert.Equal(t, "service1", server.AccessTokens[signature].Username)
+
+	tokenRequest.Form["assertion"] = ""
+	res = oauth2test.Do(server, tokenRequest)
+	assert.Equal(t, http.StatusBadRequest, res.Status)
+	assert.Equal(t, "invalid_request", res.String("error"))
+	assert.Equal(t, "missing assertion", res.String("error_description"))
+
+	tokenRequest.Form["assertion"] = assertion + "foo"
+	res = oauth2test.Do(server, tokenRequest)
+	assert.Equal(t, http.StatusBadRequest, res.Status)
+	assert.Equal(t, "invalid_grant", res.String("error"))
+	assert.Equal(t, "invalid assertion", res.String("error_description"))
+
+	var events []ServerEvent
+	server.Config.EventHandler = ServerEventHandlerFunc(func(event ServerEvent) {
+		events = append(events, event)
+	})
+
+	issuer.Close()
+	other := signTestJWT(t, "RS256", "other", key, Claims{
+		"iss": issuer.URL,
+		"sub": "service1",
+		"aud": "http://auth.server/token",
+		"exp": time.Now().Add(time.Minute).Unix(),
+	})
+
+	tokenRequest.Form["assertion"] = other
+	res = oauth2test.Do(server, tokenRequest)
+	assert.Equal(t, http.StatusBadRequest, res.Status)
+	assert.Equal(t, "invalid_grant", res.String("error"))
+	assert.Equal(t, "invalid assertion", res.String("error_description"))
+	assert.NotContains(t, res.String("error_description"), issuer.URL)
+	assert.Len(t, events, 1)
+	assert.Equal(t, AuthenticationFailedEvent, events[0].Type)
+	assert.Contains(t, events[0].Reason, "invalid assertion: ")
+	server.Config.EventHandler = nil
+
+	tokenRequest.Form["assertion"] = assertion
+	tokenRequest.Form["scope"] = "baz"
+	res = oauth2test.Do(server, tokenRequest)
+	assert.Equal(t, http.StatusBadRequest, res.Status)
+	assert.Equal(t, "invalid_scope", res.String("error"))
+}
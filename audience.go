@@ -0,0 +1,45 @@
+package oauth2
+
+import "encoding/json"
+
+// Audience is the audience of a token that is encoded as a single string if it
+// has one member and as an array of strings otherwise, as used by the "aud"
+// claim of JWTs and introspection responses.
+type Audience []string
+
+// Contains returns true if the audience contains the specified member.
+func (a Audience) Contains(member string) bool {
+	return containsString(a, member)
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (a Audience) MarshalJSON() ([]byte, error) {
+	// encode single member as string
+	if len(a) == 1 {
+		return json.Marshal(a[0])
+	}
+
+	return json.Marshal([]string(a))
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	// decode string
+	var str string
+	if json.Unmarshal(data, &str) == nil {
+		*a = Audience{str}
+		return nil
+	}
+
+	// decode list
+	var list []string
+	err := json.Unmarshal(data, &list)
+	if err != nil {
+		return err
+	}
+
+	// set list
+	*a = list
+
+	return nil
+}
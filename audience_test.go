@@ -0,0 +1,44 @@
+package oauth2
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAudienceContains(t *testing.T) {
+	assert.True(t, Audience{"foo", "bar"}.Contains("bar"))
+	assert.False(t, Audience{"foo"}.Contains("bar"))
+	assert.False(t, Audience(nil).Contains("foo"))
+}
+
+func TestAudienceMarshalJSON(t *testing.T) {
+	buf, err := json.Marshal(Audience{"foo"})
+	assert.NoError(t, err)
+	assert.Equal(t, `"foo"`, string(buf))
+
+	buf, err = json.Marshal(Audience{"foo", "bar"})
+	assert.NoError(t, err)
+	assert.Equal(t, `["foo","bar"]`, string(buf))
+
+	buf, err = json.Marshal(struct {
+		Audience Audience `json:"aud,omitempty"`
+	}{})
+	assert.NoError(t, err)
+	assert.Equal(t, `{}`, string(buf))
+}
+
+func TestAudienceUnmarshalJSON(t *testing.T) {
+	var a Audience
+	err := a.UnmarshalJSON([]byte(`"foo"`))
+	assert.NoError(t, err)
+	assert.Equal(t, Audience{"foo"}, a)
+
+	err = a.UnmarshalJSON([]byte(`["foo","bar"]`))
+	assert.NoError(t, err)
+	assert.Equal(t, Audience{"foo", "bar"}, a)
+
+	err = a.UnmarshalJSON([]byte(`1`))
+	assert.Error(t, err)
+}
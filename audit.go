@@ -0,0 +1,83 @@
+package oauth2
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// AuditLog is a server event handler that writes events as newline delimited
+// JSON to a writer.
+type AuditLog struct {
+	writer  io.Writer
+	rotate  func(written int64) io.Writer
+	written int64
+	err     error
+	mutex   sync.Mutex
+}
+
+// NewAuditLog creates and returns a new audit log that writes to the provided
+// writer. If set, the rotate hook is called before every event with the number
+// of bytes written to the current writer. It may return a new writer to
+// continue writing to or nil to keep the current writer.
+func NewAuditLog(w io.Writer, rotate func(written int64) io.Writer) *AuditLog {
+	return &AuditLog{
+		writer: w,
+		rotate: rotate,
+	}
+}
+
+// HandleEvent implements the ServerEventHandler interface.
+func (l *AuditLog) HandleEvent(event ServerEvent) {
+	// encode event
+	data, err := json.Marshal(event)
+	if err != nil {
+		l.mutex.Lock()
+		l.err = err
+		l.mutex.Unlock()
+		return
+	}
+
+	// acquire mutex
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	// rotate writer if requested
+	if l.rotate != nil {
+		if w := l.rotate(l.written); w != nil {
+			l.writer = w
+			l.written = 0
+		}
+	}
+
+	// write line
+	n, err := l.writer.Write(append(data, '\n'))
+	l.written += int64(n)
+	if err != nil {
+		l.err = err
+	}
+}
+
+// Rotate will replace the writer and return the previous writer so it can be
+// closed or archived.
+func (l *AuditLog) Rotate(w io.Writer) io.Writer {
+	// acquire mutex
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	// swap writer
+	previous := l.writer
+	l.writer = w
+	l.written = 0
+
+	return previous
+}
+
+// Err returns the last error encountered while writing an event.
+func (l *AuditLog) Err() error {
+	// acquire mutex
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.err
+}
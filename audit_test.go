@@ -0,0 +1,97 @@
+package oauth2
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/256dpi/oauth2/v2/oauth2test"
+)
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("failed")
+}
+
+func TestAuditLog(t *testing.T) {
+	var buf1, buf2 bytes.Buffer
+
+	log := NewAuditLog(&buf1, func(written int64) io.Writer {
+		if written > 0 && buf2.Len() == 0 {
+			return &buf2
+		}
+		return nil
+	})
+
+	server := newTestServer()
+	server.Config.EventHandler = log
+
+	res := oauth2test.Do(server, &oauth2test.Request{
+		Method:   "POST",
+		Path:     "/oauth2/token",
+		Username: "client1",
+		Password: "foo",
+		Form: map[string]string{
+			"grant_type": ClientCredentialsGrantType,
+			"scope":      "foo",
+		},
+	})
+	assert.Equal(t, http.StatusOK, res.Status)
+
+	oauth2test.Do(server, &oauth2test.Request{
+		Method:   "POST",
+		Path:     "/oauth2/revoke",
+		Username: "client1",
+		Password: "foo",
+		Form: map[string]string{
+			"token": res.String("access_token"),
+		},
+	})
+
+	oauth2test.Do(server, &oauth2test.Request{
+		Method:   "POST",
+		Path:     "/oauth2/token",
+		Username: "client1",
+		Password: "bar",
+		Form: map[string]string{
+			"grant_type": ClientCredentialsGrantType,
+		},
+	})
+
+	assert.NoError(t, log.Err())
+
+	lines := strings.Split(strings.TrimSpace(buf1.String()), "\n")
+	assert.Len(t, lines, 1)
+
+	var event ServerEvent
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &event))
+	assert.Equal(t, TokenIssuedEvent, event.Type)
+	assert.Equal(t, "client1", event.ClientID)
+	assert.Equal(t, AccessToken, event.TokenType)
+	assert.Equal(t, Scope{"foo"}, event.Scope)
+	assert.False(t, event.Time.IsZero())
+
+	lines = strings.Split(strings.TrimSpace(buf2.String()), "\n")
+	assert.Len(t, lines, 3)
+
+	var types []ServerEventType
+	for _, line := range lines {
+		var event ServerEvent
+		assert.NoError(t, json.Unmarshal([]byte(line), &event))
+		types = append(types, event.Type)
+	}
+	assert.Equal(t, []ServerEventType{TokenIssuedEvent, TokenRevokedEvent, AuthenticationFailedEvent}, types)
+
+	previous := log.Rotate(failingWriter{})
+	assert.Equal(t, &buf2, previous)
+
+	log.HandleEvent(ServerEvent{Type: TokenIssuedEvent})
+	assert.EqualError(t, log.Err(), "failed")
+}
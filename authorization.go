@@ -1,8 +1,10 @@
 package oauth2
 
 import (
+	"errors"
 	"net/http"
 	"net/url"
+	"strings"
 )
 
 // A AuthorizationRequest is typically returned by ParseAuthorizationRequest and
@@ -13,6 +15,19 @@ type AuthorizationRequest struct {
 	ClientID     string
 	RedirectURI  string
 	State        string
+	LoginHint    string
+	IDTokenHint  string
+
+	// The preferred languages of the end-user for the user interface and for
+	// returned claims in order of preference (e.g. "de-CH", "en").
+	UILocales     []string
+	ClaimsLocales []string
+
+	CodeChallenge       string
+	CodeChallengeMethod string
+
+	// The requested fine-grained permissions (RFC 9396).
+	AuthorizationDetails []AuthorizationDetail
 }
 
 // ParseAuthorizationRequest parses an incoming request and returns an
@@ -32,6 +47,17 @@ func ParseAuthorizationRequest(r *http.Request) (*AuthorizationRequest, error) {
 
 	// get state
 	state := r.Form.Get("state")
+	if containsControl(state) {
+		return nil, InvalidRequest("invalid state")
+	}
+
+	// get login hint and id token hint
+	loginHint := r.Form.Get("login_hint")
+	idTokenHint := r.Form.Get("id_token_hint")
+
+	// get locales
+	uiLocales := parseLocales(r.Form.Get("ui_locales"))
+	claimsLocales := parseLocales(r.Form.Get("claims_locales"))
 
 	// get response type
 	responseType := r.Form.Get("response_type")
@@ -60,11 +86,189 @@ func ParseAuthorizationRequest(r *http.Request) (*AuthorizationRequest, error) {
 		return nil, InvalidRequest("invalid redirect URI")
 	}
 
+	// get code challenge and method
+	codeChallenge := r.Form.Get("code_challenge")
+	codeChallengeMethod := r.Form.Get("code_challenge_method")
+
+	// validate code challenge and method if present
+	if codeChallenge != "" {
+		if !ValidCodeVerifier(codeChallenge) {
+			return nil, InvalidRequest("invalid code challenge")
+		}
+
+		// set default method
+		if codeChallengeMethod == "" {
+			codeChallengeMethod = PlainCodeChallengeMethod
+		}
+
+		// check method
+		if !KnownCodeChallengeMethod(codeChallengeMethod) {
+			return nil, InvalidRequest("unsupported code challenge method")
+		}
+	} else if codeChallengeMethod != "" {
+		return nil, InvalidRequest("missing code challenge")
+	}
+
+	// get authorization details
+	authorizationDetails, err := ParseAuthorizationDetails(r.Form.Get("authorization_details"))
+	if err != nil {
+		return nil, err
+	}
+
 	return &AuthorizationRequest{
-		ResponseType: responseType,
-		Scope:        scope,
-		ClientID:     clientID,
-		RedirectURI:  redirectURIString,
-		State:        state,
+		ResponseType:         responseType,
+		Scope:                scope,
+		ClientID:             clientID,
+		RedirectURI:          redirectURIString,
+		State:                state,
+		LoginHint:            loginHint,
+		IDTokenHint:          idTokenHint,
+		UILocales:            uiLocales,
+		ClaimsLocales:        claimsLocales,
+		CodeChallenge:        codeChallenge,
+		CodeChallengeMethod:  codeChallengeMethod,
+		AuthorizationDetails: authorizationDetails,
 	}, nil
 }
+
+// Locale returns the first preferred UI locale that is supported. A supported
+// base language (e.g. "de") also matches a regional preference (e.g. "de-CH").
+// If no preference is supported, an empty string is returned.
+func (r *AuthorizationRequest) Locale(supported ...string) string {
+	for _, locale := range r.UILocales {
+		// check exact match
+		for _, item := range supported {
+			if strings.EqualFold(locale, item) {
+				return item
+			}
+		}
+
+		// check base language
+		base := strings.SplitN(locale, "-", 2)[0]
+		for _, item := range supported {
+			if strings.EqualFold(base, item) {
+				return item
+			}
+		}
+	}
+
+	return ""
+}
+
+// RequestContext returns the context of the authorization request that is used
+// to redirect errors and responses.
+func (r *AuthorizationRequest) RequestContext() *RequestContext {
+	return &RequestContext{
+		RedirectURI: r.RedirectURI,
+		State:       r.State,
+		UseFragment: r.ResponseType == TokenResponseType,
+	}
+}
+
+// RequestContext redirects errors and responses of an authorization request
+// to the redirect URI with the state applied. Errors are added to the fragment
+// for the implicit grant (token response type) and to the query otherwise.
+type RequestContext struct {
+	RedirectURI string
+	State       string
+	UseFragment bool
+
+	// If enabled, redirects only carry the parameters listed in
+	// RedirectParameters, all other parameters are dropped.
+	Strict bool
+
+	// The redactor applied to the description of written errors. If nil, the
+	// description is written as is.
+	Redactor *Redactor
+}
+
+// Error returns the specified error marked to be redirected. Errors that are
+// not an Error are wrapped in a server error.
+func (c *RequestContext) Error(err error) *Error {
+	// ensure complex error
+	var anError *Error
+	if !errors.As(err, &anError) {
+		anError = ServerError("").SetCause(err)
+	}
+
+	return anError.SetRedirect(c.RedirectURI, c.State, c.UseFragment)
+}
+
+// Code returns a code response for the specified code.
+func (c *RequestContext) Code(code string) *CodeResponse {
+	return NewCodeResponse(code, c.RedirectURI, c.State)
+}
+
+// Token returns the specified token response marked to be redirected.
+func (c *RequestContext) Token(res *TokenResponse) *TokenResponse {
+	return res.SetRedirect(c.RedirectURI, c.State)
+}
+
+// WriteError will write the specified error as a redirect (see Error).
+func (c *RequestContext) WriteError(w http.ResponseWriter, err error) error {
+	// get error
+	anError := c.Error(err)
+
+	// redact description if configured
+	if c.Redactor != nil {
+		anError = anError.Redact(c.Redactor)
+	}
+
+	// write error if not strict or not redirected
+	if !c.Strict || anError.RedirectURI == "" {
+		return WriteError(w, anError)
+	}
+
+	// add headers
+	for k, v := range anError.Headers {
+		w.Header().Set(k, v)
+	}
+
+	return WriteRedirectStatus(w, anError.RedirectURI, FilterRedirectParameters(anError.Map()), anError.UseFragment, anError.RedirectStatus)
+}
+
+// WriteCode will write a code response for the specified code.
+func (c *RequestContext) WriteCode(w http.ResponseWriter, code string) error {
+	// get response
+	res := c.Code(code)
+
+	// write response if not strict
+	if !c.Strict {
+		return WriteCodeResponse(w, res)
+	}
+
+	return WriteRedirectStatus(w, res.RedirectURI, FilterRedirectParameters(res.Map()), false, res.RedirectStatus)
+}
+
+// WriteToken will write the specified token response as a redirect.
+func (c *RequestContext) WriteToken(w http.ResponseWriter, res *TokenResponse) error {
+	// mark response
+	res = c.Token(res)
+
+	// write response if not strict
+	if !c.Strict {
+		return WriteTokenResponse(w, res)
+	}
+
+	return WriteRedirectStatus(w, res.RedirectURI, FilterRedirectParameters(res.Map()), true, res.RedirectStatus)
+}
+
+func parseLocales(str string) []string {
+	// prepare list
+	var locales []string
+
+	// collect valid language tags
+	for _, tag := range strings.Fields(str) {
+		valid := !strings.HasPrefix(tag, "-") && !strings.HasSuffix(tag, "-")
+		for _, c := range tag {
+			if !(c == '-' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z') {
+				valid = false
+			}
+		}
+		if valid {
+			locales = append(locales, tag)
+		}
+	}
+
+	return locales
+}
@@ -1,7 +1,9 @@
 package oauth2
 
 import (
+	"errors"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -21,15 +23,23 @@ func TestParseAuthorizationRequestMinimal(t *testing.T) {
 	assert.Equal(t, "foo", req.ClientID)
 	assert.Equal(t, "http://example.com", req.RedirectURI)
 	assert.Equal(t, "", req.State)
+	assert.Equal(t, "", req.LoginHint)
+	assert.Equal(t, "", req.IDTokenHint)
+	assert.Nil(t, req.UILocales)
+	assert.Nil(t, req.ClaimsLocales)
 }
 
 func TestParseAuthorizationRequestFull(t *testing.T) {
 	r := newRequest(map[string]string{
-		"client_id":     "foo",
-		"scope":         "foo bar",
-		"response_type": TokenResponseType,
-		"redirect_uri":  "http://example.com",
-		"state":         "baz",
+		"client_id":      "foo",
+		"scope":          "foo bar",
+		"response_type":  TokenResponseType,
+		"redirect_uri":   "http://example.com",
+		"state":          "baz",
+		"login_hint":     "qux",
+		"id_token_hint":  "quz",
+		"ui_locales":     "de-CH fr <x> en",
+		"claims_locales": "de",
 	})
 
 	req, err := ParseAuthorizationRequest(r)
@@ -39,6 +49,37 @@ func TestParseAuthorizationRequestFull(t *testing.T) {
 	assert.Equal(t, "foo", req.ClientID)
 	assert.Equal(t, "http://example.com", req.RedirectURI)
 	assert.Equal(t, "baz", req.State)
+	assert.Equal(t, "qux", req.LoginHint)
+	assert.Equal(t, "quz", req.IDTokenHint)
+	assert.Equal(t, []string{"de-CH", "fr", "en"}, req.UILocales)
+	assert.Equal(t, []string{"de"}, req.ClaimsLocales)
+}
+
+func TestAuthorizationRequestLocale(t *testing.T) {
+	req := &AuthorizationRequest{
+		UILocales: []string{"de-CH", "fr", "en"},
+	}
+
+	assert.Equal(t, "", req.Locale())
+	assert.Equal(t, "en", req.Locale("en", "it"))
+	assert.Equal(t, "fr", req.Locale("en", "fr"))
+	assert.Equal(t, "de-ch", req.Locale("en", "de-ch", "de"))
+	assert.Equal(t, "de", req.Locale("de", "fr"))
+	assert.Equal(t, "", req.Locale("it"))
+}
+
+func TestParseAuthorizationRequestPKCE(t *testing.T) {
+	r := newRequest(map[string]string{
+		"client_id":      "foo",
+		"response_type":  CodeResponseType,
+		"redirect_uri":   "http://example.com",
+		"code_challenge": "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM",
+	})
+
+	req, err := ParseAuthorizationRequest(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM", req.CodeChallenge)
+	assert.Equal(t, "plain", req.CodeChallengeMethod)
 }
 
 func TestParseAuthorizationRequestErrors(t *testing.T) {
@@ -61,6 +102,31 @@ func TestParseAuthorizationRequestErrors(t *testing.T) {
 			"client_id":     "foo",
 			"redirect_uri":  "foo",
 		}),
+		newRequest(map[string]string{
+			"response_type": TokenResponseType,
+			"client_id":     "foo",
+			"redirect_uri":  "http://example.com",
+			"state":         "foo\r\nLocation: http://evil.com",
+		}),
+		newRequest(map[string]string{
+			"response_type":  CodeResponseType,
+			"client_id":      "foo",
+			"redirect_uri":   "http://example.com",
+			"code_challenge": "foo",
+		}),
+		newRequest(map[string]string{
+			"response_type":         CodeResponseType,
+			"client_id":             "foo",
+			"redirect_uri":          "http://example.com",
+			"code_challenge":        "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM",
+			"code_challenge_method": "foo",
+		}),
+		newRequest(map[string]string{
+			"response_type":         CodeResponseType,
+			"client_id":             "foo",
+			"redirect_uri":          "http://example.com",
+			"code_challenge_method": "S256",
+		}),
 	}
 
 	for _, i := range matrix {
@@ -69,3 +135,99 @@ func TestParseAuthorizationRequestErrors(t *testing.T) {
 		assert.Error(t, err)
 	}
 }
+
+func TestAuthorizationRequestContext(t *testing.T) {
+	req := &AuthorizationRequest{
+		ResponseType: CodeResponseType,
+		RedirectURI:  "http://example.com/callback",
+		State:        "xyz",
+	}
+
+	rec := httptest.NewRecorder()
+	err := req.RequestContext().WriteError(rec, AccessDenied(""))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusSeeOther, rec.Code)
+	assert.Equal(t, "http://example.com/callback?error=access_denied&state=xyz", rec.Header().Get("Location"))
+
+	rec = httptest.NewRecorder()
+	err = req.RequestContext().WriteError(rec, errors.New("foo"))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusSeeOther, rec.Code)
+	assert.Equal(t, "http://example.com/callback?error=server_error&state=xyz", rec.Header().Get("Location"))
+
+	rec = httptest.NewRecorder()
+	err = req.RequestContext().WriteCode(rec, "abc")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusSeeOther, rec.Code)
+	assert.Equal(t, "http://example.com/callback?code=abc&state=xyz", rec.Header().Get("Location"))
+
+	req.ResponseType = TokenResponseType
+
+	rec = httptest.NewRecorder()
+	err = req.RequestContext().WriteError(rec, AccessDenied(""))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusSeeOther, rec.Code)
+	assert.Equal(t, "http://example.com/callback#error=access_denied&state=xyz", rec.Header().Get("Location"))
+
+	rec = httptest.NewRecorder()
+	err = req.RequestContext().WriteToken(rec, NewBearerTokenResponse("foo", 1))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusSeeOther, rec.Code)
+	assert.Equal(t, "http://example.com/callback#access_token=foo&expires_in=1&state=xyz&token_type=bearer", rec.Header().Get("Location"))
+}
+
+func TestAuthorizationRequestContextStrict(t *testing.T) {
+	ctx := &RequestContext{
+		RedirectURI: "http://example.com/callback",
+		State:       "xyz",
+		Strict:      true,
+	}
+
+	anError := AccessDenied("")
+	anError.Realm = "foo"
+	anError.Headers = map[string]string{"X-Foo": "bar"}
+
+	rec := httptest.NewRecorder()
+	err := ctx.WriteError(rec, anError)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusSeeOther, rec.Code)
+	assert.Equal(t, "bar", rec.Header().Get("X-Foo"))
+	assert.Equal(t, "http://example.com/callback?error=access_denied&state=xyz", rec.Header().Get("Location"))
+
+	rec = httptest.NewRecorder()
+	err = ctx.WriteCode(rec, "abc")
+	assert.NoError(t, err)
+	assert.Equal(t, "http://example.com/callback?code=abc&state=xyz", rec.Header().Get("Location"))
+
+	ctx.UseFragment = true
+
+	res := NewBearerTokenResponse("foo", 1)
+	res.RefreshToken = "bar"
+	res.IssuedAt = 42
+
+	rec = httptest.NewRecorder()
+	err = ctx.WriteToken(rec, res)
+	assert.NoError(t, err)
+	assert.Equal(t, "http://example.com/callback#access_token=foo&expires_in=1&state=xyz&token_type=bearer", rec.Header().Get("Location"))
+
+	ctx.Strict = false
+
+	rec = httptest.NewRecorder()
+	err = ctx.WriteError(rec, anError)
+	assert.NoError(t, err)
+	assert.Equal(t, "http://example.com/callback#error=access_denied&realm=foo&state=xyz", rec.Header().Get("Location"))
+}
+
+func TestFilterRedirectParameters(t *testing.T) {
+	assert.Equal(t, map[string]string{
+		"code":  "foo",
+		"state": "bar",
+		"iss":   "https://example.com",
+	}, FilterRedirectParameters(map[string]string{
+		"code":     "foo",
+		"state":    "bar",
+		"iss":      "https://example.com",
+		"username": "baz",
+		"realm":    "qux",
+	}))
+}
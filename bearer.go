@@ -1,6 +1,7 @@
 package oauth2
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 )
@@ -50,16 +51,16 @@ func ParseBearerToken(r *http.Request) (string, error) {
 	return token, nil
 }
 
-// WriteBearerError will write the specified error to the response writer. The
-// function will fall back and write an internal server error if the specified
-// error is not known.
+// WriteBearerError will write the specified error to the response writer.
+// Wrapped errors are unwrapped using errors.As. The function will fall back and
+// write an internal server error if the specified error is not known.
 //
 // Common bearer token errors: ProtectedResource, InvalidRequest, InvalidToken,
 // InsufficientScope, ServerError.
 func WriteBearerError(w http.ResponseWriter, err error) error {
 	// ensure complex error
-	anError, ok := err.(*Error)
-	if !ok || anError.Status == http.StatusInternalServerError {
+	var anError *Error
+	if !errors.As(err, &anError) || anError.Status == http.StatusInternalServerError {
 		// write internal server error
 		w.WriteHeader(http.StatusInternalServerError)
 
@@ -2,6 +2,7 @@ package oauth2
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -79,6 +80,17 @@ func TestWriteBearerErrorForcedRealm(t *testing.T) {
 	assert.Empty(t, rec.Body.String())
 }
 
+func TestWriteBearerErrorWrapped(t *testing.T) {
+	err1 := fmt.Errorf("bar: %w", InvalidToken("foo"))
+
+	rec := httptest.NewRecorder()
+
+	err2 := WriteBearerError(rec, err1)
+	assert.NoError(t, err2)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, `Bearer error="invalid_token", error_description="foo"`, rec.Header().Get("WWW-Authenticate"))
+}
+
 func TestWriteBearerErrorFallback(t *testing.T) {
 	err1 := errors.New("foo")
 	rec := httptest.NewRecorder()
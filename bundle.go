@@ -0,0 +1,98 @@
+package oauth2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// VerificationBundle contains everything a resource server needs to validate
+// tokens of an issuer without contacting it. It can be exported while the
+// issuer is available and loaded at startup to bridge outages.
+type VerificationBundle struct {
+	Issuer     string       `json:"issuer"`
+	Algorithms []string     `json:"algorithms"`
+	Keys       []JSONWebKey `json:"keys"`
+	ExportedAt time.Time    `json:"exported_at"`
+}
+
+// Export will return a verification bundle with the currently known keys. The
+// keys are fetched if they have not been fetched yet.
+func (v *Validator) Export(ctx context.Context) (*VerificationBundle, error) {
+	// check keys
+	v.mutex.Lock()
+	missing := v.keys == nil
+	v.mutex.Unlock()
+
+	// fetch keys if missing
+	if missing {
+		err := v.refresh(ctx, time.Now())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// acquire mutex
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	return &VerificationBundle{
+		Issuer:     v.config.Issuer,
+		Algorithms: append([]string{}, v.config.Algorithms...),
+		Keys:       append([]JSONWebKey{}, v.jwks...),
+		ExportedAt: v.fetched,
+	}, nil
+}
+
+// Load will load the keys of the specified verification bundle. The keys are
+// used until they can be refreshed from the issuer. Warnings are returned if
+// the bundle is older than the specified maximum age, contains no usable keys
+// or lists algorithms that are not accepted by the validator.
+func (v *Validator) Load(bundle *VerificationBundle, maxAge time.Duration) ([]string, error) {
+	// check issuer
+	if bundle.Issuer != v.config.Issuer {
+		return nil, errors.New("verification bundle issuer mismatch")
+	}
+
+	// check export time
+	if bundle.ExportedAt.IsZero() {
+		return nil, errors.New("verification bundle is missing export time")
+	}
+
+	// parse keys
+	jwks, keys := parseKeySet(bundle.Keys)
+
+	// prepare warnings
+	var warnings []string
+
+	// check freshness
+	if age := time.Since(bundle.ExportedAt); maxAge > 0 && age > maxAge {
+		warnings = append(warnings, fmt.Sprintf("verification bundle is stale (exported %s ago)", age.Round(time.Second)))
+	}
+
+	// check keys
+	if len(keys) == 0 {
+		warnings = append(warnings, "verification bundle contains no usable keys")
+	} else if len(keys) < len(bundle.Keys) {
+		warnings = append(warnings, "verification bundle contains unsupported keys")
+	}
+
+	// check algorithms
+	for _, alg := range bundle.Algorithms {
+		if !v.allowed(alg) {
+			warnings = append(warnings, fmt.Sprintf("verification bundle algorithm %q is not accepted", alg))
+		}
+	}
+
+	// acquire mutex
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	// set keys
+	v.jwks = jwks
+	v.keys = keys
+	v.fetched = bundle.ExportedAt
+
+	return warnings, nil
+}
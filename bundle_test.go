@@ -0,0 +1,102 @@
+package oauth2
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidatorBundle(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	var issuer string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":   issuer,
+			"jwks_uri": issuer + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{
+				{
+					"kty": "RSA",
+					"kid": "rsa",
+					"n":   b64.EncodeToString(key.N.Bytes()),
+					"e":   b64.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+				},
+			},
+		})
+	})
+
+	server := httptest.NewServer(mux)
+	issuer = server.URL
+
+	bundle, err := NewValidator(ValidatorConfig{
+		Issuer: issuer,
+	}).Export(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, issuer, bundle.Issuer)
+	assert.Equal(t, []string{"RS256"}, bundle.Algorithms)
+	assert.Len(t, bundle.Keys, 1)
+	assert.False(t, bundle.ExportedAt.IsZero())
+
+	data, err := json.Marshal(bundle)
+	assert.NoError(t, err)
+
+	server.Close()
+
+	var loaded VerificationBundle
+	err = json.Unmarshal(data, &loaded)
+	assert.NoError(t, err)
+
+	validator := NewValidator(ValidatorConfig{
+		Issuer: issuer,
+	})
+
+	warnings, err := validator.Load(&loaded, time.Hour)
+	assert.NoError(t, err)
+	assert.Empty(t, warnings)
+
+	token := signTestJWT(t, "RS256", "rsa", key, Claims{
+		"iss": issuer,
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+
+	_, err = validator.Validate(context.Background(), token)
+	assert.NoError(t, err)
+
+	loaded.ExportedAt = time.Now().Add(-2 * time.Hour)
+	loaded.Algorithms = []string{"RS256", "ES256"}
+
+	warnings, err = validator.Load(&loaded, time.Hour)
+	assert.NoError(t, err)
+	assert.Len(t, warnings, 2)
+
+	_, err = validator.Validate(context.Background(), token)
+	assert.NoError(t, err)
+
+	loaded.Keys = nil
+
+	warnings, err = validator.Load(&loaded, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"verification bundle contains no usable keys",
+		`verification bundle algorithm "ES256" is not accepted`,
+	}, warnings)
+
+	loaded.Issuer = "other"
+
+	_, err = validator.Load(&loaded, 0)
+	assert.Error(t, err)
+}
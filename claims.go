@@ -0,0 +1,169 @@
+package oauth2
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Claims is a set of claims that is used for JWT payloads, introspection extras
+// and values that are propagated through a context.
+type Claims map[string]interface{}
+
+// Get returns the raw value of the specified claim.
+func (c Claims) Get(name string) (interface{}, bool) {
+	value, ok := c[name]
+	return value, ok
+}
+
+// Set will set the specified claim.
+func (c Claims) Set(name string, value interface{}) {
+	c[name] = value
+}
+
+// GetString returns the string value of the specified claim. An empty string
+// is returned if the claim is missing or not a string.
+func (c Claims) GetString(name string) string {
+	str, _ := c[name].(string)
+	return str
+}
+
+// GetInt64 returns the numeric value of the specified claim. Zero is returned
+// if the claim is missing or not a number.
+func (c Claims) GetInt64(name string) int64 {
+	switch value := c[name].(type) {
+	case int:
+		return int64(value)
+	case int64:
+		return value
+	case float64:
+		return int64(value)
+	case json.Number:
+		num, _ := value.Int64()
+		return num
+	}
+
+	return 0
+}
+
+// GetTime returns the time value of the specified claim that is encoded as a
+// numeric date. A zero time is returned if the claim is missing or invalid.
+func (c Claims) GetTime(name string) time.Time {
+	// handle time values
+	if value, ok := c[name].(time.Time); ok {
+		return value
+	}
+
+	// get number
+	num := c.GetInt64(name)
+	if num == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(num, 0)
+}
+
+// SetTime will set the specified claim to the numeric date of the time.
+func (c Claims) SetTime(name string, t time.Time) {
+	c[name] = t.Unix()
+}
+
+// GetScope returns the scope stored in the "scope" claim either as a joined
+// string or a list of strings.
+func (c Claims) GetScope() Scope {
+	switch value := c["scope"].(type) {
+	case string:
+		return ParseScope(value)
+	case Scope:
+		return value
+	case []string:
+		return value
+	case []interface{}:
+		var scope Scope
+		for _, item := range value {
+			if str, ok := item.(string); ok {
+				scope = append(scope, str)
+			}
+		}
+		return scope
+	}
+
+	return nil
+}
+
+// SetScope will set the "scope" claim to the joined string of the scope.
+func (c Claims) SetScope(scope Scope) {
+	c["scope"] = scope.String()
+}
+
+// GetAudience returns the audience stored in the "aud" claim either as a single
+// string or a list of strings.
+func (c Claims) GetAudience() Audience {
+	switch value := c["aud"].(type) {
+	case string:
+		return Audience{value}
+	case Audience:
+		return value
+	case []string:
+		return value
+	case []interface{}:
+		var audience Audience
+		for _, item := range value {
+			if str, ok := item.(string); ok {
+				audience = append(audience, str)
+			}
+		}
+		return audience
+	}
+
+	return nil
+}
+
+// SetAudience will set the "aud" claim to the audience.
+func (c Claims) SetAudience(audience Audience) {
+	c["aud"] = audience
+}
+
+type claimsKey struct{}
+
+// ContextWithClaims returns a new context that carries the specified claims.
+func ContextWithClaims(ctx context.Context, claims Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// ClaimsFromContext returns the claims carried by the specified context.
+func ClaimsFromContext(ctx context.Context) (Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(Claims)
+	return claims, ok
+}
+
+// Access describes the access granted by a validated bearer token.
+type Access struct {
+	ClientID  string
+	Username  string
+	Scope     Scope
+	ExpiresAt time.Time
+}
+
+// Access returns the access described by the "client_id", "sub", "scope" and
+// "exp" claims.
+func (c Claims) Access() Access {
+	return Access{
+		ClientID:  c.GetString("client_id"),
+		Username:  c.GetString("sub"),
+		Scope:     c.GetScope(),
+		ExpiresAt: c.GetTime("exp"),
+	}
+}
+
+// AccessFromContext returns the access of the validated bearer token whose
+// claims are carried by the specified context.
+func AccessFromContext(ctx context.Context) (Access, bool) {
+	// get claims
+	claims, ok := ClaimsFromContext(ctx)
+	if !ok {
+		return Access{}, false
+	}
+
+	return claims.Access(), true
+}
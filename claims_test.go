@@ -0,0 +1,100 @@
+package oauth2
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClaims(t *testing.T) {
+	now := time.Unix(time.Now().Unix(), 0)
+
+	claims := Claims{}
+	claims.Set("sub", "user1")
+	claims.SetTime("exp", now)
+	claims.SetScope(Scope{"foo", "bar"})
+
+	value, ok := claims.Get("sub")
+	assert.True(t, ok)
+	assert.Equal(t, "user1", value)
+	assert.Equal(t, "user1", claims.GetString("sub"))
+	assert.Equal(t, "", claims.GetString("exp"))
+	assert.Equal(t, now.Unix(), claims.GetInt64("exp"))
+	assert.Equal(t, now, claims.GetTime("exp"))
+	assert.Equal(t, time.Time{}, claims.GetTime("sub"))
+	assert.Equal(t, Scope{"foo", "bar"}, claims.GetScope())
+
+	data, err := json.Marshal(claims)
+	assert.NoError(t, err)
+
+	var decoded Claims
+	err = json.Unmarshal(data, &decoded)
+	assert.NoError(t, err)
+	assert.Equal(t, "user1", decoded.GetString("sub"))
+	assert.Equal(t, now, decoded.GetTime("exp"))
+	assert.Equal(t, Scope{"foo", "bar"}, decoded.GetScope())
+
+	decoded = Claims{}
+	err = json.Unmarshal([]byte(`{"scope":["foo","bar"]}`), &decoded)
+	assert.NoError(t, err)
+	assert.Equal(t, Scope{"foo", "bar"}, decoded.GetScope())
+	assert.Nil(t, Claims{}.GetScope())
+}
+
+func TestClaimsAudience(t *testing.T) {
+	claims := Claims{}
+	assert.Nil(t, claims.GetAudience())
+
+	claims.SetAudience(Audience{"foo"})
+	assert.Equal(t, Audience{"foo"}, claims.GetAudience())
+
+	data, err := json.Marshal(claims)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"aud":"foo"}`, string(data))
+
+	decoded := Claims{}
+	err = json.Unmarshal([]byte(`{"aud":"foo"}`), &decoded)
+	assert.NoError(t, err)
+	assert.Equal(t, Audience{"foo"}, decoded.GetAudience())
+
+	decoded = Claims{}
+	err = json.Unmarshal([]byte(`{"aud":["foo","bar"]}`), &decoded)
+	assert.NoError(t, err)
+	assert.Equal(t, Audience{"foo", "bar"}, decoded.GetAudience())
+}
+
+func TestClaimsContext(t *testing.T) {
+	claims, ok := ClaimsFromContext(context.Background())
+	assert.False(t, ok)
+	assert.Nil(t, claims)
+
+	ctx := ContextWithClaims(context.Background(), Claims{"sub": "user1"})
+	claims, ok = ClaimsFromContext(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, "user1", claims.GetString("sub"))
+}
+
+func TestAccessFromContext(t *testing.T) {
+	access, ok := AccessFromContext(context.Background())
+	assert.False(t, ok)
+	assert.Equal(t, Access{}, access)
+
+	exp := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	claims := Claims{
+		"client_id": "client1",
+		"sub":       "user1",
+	}
+	claims.SetScope(Scope{"foo", "bar"})
+	claims.SetTime("exp", exp)
+
+	access, ok = AccessFromContext(ContextWithClaims(context.Background(), claims))
+	assert.True(t, ok)
+	assert.Equal(t, "client1", access.ClientID)
+	assert.Equal(t, "user1", access.Username)
+	assert.Equal(t, Scope{"foo", "bar"}, access.Scope)
+	assert.True(t, exp.Equal(access.ExpiresAt))
+}
@@ -1,7 +1,9 @@
 package oauth2
 
 import (
+	"errors"
 	"net/http"
+	"sync"
 )
 
 // ClientConfig is used to configure a client.
@@ -23,10 +25,17 @@ func Default(baseURI string) ClientConfig {
 	}
 }
 
-// Client is a low-level OAuth2 client.
+// the maximum number of remembered token type hints
+const clientHintLimit = 1024
+
+// Client is a low-level OAuth2 client. The client remembers the type of the
+// tokens it obtained to set the token type hint of introspection and
+// revocation requests that do not specify one.
 type Client struct {
 	config ClientConfig
 	client *http.Client
+	hints  map[string]TokenTypeHint
+	mutex  sync.Mutex
 }
 
 // NewClient will create and return a new client.
@@ -45,6 +54,7 @@ func NewClientWithClient(config ClientConfig, client *http.Client) *Client {
 	return &Client{
 		config: config,
 		client: client,
+		hints:  map[string]TokenTypeHint{},
 	}
 }
 
@@ -80,12 +90,36 @@ func (c *Client) Authenticate(trq TokenRequest) (*TokenResponse, error) {
 		return nil, err
 	}
 
+	// remember token types
+	c.remember(trs.AccessToken, AccessTokenHint)
+	c.remember(trs.RefreshToken, RefreshTokenHint)
+
 	return trs, nil
 }
 
 // Introspect will send the provided introspection request and return the servers
-// response of an error if failed.
+// response of an error if failed. If the request has no token type hint, the
+// hint is set if the token has been obtained by the client. If the server does
+// not support the hinted token type, the request is retried without a hint.
 func (c *Client) Introspect(irq IntrospectionRequest) (*IntrospectionResponse, error) {
+	// set token type hint
+	if irq.TokenTypeHint == "" {
+		irq.TokenTypeHint = string(c.hint(irq.Token))
+	}
+
+	// introspect token
+	irs, err := c.introspect(irq)
+
+	// retry without token type hint if unsupported
+	if irq.TokenTypeHint != "" && unsupportedTokenType(err) {
+		irq.TokenTypeHint = ""
+		irs, err = c.introspect(irq)
+	}
+
+	return irs, err
+}
+
+func (c *Client) introspect(irq IntrospectionRequest) (*IntrospectionResponse, error) {
 	// prepare endpoint
 	endpoint := c.config.BaseURI + c.config.IntrospectionEndpoint
 
@@ -119,8 +153,31 @@ func (c *Client) Introspect(irq IntrospectionRequest) (*IntrospectionResponse, e
 }
 
 // Revoke will send the provided revocation request and return and error if it
-// failed.
+// failed. Token type hints are set and retried like with Introspect.
 func (c *Client) Revoke(rrq RevocationRequest) error {
+	// set token type hint
+	if rrq.TokenTypeHint == "" {
+		rrq.TokenTypeHint = string(c.hint(rrq.Token))
+	}
+
+	// revoke token
+	err := c.revoke(rrq)
+
+	// retry without token type hint if unsupported
+	if rrq.TokenTypeHint != "" && unsupportedTokenType(err) {
+		rrq.TokenTypeHint = ""
+		err = c.revoke(rrq)
+	}
+
+	// forget token
+	if err == nil {
+		c.forget(rrq.Token)
+	}
+
+	return err
+}
+
+func (c *Client) revoke(rrq RevocationRequest) error {
 	// prepare endpoint
 	endpoint := c.config.BaseURI + c.config.RevocationEndpoint
 
@@ -146,3 +203,44 @@ func (c *Client) Revoke(rrq RevocationRequest) error {
 
 	return nil
 }
+
+func (c *Client) remember(token string, hint TokenTypeHint) {
+	// check token
+	if token == "" {
+		return
+	}
+
+	// acquire mutex
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// prepare map
+	if c.hints == nil || len(c.hints) >= clientHintLimit {
+		c.hints = map[string]TokenTypeHint{}
+	}
+
+	// set hint
+	c.hints[token] = hint
+}
+
+func (c *Client) hint(token string) TokenTypeHint {
+	// acquire mutex
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.hints[token]
+}
+
+func (c *Client) forget(token string) {
+	// acquire mutex
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// delete hint
+	delete(c.hints, token)
+}
+
+func unsupportedTokenType(err error) bool {
+	var anError *Error
+	return errors.As(err, &anError) && anError.Name == "unsupported_token_type"
+}
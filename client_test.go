@@ -2,6 +2,7 @@ package oauth2
 
 import (
 	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
@@ -248,3 +249,104 @@ func TestClientRevoke(t *testing.T) {
 		assert.NoError(t, err)
 	})
 }
+
+func TestClientTokenTypeHint(t *testing.T) {
+	var hints []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/oauth2/token":
+			_ = WriteTokenResponse(w, &TokenResponse{
+				TokenType:    BearerAccessTokenType,
+				AccessToken:  "access",
+				RefreshToken: "refresh",
+			})
+		case "/oauth2/introspect", "/oauth2/revoke":
+			hint := r.PostFormValue("token_type_hint")
+			hints = append(hints, hint)
+			if hint == string(RefreshTokenHint) {
+				_ = WriteError(w, UnsupportedTokenType(""))
+				return
+			}
+			_ = WriteIntrospectionResponse(w, &IntrospectionResponse{
+				Active:    true,
+				TokenType: AccessToken,
+			})
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(Default(server.URL))
+
+	_, err := client.Authenticate(TokenRequest{
+		GrantType: ClientCredentialsGrantType,
+		ClientID:  "c1",
+	})
+	assert.NoError(t, err)
+
+	// access token
+	irs, err := client.Introspect(IntrospectionRequest{
+		Token:    "access",
+		ClientID: "c1",
+	})
+	assert.NoError(t, err)
+	assert.True(t, irs.Active)
+	assert.Equal(t, []string{"access_token"}, hints)
+
+	// refresh token
+	hints = nil
+	irs, err = client.Introspect(IntrospectionRequest{
+		Token:    "refresh",
+		ClientID: "c1",
+	})
+	assert.NoError(t, err)
+	assert.True(t, irs.Active)
+	assert.Equal(t, []string{"refresh_token", ""}, hints)
+
+	// unknown token
+	hints = nil
+	irs, err = client.Introspect(IntrospectionRequest{
+		Token:    "foo",
+		ClientID: "c1",
+	})
+	assert.NoError(t, err)
+	assert.True(t, irs.Active)
+	assert.Equal(t, []string{""}, hints)
+
+	// explicit hint
+	hints = nil
+	irs, err = client.Introspect(IntrospectionRequest{
+		Token:         "access",
+		TokenTypeHint: string(RefreshTokenHint),
+		ClientID:      "c1",
+	})
+	assert.NoError(t, err)
+	assert.True(t, irs.Active)
+	assert.Equal(t, []string{"refresh_token", ""}, hints)
+
+	// revoke refresh token
+	hints = nil
+	err = client.Revoke(RevocationRequest{
+		Token:    "refresh",
+		ClientID: "c1",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"refresh_token", ""}, hints)
+
+	// revoke access token
+	hints = nil
+	err = client.Revoke(RevocationRequest{
+		Token:    "access",
+		ClientID: "c1",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"access_token"}, hints)
+
+	// forgotten token
+	hints = nil
+	err = client.Revoke(RevocationRequest{
+		Token:    "access",
+		ClientID: "c1",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{""}, hints)
+}
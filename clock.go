@@ -0,0 +1,38 @@
+package oauth2
+
+import "time"
+
+// Clock provides the current time.
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc is a function that implements the Clock interface.
+type ClockFunc func() time.Time
+
+// Now implements the Clock interface.
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+// AdvanceTime will shift the time of the server by the specified duration.
+// Subsequent requests observe the shifted time when checking and setting
+// expiries, which allows tests to expire credentials without sleeping.
+func (s *Server) AdvanceTime(d time.Duration) {
+	// acquire mutex
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+
+	// advance time
+	s.timeOffset += d
+}
+
+func (s *Server) now() time.Time {
+	// get time
+	now := time.Now()
+	if s.Config.Clock != nil {
+		now = s.Config.Clock.Now()
+	}
+
+	return now.Add(s.timeOffset)
+}
@@ -0,0 +1,124 @@
+package oauth2
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/256dpi/oauth2/v2/oauth2test"
+)
+
+func TestServerAdvanceTime(t *testing.T) {
+	server := newTestServer()
+
+	res := oauth2test.Do(server, &oauth2test.Request{
+		Method:   "POST",
+		Path:     "/oauth2/token",
+		Username: "client1",
+		Password: "foo",
+		Form: map[string]string{
+			"grant_type": PasswordGrantType,
+			"username":   "user1",
+			"password":   "foo",
+			"scope":      "foo",
+		},
+	})
+	assert.Equal(t, http.StatusOK, res.Status)
+
+	authorize := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		server.Authorize(rec, req, nil)
+		return rec
+	}
+
+	refresh := func(token string) *oauth2test.Response {
+		return oauth2test.Do(server, &oauth2test.Request{
+			Method:   "POST",
+			Path:     "/oauth2/token",
+			Username: "client1",
+			Password: "foo",
+			Form: map[string]string{
+				"grant_type":    RefreshTokenGrantType,
+				"refresh_token": token,
+			},
+		})
+	}
+
+	rec := authorize(res.String("access_token"))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	server.AdvanceTime(2 * time.Hour)
+
+	rec = authorize(res.String("access_token"))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Contains(t, rec.Header().Get("WWW-Authenticate"), "expired token")
+
+	res = refresh(res.String("refresh_token"))
+	assert.Equal(t, http.StatusOK, res.Status)
+
+	rec = authorize(res.String("access_token"))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	server.AdvanceTime(8 * 24 * time.Hour)
+
+	res = refresh(res.String("refresh_token"))
+	assert.Equal(t, http.StatusBadRequest, res.Status)
+	assert.Equal(t, "expired refresh token", res.String("error_description"))
+}
+
+func TestServerClock(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	server := newTestServer()
+	server.Config.Clock = ClockFunc(func() time.Time {
+		return now
+	})
+
+	res, err := server.issueTokens(false, Scope{"foo"}, "client1", "", "")
+	assert.NoError(t, err)
+
+	key, err := server.tokenKey(res.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, now.Add(time.Hour), server.AccessTokens[key].ExpiresAt)
+
+	server.AdvanceTime(time.Minute)
+	assert.Equal(t, now.Add(time.Minute), server.now())
+}
+
+func TestServerClockEvents(t *testing.T) {
+	server := newTestServer()
+	server.Config.DebugExchanges = 1
+
+	var events []ServerEvent
+	server.Config.EventHandler = ServerEventHandlerFunc(func(event ServerEvent) {
+		events = append(events, event)
+	})
+
+	server.AdvanceTime(24 * time.Hour)
+
+	res := oauth2test.Do(server, &oauth2test.Request{
+		Method:   "POST",
+		Path:     "/oauth2/token",
+		Username: "client1",
+		Password: "foo",
+		Form: map[string]string{
+			"grant_type": ClientCredentialsGrantType,
+			"scope":      "foo",
+		},
+	})
+	assert.Equal(t, http.StatusOK, res.Status)
+
+	assert.NotEmpty(t, events)
+	for _, event := range events {
+		assert.True(t, event.Time.After(time.Now().Add(23*time.Hour)))
+	}
+
+	exchanges := server.Exchanges()
+	assert.Len(t, exchanges, 1)
+	assert.True(t, exchanges[0].Time.After(time.Now().Add(23*time.Hour)))
+}
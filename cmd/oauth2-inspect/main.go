@@ -0,0 +1,274 @@
+// Command oauth2-inspect decodes and validates access tokens to help debugging
+// integrations with servers built using the oauth2 package.
+//
+// Usage:
+//
+//	oauth2-inspect [flags] [--] <token>
+//
+// Tokens that start with a dash must be preceded by "--".
+//
+// JWTs are always decoded. The signature of HS256 JWTs and opaque HS256 tokens
+// is verified if a secret is provided, the signature and claims of JWT access
+// tokens are validated using OIDC discovery and JWKS if an issuer is provided
+// and tokens of any format are checked using an introspection endpoint if
+// provided.
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/256dpi/oauth2/v2"
+)
+
+func main() {
+	// run command
+	code, err := run(os.Args[1:], os.Stdout, http.DefaultClient, time.Now())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+
+	os.Exit(code)
+}
+
+type inspector struct {
+	out    io.Writer
+	client *http.Client
+	now    time.Time
+	valid  bool
+}
+
+func run(args []string, out io.Writer, client *http.Client, now time.Time) (int, error) {
+	// prepare flags
+	set := flag.NewFlagSet("oauth2-inspect", flag.ContinueOnError)
+	secret := set.String("secret", "", "the secret used to verify HS256 tokens")
+	issuer := set.String("issuer", "", "the issuer used to validate JWT access tokens")
+	audience := set.String("audience", "", "the expected audience of JWT access tokens")
+	algorithms := set.String("algorithms", "RS256", "the comma separated accepted JWT algorithms")
+	introspect := set.String("introspect", "", "the introspection endpoint used to check the token")
+	clientID := set.String("client-id", "", "the client ID used to authenticate at the introspection endpoint")
+	clientSecret := set.String("client-secret", "", "the client secret used to authenticate at the introspection endpoint")
+	set.SetOutput(out)
+
+	// parse flags
+	err := set.Parse(args)
+	if err != nil {
+		return 2, nil
+	}
+
+	// check token
+	if set.NArg() != 1 {
+		set.Usage()
+		return 2, errors.New("exactly one token must be provided")
+	}
+
+	// get token
+	token := set.Arg(0)
+
+	// prepare inspector
+	i := &inspector{
+		out:    out,
+		client: client,
+		now:    now,
+		valid:  true,
+	}
+
+	// decode token
+	jwt := i.decode(token)
+
+	// verify secret
+	if *secret != "" {
+		i.verify(token, jwt, []byte(*secret))
+	}
+
+	// validate token
+	if *issuer != "" {
+		i.validate(token, oauth2.ValidatorConfig{
+			Issuer:     *issuer,
+			Audience:   *audience,
+			Algorithms: strings.Split(*algorithms, ","),
+		})
+	}
+
+	// introspect token
+	if *introspect != "" {
+		i.introspect(*introspect, oauth2.IntrospectionRequest{
+			Token:        token,
+			ClientID:     *clientID,
+			ClientSecret: *clientSecret,
+			AuthMethod:   oauth2.ClientSecretBasic,
+		})
+	}
+
+	// check result
+	if !i.valid {
+		fmt.Fprintln(out, "result: invalid")
+		return 1, nil
+	}
+
+	fmt.Fprintln(out, "result: valid")
+
+	return 0, nil
+}
+
+func (i *inspector) decode(token string) oauth2.Claims {
+	// split segments
+	s := strings.Split(token, ".")
+	if len(s) != 3 {
+		fmt.Fprintln(i.out, "format: opaque")
+		return nil
+	}
+
+	// decode segments
+	var header, claims oauth2.Claims
+	err := decodeSegment(s[0], &header)
+	if err == nil {
+		err = decodeSegment(s[1], &claims)
+	}
+	if err != nil {
+		fmt.Fprintln(i.out, "format: opaque")
+		return nil
+	}
+
+	// print header and claims
+	fmt.Fprintln(i.out, "format: jwt")
+	i.print("header", header)
+	i.print("claims", claims)
+
+	// print expiry
+	if _, ok := claims.Get("exp"); ok {
+		i.expiry(claims.GetTime("exp"))
+	}
+
+	// print scope
+	if scope := claims.GetScope(); len(scope) > 0 {
+		fmt.Fprintf(i.out, "scope: %s\n", scope.String())
+	}
+
+	return header
+}
+
+func (i *inspector) verify(token string, jwt oauth2.Claims, secret []byte) {
+	// verify JWS or opaque token
+	var err error
+	if jwt != nil {
+		err = oauth2.VerifyJWS(secret, token, &oauth2.Claims{})
+	} else {
+		_, err = oauth2.ParseHS256Token(secret, token)
+	}
+
+	i.check("secret", err)
+}
+
+func (i *inspector) validate(token string, config oauth2.ValidatorConfig) {
+	// validate token
+	validator := oauth2.NewValidatorWithClient(config, i.client)
+	_, err := validator.Validate(context.Background(), token)
+
+	i.check("issuer", err)
+}
+
+func (i *inspector) introspect(uri string, irq oauth2.IntrospectionRequest) {
+	// build request
+	req, err := oauth2.BuildIntrospectionRequest(uri, irq)
+	if err != nil {
+		i.check("introspection", err)
+		return
+	}
+
+	// perform request
+	res, err := i.client.Do(req)
+	if err != nil {
+		i.check("introspection", err)
+		return
+	}
+
+	// ensure body is closed
+	defer res.Body.Close()
+
+	// check status
+	if res.StatusCode != http.StatusOK {
+		i.check("introspection", oauth2.ParseRequestError(res, 2048))
+		return
+	}
+
+	// parse response
+	irs, err := oauth2.ParseIntrospectionResponse(res, 2048)
+	if err != nil {
+		i.check("introspection", err)
+		return
+	}
+
+	// print response
+	i.print("introspection", irs)
+
+	// check active
+	if !irs.Active {
+		i.check("introspection", errors.New("token is not active"))
+		return
+	}
+
+	// print expiry
+	if irs.ExpiresAt > 0 {
+		i.expiry(time.Unix(irs.ExpiresAt, 0))
+	}
+
+	// print scope
+	if irs.Scope != "" {
+		fmt.Fprintf(i.out, "scope: %s\n", irs.Scope)
+	}
+
+	i.check("introspection", nil)
+}
+
+func (i *inspector) expiry(exp time.Time) {
+	// check expiry
+	if !exp.After(i.now) {
+		fmt.Fprintf(i.out, "expires: %s (expired %s ago)\n", exp.UTC().Format(time.RFC3339), i.now.Sub(exp).Round(time.Second))
+		i.valid = false
+		return
+	}
+
+	fmt.Fprintf(i.out, "expires: %s (in %s)\n", exp.UTC().Format(time.RFC3339), exp.Sub(i.now).Round(time.Second))
+}
+
+func (i *inspector) check(name string, err error) {
+	// check error
+	if err != nil {
+		fmt.Fprintf(i.out, "%s: invalid (%s)\n", name, err.Error())
+		i.valid = false
+		return
+	}
+
+	fmt.Fprintf(i.out, "%s: valid\n", name)
+}
+
+func (i *inspector) print(name string, obj interface{}) {
+	// encode object
+	data, err := json.MarshalIndent(obj, "", "  ")
+	if err != nil {
+		fmt.Fprintf(i.out, "%s: %s\n", name, err.Error())
+		return
+	}
+
+	fmt.Fprintf(i.out, "%s: %s\n", name, data)
+}
+
+func decodeSegment(str string, obj interface{}) error {
+	// decode segment
+	data, err := base64.RawURLEncoding.DecodeString(str)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, obj)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/256dpi/oauth2/v2"
+)
+
+func TestRunSecret(t *testing.T) {
+	secret := []byte("abcd1234abcd1234")
+	now := time.Unix(time.Now().Unix(), 0)
+
+	token, err := oauth2.SignJWS(secret, oauth2.Claims{
+		"sub":   "user",
+		"scope": "foo bar",
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	assert.NoError(t, err)
+
+	var out bytes.Buffer
+	code, err := run([]string{"-secret", string(secret), "--", token}, &out, http.DefaultClient, now)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, code)
+	assert.Contains(t, out.String(), "format: jwt")
+	assert.Contains(t, out.String(), `"sub": "user"`)
+	assert.Contains(t, out.String(), "(in 1h0m0s)")
+	assert.Contains(t, out.String(), "scope: foo bar")
+	assert.Contains(t, out.String(), "secret: valid")
+	assert.Contains(t, out.String(), "result: valid")
+
+	out.Reset()
+	code, err = run([]string{"-secret", "foo", "--", token}, &out, http.DefaultClient, now.Add(2*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, out.String(), "(expired 1h0m0s ago)")
+	assert.Contains(t, out.String(), "secret: invalid (invalid JWS signature)")
+	assert.Contains(t, out.String(), "result: invalid")
+
+	opaque := oauth2.MustGenerateHS256Token(secret, 32).String()
+
+	out.Reset()
+	code, err = run([]string{"-secret", string(secret), "--", opaque}, &out, http.DefaultClient, now)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, code)
+	assert.Contains(t, out.String(), "format: opaque")
+	assert.Contains(t, out.String(), "secret: valid")
+}
+
+func TestRunIntrospection(t *testing.T) {
+	provider := oauth2.NewTestProvider()
+
+	server := httptest.NewServer(provider)
+	defer server.Close()
+
+	client := oauth2.NewClient(oauth2.Default(server.URL))
+	res, err := client.Authenticate(oauth2.TokenRequest{
+		GrantType:    oauth2.ClientCredentialsGrantType,
+		Scope:        oauth2.Scope{"read"},
+		ClientID:     provider.ConfidentialClientID,
+		ClientSecret: provider.ConfidentialClientSecret,
+		AuthMethod:   oauth2.ClientSecretBasic,
+	})
+	assert.NoError(t, err)
+
+	args := []string{
+		"-introspect", server.URL + "/oauth2/introspect",
+		"-client-id", provider.ConfidentialClientID,
+		"-client-secret", provider.ConfidentialClientSecret,
+		"--",
+	}
+
+	var out bytes.Buffer
+	code, err := run(append(args, res.AccessToken), &out, server.Client(), time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, code)
+	assert.Contains(t, out.String(), "format: opaque")
+	assert.Contains(t, out.String(), "scope: read")
+	assert.Contains(t, out.String(), "introspection: valid")
+
+	out.Reset()
+	code, err = run(append(args, oauth2.MustGenerateHS256Token([]byte("abcd1234abcd1234"), 32).String()), &out, server.Client(), time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, out.String(), "introspection: invalid (invalid_request: invalid token supplied)")
+}
+
+func TestRunUsage(t *testing.T) {
+	var out bytes.Buffer
+	code, err := run(nil, &out, http.DefaultClient, time.Now())
+	assert.Error(t, err)
+	assert.Equal(t, 2, code)
+	assert.Contains(t, out.String(), "-introspect")
+}
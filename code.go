@@ -11,6 +11,10 @@ type CodeResponse struct {
 	State string `json:"state,omitempty"`
 
 	RedirectURI string `json:"-"`
+
+	// The status used for the redirect. Defaults to 303 See Other (see
+	// WriteRedirectStatus).
+	RedirectStatus int `json:"-"`
 }
 
 // NewCodeResponse constructs a CodeResponse.
@@ -42,5 +46,5 @@ func (r *CodeResponse) Map() map[string]string {
 // WriteCodeResponse will write a redirection based on the specified code
 // response to the response writer.
 func WriteCodeResponse(w http.ResponseWriter, r *CodeResponse) error {
-	return WriteRedirect(w, r.RedirectURI, r.Map(), false)
+	return WriteRedirectStatus(w, r.RedirectURI, r.Map(), false, r.RedirectStatus)
 }
@@ -25,4 +25,11 @@ func TestWriteCodeResponse(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, http.StatusSeeOther, w.Code)
 	assert.Equal(t, "http://example.com?code=foo&foo=bar&state=bar", w.Header().Get("Location"))
+
+	w = httptest.NewRecorder()
+	r.RedirectStatus = http.StatusFound
+
+	err = WriteCodeResponse(w, r)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusFound, w.Code)
 }
@@ -0,0 +1,32 @@
+package oauth2
+
+// ConsentDecision describes the decision of a resource owner about the scope
+// requested by a client. Resource owners may approve only a subset of the
+// requested scope.
+type ConsentDecision struct {
+	// The scope approved by the resource owner.
+	Approved Scope
+
+	// The scope that must at least be approved for the authorization to be
+	// granted.
+	Required Scope
+}
+
+// Grant returns the requested scope narrowed to the approved scope. It will
+// return an Error if the required scope has not been approved.
+func (d ConsentDecision) Grant(requested Scope) (Scope, error) {
+	// narrow scope
+	granted := Scope{}
+	for _, item := range requested {
+		if d.Approved.Contains(item) {
+			granted = append(granted, item)
+		}
+	}
+
+	// check required scope
+	if !granted.Includes(d.Required) {
+		return nil, InvalidScope("required scope not approved")
+	}
+
+	return granted, nil
+}
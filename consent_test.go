@@ -0,0 +1,33 @@
+package oauth2
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConsentDecisionGrant(t *testing.T) {
+	scope, err := ConsentDecision{
+		Approved: Scope{"foo", "bar"},
+	}.Grant(Scope{"foo", "bar"})
+	assert.NoError(t, err)
+	assert.Equal(t, Scope{"foo", "bar"}, scope)
+
+	scope, err = ConsentDecision{
+		Approved: Scope{"bar", "baz"},
+		Required: Scope{"bar"},
+	}.Grant(Scope{"foo", "bar"})
+	assert.NoError(t, err)
+	assert.Equal(t, Scope{"bar"}, scope)
+
+	scope, err = ConsentDecision{
+		Approved: Scope{"bar"},
+		Required: Scope{"foo"},
+	}.Grant(Scope{"foo", "bar"})
+	assert.Equal(t, InvalidScope("required scope not approved"), err)
+	assert.Nil(t, scope)
+
+	scope, err = ConsentDecision{}.Grant(Scope{"foo"})
+	assert.NoError(t, err)
+	assert.Equal(t, Scope{}, scope)
+}
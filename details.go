@@ -0,0 +1,166 @@
+package oauth2
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+// AuthorizationDetail is a single fine-grained permission requested with the
+// authorization details parameter as defined by RFC 9396.
+type AuthorizationDetail struct {
+	// The type of the authorization detail. It determines the allowed
+	// additional fields.
+	Type string `json:"type"`
+
+	// The common data fields that may be used by all types.
+	Locations  []string `json:"locations,omitempty"`
+	Actions    []string `json:"actions,omitempty"`
+	DataTypes  []string `json:"datatypes,omitempty"`
+	Identifier string   `json:"identifier,omitempty"`
+	Privileges []string `json:"privileges,omitempty"`
+
+	// The additional type specific fields.
+	Extra map[string]interface{} `json:"-"`
+}
+
+type plainAuthorizationDetail AuthorizationDetail
+
+func (d AuthorizationDetail) clone() AuthorizationDetail {
+	// copy common fields
+	d.Locations = copyStrings(d.Locations)
+	d.Actions = copyStrings(d.Actions)
+	d.DataTypes = copyStrings(d.DataTypes)
+	d.Privileges = copyStrings(d.Privileges)
+
+	// copy additional fields
+	if d.Extra != nil {
+		extra := make(map[string]interface{}, len(d.Extra))
+		for key, value := range d.Extra {
+			extra[key] = value
+		}
+		d.Extra = extra
+	}
+
+	return d
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (d AuthorizationDetail) MarshalJSON() ([]byte, error) {
+	// encode common fields
+	data, err := json.Marshal(plainAuthorizationDetail(d))
+	if err != nil || len(d.Extra) == 0 {
+		return data, err
+	}
+
+	// decode common fields
+	var fields map[string]interface{}
+	err = json.Unmarshal(data, &fields)
+	if err != nil {
+		return nil, err
+	}
+
+	// add extra fields
+	for key, value := range d.Extra {
+		if _, ok := fields[key]; !ok {
+			fields[key] = value
+		}
+	}
+
+	return json.Marshal(fields)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (d *AuthorizationDetail) UnmarshalJSON(data []byte) error {
+	// decode common fields
+	var detail plainAuthorizationDetail
+	err := json.Unmarshal(data, &detail)
+	if err != nil {
+		return err
+	}
+
+	// decode all fields
+	var fields map[string]interface{}
+	err = json.Unmarshal(data, &fields)
+	if err != nil {
+		return err
+	}
+
+	// remove common fields
+	for _, key := range []string{"type", "locations", "actions", "datatypes", "identifier", "privileges"} {
+		delete(fields, key)
+	}
+
+	// set extra fields
+	if len(fields) > 0 {
+		detail.Extra = fields
+	}
+
+	*d = AuthorizationDetail(detail)
+
+	return nil
+}
+
+// ParseAuthorizationDetails parses the JSON encoded authorization details
+// parameter. An empty string yields no details.
+func ParseAuthorizationDetails(str string) ([]AuthorizationDetail, error) {
+	// check string
+	if str == "" {
+		return nil, nil
+	}
+
+	// decode details
+	var details []AuthorizationDetail
+	err := json.Unmarshal([]byte(str), &details)
+	if err != nil {
+		return nil, InvalidAuthorizationDetails("malformed authorization details")
+	}
+
+	// check types
+	for _, detail := range details {
+		if detail.Type == "" {
+			return nil, InvalidAuthorizationDetails("missing authorization detail type")
+		}
+	}
+
+	return details, nil
+}
+
+// IncludesAuthorizationDetails returns true if every requested authorization
+// detail equals a granted authorization detail.
+func IncludesAuthorizationDetails(granted, requested []AuthorizationDetail) bool {
+	for _, detail := range requested {
+		found := false
+		for _, item := range granted {
+			if reflect.DeepEqual(normalizeAuthorizationDetail(item), normalizeAuthorizationDetail(detail)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+func normalizeAuthorizationDetail(detail AuthorizationDetail) interface{} {
+	// round trip detail to normalize values
+	data, _ := json.Marshal(detail)
+	var value interface{}
+	_ = json.Unmarshal(data, &value)
+
+	return value
+}
+
+func encodeAuthorizationDetails(details []AuthorizationDetail) string {
+	// check details
+	if len(details) == 0 {
+		return ""
+	}
+
+	// encode details
+	data, _ := json.Marshal(details)
+
+	return string(data)
+}
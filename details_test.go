@@ -0,0 +1,92 @@
+package oauth2
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthorizationDetailJSON(t *testing.T) {
+	detail := AuthorizationDetail{
+		Type:      "payment_initiation",
+		Locations: []string{"https://example.com/payments"},
+		Actions:   []string{"initiate", "status"},
+		Extra: map[string]interface{}{
+			"type": "foo",
+			"instructedAmount": map[string]interface{}{
+				"currency": "EUR",
+				"amount":   "123.50",
+			},
+		},
+	}
+
+	data, err := json.Marshal(detail)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"type": "payment_initiation",
+		"locations": ["https://example.com/payments"],
+		"actions": ["initiate", "status"],
+		"instructedAmount": {
+			"currency": "EUR",
+			"amount": "123.50"
+		}
+	}`, string(data))
+
+	var detail2 AuthorizationDetail
+	err = json.Unmarshal(data, &detail2)
+	assert.NoError(t, err)
+	assert.Equal(t, AuthorizationDetail{
+		Type:      "payment_initiation",
+		Locations: []string{"https://example.com/payments"},
+		Actions:   []string{"initiate", "status"},
+		Extra: map[string]interface{}{
+			"instructedAmount": map[string]interface{}{
+				"currency": "EUR",
+				"amount":   "123.50",
+			},
+		},
+	}, detail2)
+
+	data, err = json.Marshal(AuthorizationDetail{Type: "foo"})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"type": "foo"}`, string(data))
+}
+
+func TestParseAuthorizationDetails(t *testing.T) {
+	details, err := ParseAuthorizationDetails("")
+	assert.NoError(t, err)
+	assert.Empty(t, details)
+
+	details, err = ParseAuthorizationDetails(`[{"type":"foo","actions":["read"]},{"type":"bar","baz":1}]`)
+	assert.NoError(t, err)
+	assert.Equal(t, []AuthorizationDetail{
+		{Type: "foo", Actions: []string{"read"}},
+		{Type: "bar", Extra: map[string]interface{}{"baz": float64(1)}},
+	}, details)
+
+	details, err = ParseAuthorizationDetails(`{"type":"foo"}`)
+	assert.EqualError(t, err, "invalid_authorization_details: malformed authorization details")
+	assert.Empty(t, details)
+
+	details, err = ParseAuthorizationDetails(`[{"actions":["read"]}]`)
+	assert.EqualError(t, err, "invalid_authorization_details: missing authorization detail type")
+	assert.Empty(t, details)
+}
+
+func TestIncludesAuthorizationDetails(t *testing.T) {
+	granted := []AuthorizationDetail{
+		{Type: "foo", Actions: []string{"read"}},
+		{Type: "bar", Extra: map[string]interface{}{"baz": 1}},
+	}
+
+	assert.True(t, IncludesAuthorizationDetails(granted, nil))
+	assert.True(t, IncludesAuthorizationDetails(granted, granted[:1]))
+	assert.True(t, IncludesAuthorizationDetails(granted, []AuthorizationDetail{
+		{Type: "bar", Extra: map[string]interface{}{"baz": float64(1)}},
+	}))
+	assert.False(t, IncludesAuthorizationDetails(granted, []AuthorizationDetail{
+		{Type: "foo", Actions: []string{"write"}},
+	}))
+	assert.False(t, IncludesAuthorizationDetails(nil, granted))
+}
@@ -0,0 +1,62 @@
+package oauth2
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// WriteDocument will encode the specified object as json and write it as a
+// cacheable document like a discovery document or key set. The response
+// carries an ETag derived from the content and a Cache-Control header with
+// the specified maximum age. Conditional requests with a matching
+// If-None-Match header are answered with 304 Not Modified.
+func WriteDocument(w http.ResponseWriter, r *http.Request, obj interface{}, maxAge time.Duration) error {
+	// encode document
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+
+	// compute etag
+	sum := sha256.Sum256(data)
+	etag := `"` + b64.EncodeToString(sum[:16]) + `"`
+
+	// set headers
+	w.Header().Set("ETag", etag)
+	if maxAge > 0 {
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(maxAge/time.Second)))
+	} else {
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+
+	// check condition
+	if matchETag(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+
+	// set content type
+	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+
+	// write document
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(data)
+
+	return err
+}
+
+func matchETag(header, etag string) bool {
+	// check tags (weak comparison)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+
+	return false
+}
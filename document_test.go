@@ -0,0 +1,97 @@
+package oauth2
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteDocument(t *testing.T) {
+	doc := map[string]string{"foo": "bar"}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/jwks", nil)
+	assert.NoError(t, WriteDocument(rec, req, doc, time.Hour))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "public, max-age=3600", rec.Header().Get("Cache-Control"))
+	assert.JSONEq(t, `{"foo":"bar"}`, rec.Body.String())
+
+	etag := rec.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	for _, header := range []string{etag, "W/" + etag, `"foo", ` + etag, "*"} {
+		rec = httptest.NewRecorder()
+		req.Header.Set("If-None-Match", header)
+		assert.NoError(t, WriteDocument(rec, req, doc, time.Hour))
+		assert.Equal(t, http.StatusNotModified, rec.Code, header)
+		assert.Equal(t, etag, rec.Header().Get("ETag"))
+		assert.Empty(t, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	req.Header.Set("If-None-Match", etag)
+	assert.NoError(t, WriteDocument(rec, req, map[string]string{"foo": "baz"}, 0))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "no-cache", rec.Header().Get("Cache-Control"))
+	assert.NotEqual(t, etag, rec.Header().Get("ETag"))
+}
+
+func TestValidatorConditionalRefresh(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	var requests, conditional int
+	record := func(w http.ResponseWriter, r *http.Request, obj interface{}) {
+		requests++
+		if r.Header.Get("If-None-Match") != "" {
+			conditional++
+		}
+		_ = WriteDocument(w, r, obj, time.Minute)
+	}
+
+	var issuer string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		record(w, r, map[string]string{
+			"issuer":   issuer,
+			"jwks_uri": issuer + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		record(w, r, map[string]interface{}{
+			"keys": []map[string]string{
+				{
+					"kty": "RSA",
+					"kid": "rsa",
+					"n":   b64.EncodeToString(key.N.Bytes()),
+					"e":   b64.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+				},
+			},
+		})
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	issuer = server.URL
+
+	validator := NewValidator(ValidatorConfig{
+		Issuer: issuer,
+	})
+
+	assert.NoError(t, validator.refresh(context.Background(), time.Now()))
+	assert.Equal(t, 2, requests)
+	assert.Equal(t, 0, conditional)
+	assert.Len(t, validator.keys, 1)
+
+	assert.NoError(t, validator.refresh(context.Background(), time.Now()))
+	assert.Equal(t, 4, requests)
+	assert.Equal(t, 2, conditional)
+	assert.Len(t, validator.keys, 1)
+}
@@ -2,6 +2,7 @@ package oauth2
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -25,6 +26,14 @@ type Error struct {
 	Headers     map[string]string `json:"-"`
 	RedirectURI string            `json:"-"`
 	UseFragment bool              `json:"-"`
+
+	// The status used when the error is redirected. Defaults to 303 See
+	// Other (see WriteRedirectStatus).
+	RedirectStatus int `json:"-"`
+
+	// The internal cause of the error. It is never presented to the client
+	// but can be used for logging and is returned by Unwrap.
+	Cause error `json:"-"`
 }
 
 // SetRedirect marks the error to be redirected by setting the state value as
@@ -38,6 +47,30 @@ func (e *Error) SetRedirect(uri, state string, useFragment bool) *Error {
 	return e
 }
 
+// SetCause sets the internal cause of the error.
+func (e *Error) SetCause(err error) *Error {
+	e.Cause = err
+
+	return e
+}
+
+// Redact returns a copy of the error with the description redacted using the
+// specified redactor.
+func (e *Error) Redact(redactor *Redactor) *Error {
+	// copy error
+	err := *e
+
+	// redact description
+	err.Description = redactor.Redact(err.Description)
+
+	return &err
+}
+
+// Unwrap returns the internal cause of the error.
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
 // String implements the fmt.Stringer interface.
 func (e *Error) String() string {
 	return fmt.Sprintf("%s: %s", e.Name, e.Description)
@@ -150,6 +183,17 @@ func InvalidScope(description string) *Error {
 	}
 }
 
+// InvalidAuthorizationDetails constructs an error that indicates that the
+// requested authorization details are malformed, of an unknown type or exceed
+// the authorization details granted by the resource owner (RFC 9396).
+func InvalidAuthorizationDetails(description string) *Error {
+	return &Error{
+		Status:      http.StatusBadRequest,
+		Name:        "invalid_authorization_details",
+		Description: description,
+	}
+}
+
 // InvalidToken constructs and error that indicates that the access token
 // provided is expired, revoked, malformed, or invalid for
 // other reasons.
@@ -253,28 +297,33 @@ func TemporarilyUnavailable(description string) *Error {
 	}
 }
 
-// WriteError will write the specified error to the response writer. The function
-// will fall back and write a server error if the specified error is not known.
-// If the RedirectURI field is present on the error a redirection will be written
-// instead.
+// WriteError will write the specified error to the response writer. Wrapped
+// errors are unwrapped using errors.As. The function will fall back and write a
+// server error if the specified error is not known. If the RedirectURI field is
+// present on the error a redirection will be written instead.
 func WriteError(w http.ResponseWriter, err error) error {
+	return WriteErrorToSink(HTTPSink{w}, err)
+}
+
+// WriteErrorToSink will write an error like WriteError to the specified sink.
+func WriteErrorToSink(sink ResponseSink, err error) error {
 	// ensure complex error
-	anError, ok := err.(*Error)
-	if !ok {
-		anError = ServerError("")
+	var anError *Error
+	if !errors.As(err, &anError) {
+		anError = ServerError("").SetCause(err)
 	}
 
 	// add headers
 	for k, v := range anError.Headers {
-		w.Header().Set(k, v)
+		sink.Header().Set(k, v)
 	}
 
 	// redirect error if requested
 	if anError.RedirectURI != "" {
-		return WriteRedirect(w, anError.RedirectURI, anError.Map(), anError.UseFragment)
+		return WriteRedirectToSink(sink, anError.RedirectURI, anError.Map(), anError.UseFragment, anError.RedirectStatus)
 	}
 
-	return Write(w, anError, anError.Status)
+	return WriteToSink(sink, anError, anError.Status)
 }
 
 // ParseRequestError will try to parse an oauth2.Error from the provided
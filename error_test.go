@@ -2,6 +2,7 @@ package oauth2
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -19,6 +20,7 @@ func TestErrorBuilders(t *testing.T) {
 		{InvalidClient("foo"), "invalid_client", http.StatusUnauthorized},
 		{InvalidGrant("foo"), "invalid_grant", http.StatusBadRequest},
 		{InvalidScope("foo"), "invalid_scope", http.StatusBadRequest},
+		{InvalidAuthorizationDetails("foo"), "invalid_authorization_details", http.StatusBadRequest},
 		{InvalidToken("foo"), "invalid_token", http.StatusUnauthorized},
 		{UnauthorizedClient("foo"), "unauthorized_client", http.StatusBadRequest},
 		{UnsupportedGrantType("foo"), "unsupported_grant_type", http.StatusBadRequest},
@@ -48,6 +50,29 @@ func TestError(t *testing.T) {
 	}, err.Map())
 }
 
+func TestErrorCause(t *testing.T) {
+	cause := errors.New("secret details")
+
+	err := InvalidRequest("foo").SetCause(cause)
+	assert.Equal(t, cause, err.Cause)
+	assert.Equal(t, cause, errors.Unwrap(err))
+	assert.True(t, errors.Is(err, cause))
+	assert.Equal(t, "invalid_request: foo", err.Error())
+	assert.NotContains(t, err.Params(), "secret")
+
+	rec := httptest.NewRecorder()
+
+	err2 := WriteError(rec, err)
+	assert.NoError(t, err2)
+	assert.NotContains(t, rec.Body.String(), "secret")
+
+	rec = httptest.NewRecorder()
+
+	err2 = WriteError(rec, err.SetRedirect("http://example.com", "", false))
+	assert.NoError(t, err2)
+	assert.NotContains(t, rec.Header().Get("Location"), "secret")
+}
+
 func TestErrorMap(t *testing.T) {
 	err := InvalidRequest("foo")
 	err.URI = "http://example.com"
@@ -105,6 +130,15 @@ func TestWriteErrorAsRedirect(t *testing.T) {
 	assert.NoError(t, err2)
 	assert.Equal(t, http.StatusSeeOther, rec.Code)
 	assert.Equal(t, "http://example.com?error=invalid_request&error_description=foo&state=bar", rec.Header().Get("Location"))
+
+	err1 = AccessDenied("").SetRedirect("http://example.com", "", true)
+	err1.RedirectStatus = http.StatusFound
+	rec = httptest.NewRecorder()
+
+	err2 = WriteError(rec, err1)
+	assert.NoError(t, err2)
+	assert.Equal(t, http.StatusFound, rec.Code)
+	assert.Equal(t, "http://example.com#error=access_denied", rec.Header().Get("Location"))
 }
 
 func TestWriteErrorFallback(t *testing.T) {
@@ -119,6 +153,24 @@ func TestWriteErrorFallback(t *testing.T) {
 	}`, rec.Body.String())
 }
 
+func TestWriteErrorWrapped(t *testing.T) {
+	cause := errors.New("foo")
+	err1 := fmt.Errorf("bar: %w", InvalidRequest("baz").SetCause(cause))
+	rec := httptest.NewRecorder()
+
+	err2 := WriteError(rec, err1)
+	assert.NoError(t, err2)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.JSONEq(t, `{
+		"error": "invalid_request",
+		"error_description": "baz"
+	}`, rec.Body.String())
+
+	var anError *Error
+	assert.True(t, errors.As(err1, &anError))
+	assert.True(t, errors.Is(err1, cause))
+}
+
 func TestParseRequestError(t *testing.T) {
 	it := InvalidToken("test")
 
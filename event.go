@@ -0,0 +1,71 @@
+package oauth2
+
+import "time"
+
+// ServerEventType denotes the type of server event.
+type ServerEventType string
+
+// The available server event types.
+const (
+	TokenIssuedEvent          ServerEventType = "token_issued"
+	TokenRevokedEvent         ServerEventType = "token_revoked"
+	AuthenticationFailedEvent ServerEventType = "authentication_failed"
+	ClientDisabledEvent       ServerEventType = "client_disabled"
+	CredentialChangedEvent    ServerEventType = "credential_changed"
+)
+
+// ServerEvent describes a security relevant action of the server.
+type ServerEvent struct {
+	Type      ServerEventType `json:"type"`
+	Time      time.Time       `json:"time"`
+	ClientID  string          `json:"client_id,omitempty"`
+	Username  string          `json:"username,omitempty"`
+	TokenType string          `json:"token_type,omitempty"`
+	Scope     Scope           `json:"scope,omitempty"`
+	Reason    string          `json:"reason,omitempty"`
+
+	// The fingerprint of the issued or revoked token (see Fingerprint).
+	Fingerprint string `json:"fingerprint,omitempty"`
+}
+
+// ServerEventHandler is called by the server for every emitted event. It is
+// called while the server is locked and must not call back into the server.
+type ServerEventHandler interface {
+	HandleEvent(event ServerEvent)
+}
+
+// ServerEventHandlerFunc is a function that implements the ServerEventHandler
+// interface.
+type ServerEventHandlerFunc func(event ServerEvent)
+
+// HandleEvent implements the ServerEventHandler interface.
+func (f ServerEventHandlerFunc) HandleEvent(event ServerEvent) {
+	f(event)
+}
+
+func (s *Server) emit(event ServerEvent) {
+	// check handler
+	if s.Config.EventHandler == nil {
+		return
+	}
+
+	// set time
+	if event.Time.IsZero() {
+		event.Time = s.now()
+	}
+
+	// redact reason
+	event.Reason = s.redactor().Redact(event.Reason)
+
+	// handle event
+	s.Config.EventHandler.HandleEvent(event)
+}
+
+func (s *Server) redactor() *Redactor {
+	// get redactor
+	if s.Config.Redactor != nil {
+		return s.Config.Redactor
+	}
+
+	return DefaultRedactor
+}
@@ -0,0 +1,279 @@
+package oauth2
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ServerExchange is a raw HTTP exchange handled by the server that has been
+// recorded in debug mode. Credentials in headers and bodies are masked. Form,
+// multipart and JSON bodies are parsed to mask known secret parameters,
+// multipart bodies are recorded form encoded without their files. Plain text
+// and HTML bodies are only redacted and all other bodies are dropped.
+type ServerExchange struct {
+	Time           time.Time
+	Method         string
+	URL            string
+	RequestHeader  http.Header
+	RequestBody    string
+	Status         int
+	ResponseHeader http.Header
+	ResponseBody   string
+}
+
+// the maximum number of recorded body bytes
+const exchangeBodyLimit = 64 << 10
+
+// the headers whose values are always masked
+var exchangeSecretHeaders = []string{"Authorization", "Cookie", "Set-Cookie", InstanceProofHeader}
+
+// the body parameters whose values are always masked
+var exchangeSecretParams = []string{
+	"password", "client_secret", "client_assertion", "assertion", "code",
+	"code_verifier", "pre-authorized_code", "tx_code", "token", "access_token",
+	"refresh_token", "id_token", "subject_token", "actor_token",
+}
+
+type exchangeRecorder struct {
+	exchanges []ServerExchange
+	next      int
+	mutex     sync.Mutex
+}
+
+func (c *exchangeRecorder) capture(w http.ResponseWriter, r *http.Request, now time.Time, size int, redactor *Redactor) (http.ResponseWriter, func()) {
+	// read and restore body
+	var body []byte
+	if r.Body != nil {
+		body, _ = ioutil.ReadAll(r.Body)
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	// use parsed form if the body has already been consumed
+	contentType := r.Header.Get("Content-Type")
+	if len(body) == 0 && len(r.PostForm) > 0 {
+		body = []byte(r.PostForm.Encode())
+		contentType = "application/x-www-form-urlencoded"
+	}
+
+	// prepare exchange
+	exchange := ServerExchange{
+		Time:          now,
+		Method:        r.Method,
+		URL:           redactor.Redact(r.URL.String()),
+		RequestHeader: maskHeader(r.Header, redactor),
+		RequestBody:   maskBody(body, contentType, redactor),
+	}
+
+	// wrap writer
+	rec := &exchangeWriter{ResponseWriter: w}
+
+	return rec, func() {
+		// finish exchange
+		exchange.Status = rec.status
+		if exchange.Status == 0 {
+			exchange.Status = http.StatusOK
+		}
+		exchange.ResponseHeader = maskHeader(w.Header(), redactor)
+		exchange.ResponseBody = maskBody(rec.body.Bytes(), w.Header().Get("Content-Type"), redactor)
+
+		// record exchange
+		c.record(exchange, size)
+	}
+}
+
+func (c *exchangeRecorder) record(exchange ServerExchange, size int) {
+	// acquire mutex
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// append exchange until full
+	if len(c.exchanges) < size {
+		c.exchanges = append(c.exchanges, exchange)
+		return
+	}
+
+	// otherwise, overwrite oldest exchange
+	c.exchanges[c.next] = exchange
+	c.next = (c.next + 1) % len(c.exchanges)
+}
+
+func (c *exchangeRecorder) snapshot() []ServerExchange {
+	// acquire mutex
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// copy exchanges oldest first
+	list := make([]ServerExchange, 0, len(c.exchanges))
+	list = append(list, c.exchanges[c.next:]...)
+	list = append(list, c.exchanges[:c.next]...)
+
+	return list
+}
+
+type exchangeWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *exchangeWriter) WriteHeader(status int) {
+	// keep first status
+	if w.status == 0 {
+		w.status = status
+	}
+
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *exchangeWriter) Write(data []byte) (int, error) {
+	// capture data up to limit
+	if n := exchangeBodyLimit - w.body.Len(); n > 0 {
+		if len(data) < n {
+			n = len(data)
+		}
+		w.body.Write(data[:n])
+	}
+
+	return w.ResponseWriter.Write(data)
+}
+
+func maskHeader(header http.Header, redactor *Redactor) http.Header {
+	// copy and mask header
+	masked := http.Header{}
+	for name, values := range header {
+		for _, value := range values {
+			if containsString(exchangeSecretHeaders, http.CanonicalHeaderKey(name)) {
+				value = redactor.mask()
+			} else {
+				value = redactor.Redact(value)
+			}
+			masked.Add(name, value)
+		}
+	}
+
+	return masked
+}
+
+func maskBody(body []byte, contentType string, redactor *Redactor) string {
+	// check body
+	if len(body) == 0 {
+		return ""
+	}
+
+	// truncate body
+	if len(body) > exchangeBodyLimit {
+		body = body[:exchangeBodyLimit]
+	}
+
+	// get media type
+	mediaType, params, _ := mime.ParseMediaType(contentType)
+
+	// mask body
+	var ok bool
+	switch {
+	case mediaType == "application/x-www-form-urlencoded":
+		body, ok = maskFormBody(body, redactor)
+	case mediaType == "multipart/form-data":
+		body, ok = maskMultipartBody(body, params["boundary"], redactor)
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		body, ok = maskJSONBody(body, redactor)
+	case mediaType == "text/plain" || mediaType == "text/html":
+		ok = true
+	}
+
+	// drop body if it could not be masked
+	if !ok {
+		return redactor.mask()
+	}
+
+	return redactor.Redact(string(body))
+}
+
+func maskFormBody(body []byte, redactor *Redactor) ([]byte, bool) {
+	// parse form
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, false
+	}
+
+	// mask parameters
+	maskValues(values, redactor)
+
+	return []byte(values.Encode()), true
+}
+
+func maskMultipartBody(body []byte, boundary string, redactor *Redactor) ([]byte, bool) {
+	// check boundary
+	if boundary == "" {
+		return nil, false
+	}
+
+	// parse form, truncated bodies fail here
+	form, err := multipart.NewReader(bytes.NewReader(body), boundary).ReadForm(exchangeBodyLimit)
+	if err != nil {
+		return nil, false
+	}
+	defer form.RemoveAll()
+
+	// mask parameters
+	values := url.Values(form.Value)
+	maskValues(values, redactor)
+
+	return []byte(values.Encode()), true
+}
+
+func maskJSONBody(body []byte, redactor *Redactor) ([]byte, bool) {
+	// parse value
+	var value interface{}
+	err := json.Unmarshal(body, &value)
+	if err != nil {
+		return nil, false
+	}
+
+	// mask value
+	value = maskJSONValue(value, redactor)
+
+	// encode value
+	body, err = json.Marshal(value)
+	if err != nil {
+		return nil, false
+	}
+
+	return body, true
+}
+
+func maskJSONValue(value interface{}, redactor *Redactor) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, item := range value {
+			if containsString(exchangeSecretParams, key) {
+				value[key] = redactor.mask()
+			} else {
+				value[key] = maskJSONValue(item, redactor)
+			}
+		}
+	case []interface{}:
+		for i, item := range value {
+			value[i] = maskJSONValue(item, redactor)
+		}
+	}
+
+	return value
+}
+
+func maskValues(values url.Values, redactor *Redactor) {
+	// mask parameters
+	for _, param := range exchangeSecretParams {
+		if _, ok := values[param]; ok {
+			values.Set(param, redactor.mask())
+		}
+	}
+}
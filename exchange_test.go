@@ -0,0 +1,121 @@
+package oauth2
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/256dpi/oauth2/v2/oauth2test"
+)
+
+func TestServerExchanges(t *testing.T) {
+	server := newTestServer()
+	server.Config.DebugExchanges = 2
+
+	for i := 0; i < 3; i++ {
+		res := oauth2test.Do(server, &oauth2test.Request{
+			Method:   "POST",
+			Path:     "/oauth2/token",
+			Username: "client1",
+			Password: "foo",
+			Form: map[string]string{
+				"grant_type": PasswordGrantType,
+				"scope":      "foo",
+				"username":   "user1",
+				"password":   "foo",
+			},
+		})
+		assert.Equal(t, http.StatusOK, res.Status)
+	}
+
+	res := oauth2test.Do(server, &oauth2test.Request{
+		Method: "GET",
+		Path:   "/oauth2/foo",
+	})
+	assert.Equal(t, http.StatusNotFound, res.Status)
+
+	exchanges := server.Exchanges()
+	assert.Len(t, exchanges, 2)
+
+	exchange := exchanges[0]
+	assert.Equal(t, "POST", exchange.Method)
+	assert.Equal(t, "/oauth2/token", exchange.URL)
+	assert.Equal(t, "[redacted]", exchange.RequestHeader.Get("Authorization"))
+	assert.Contains(t, exchange.RequestBody, "grant_type=password")
+	assert.Contains(t, exchange.RequestBody, "password=%5Bredacted%5D")
+	assert.NotContains(t, exchange.RequestBody, "password=foo")
+	assert.Equal(t, http.StatusOK, exchange.Status)
+	assert.Equal(t, "application/json;charset=UTF-8", exchange.ResponseHeader.Get("Content-Type"))
+	assert.Contains(t, exchange.ResponseBody, `"access_token":"[redacted]"`)
+	assert.Contains(t, exchange.ResponseBody, `"token_type":"bearer"`)
+
+	exchange = exchanges[1]
+	assert.Equal(t, "GET", exchange.Method)
+	assert.Equal(t, "/oauth2/foo", exchange.URL)
+	assert.Equal(t, http.StatusNotFound, exchange.Status)
+	assert.Contains(t, exchange.ResponseBody, "404 page not found")
+}
+
+func TestServerExchangesMultipart(t *testing.T) {
+	server := newTestServer()
+	server.Config.DebugExchanges = 1
+	server.Config.AllowMultipartTokenRequests = true
+
+	req := newMultipartRequest(map[string]string{
+		"grant_type":    PasswordGrantType,
+		"scope":         "foo",
+		"username":      "user1",
+		"password":      "foo",
+		"client_id":     "client1",
+		"client_secret": "foo",
+	})
+	req.URL.Path = "/oauth2/token"
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	exchanges := server.Exchanges()
+	assert.Len(t, exchanges, 1)
+
+	exchange := exchanges[0]
+	assert.Contains(t, exchange.RequestBody, "grant_type=password")
+	assert.Contains(t, exchange.RequestBody, "password=%5Bredacted%5D")
+	assert.Contains(t, exchange.RequestBody, "client_secret=%5Bredacted%5D")
+	assert.NotContains(t, exchange.RequestBody, "password=foo")
+	assert.NotContains(t, exchange.RequestBody, "client_secret=foo")
+	assert.Contains(t, exchange.ResponseBody, `"access_token":"[redacted]"`)
+}
+
+func TestMaskBody(t *testing.T) {
+	body := maskBody([]byte(`{"tx_code":"1234","nested":{"client_assertion":"x"},"scope":"foo"}`), "application/json", nil)
+	assert.JSONEq(t, `{"tx_code":"[redacted]","nested":{"client_assertion":"[redacted]"},"scope":"foo"}`, body)
+
+	body = maskBody([]byte("tx_code=1234&grant_type=foo"), "application/x-www-form-urlencoded", nil)
+	assert.Equal(t, "grant_type=foo&tx_code=%5Bredacted%5D", body)
+
+	body = maskBody([]byte(`{"password":"foo"`), "application/json", nil)
+	assert.Equal(t, "[redacted]", body)
+
+	body = maskBody([]byte("password=foo"), "application/octet-stream", nil)
+	assert.Equal(t, "[redacted]", body)
+
+	body = maskBody([]byte("--x\r\npassword"), "multipart/form-data; boundary=x", nil)
+	assert.Equal(t, "[redacted]", body)
+
+	body = maskBody([]byte("404 page not found"), "text/plain; charset=utf-8", nil)
+	assert.Equal(t, "404 page not found", body)
+}
+
+func TestServerExchangesDisabled(t *testing.T) {
+	server := newTestServer()
+
+	res := oauth2test.Do(server, &oauth2test.Request{
+		Method: "GET",
+		Path:   "/oauth2/foo",
+	})
+	assert.Equal(t, http.StatusNotFound, res.Status)
+	assert.Empty(t, server.Exchanges())
+}
@@ -0,0 +1,24 @@
+package oauth2
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+)
+
+// Fingerprint returns a short and stable fingerprint of the specified token.
+// The fingerprint is the hex encoded prefix of the SHA-256 hash of the token
+// and can be logged or used as a metrics key to correlate a token across
+// systems without revealing the token itself.
+func Fingerprint(token string) string {
+	// hash token
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:8])
+}
+
+// MatchFingerprint returns whether the fingerprint belongs to the specified
+// token.
+func MatchFingerprint(token, fingerprint string) bool {
+	return subtle.ConstantTimeCompare([]byte(Fingerprint(token)), []byte(fingerprint)) == 1
+}
@@ -0,0 +1,55 @@
+package oauth2
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFingerprint(t *testing.T) {
+	fp := Fingerprint("foo")
+	assert.Equal(t, "2c26b46b68ffc68f", fp)
+	assert.Equal(t, fp, Fingerprint("foo"))
+	assert.NotEqual(t, fp, Fingerprint("bar"))
+	assert.Equal(t, "foo 2c26b46b68ffc68f", DefaultRedactor.Redact("foo "+fp))
+
+	assert.True(t, MatchFingerprint("foo", fp))
+	assert.False(t, MatchFingerprint("bar", fp))
+	assert.False(t, MatchFingerprint("foo", ""))
+}
+
+func TestServerFingerprint(t *testing.T) {
+	server := newTestServer()
+
+	var events []ServerEvent
+	server.Config.EventHandler = ServerEventHandlerFunc(func(event ServerEvent) {
+		events = append(events, event)
+	})
+
+	res, err := server.issueTokens(true, Scope{"foo"}, "client1", "user1", "")
+	assert.NoError(t, err)
+	server.issueTokens(true, Scope{"foo"}, "client1", "user1", "")
+	assert.Len(t, events, 4)
+	assert.Equal(t, Fingerprint(res.AccessToken), events[0].Fingerprint)
+	assert.Equal(t, Fingerprint(res.RefreshToken), events[1].Fingerprint)
+
+	items, _, err := server.ListAccessTokens(ServerQuery{
+		Fingerprint: Fingerprint(res.AccessToken),
+	})
+	assert.NoError(t, err)
+	assert.Len(t, items, 1)
+	assert.Equal(t, Fingerprint(res.AccessToken), items[0].Credential.Fingerprint)
+
+	err = server.Revoke(RevocationTask{
+		ClientID: "client1",
+		Token:    res.AccessToken,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, events, 5)
+	assert.Equal(t, TokenRevokedEvent, events[4].Type)
+	assert.Equal(t, Fingerprint(res.AccessToken), events[4].Fingerprint)
+
+	assert.Equal(t, 3, server.RevokeUserTokens("user1"))
+	assert.Len(t, events, 8)
+	assert.Contains(t, events[5].Fingerprint+events[6].Fingerprint+events[7].Fingerprint, Fingerprint(res.RefreshToken))
+}
@@ -0,0 +1,167 @@
+package oauth2
+
+import (
+	"crypto"
+	"crypto/hmac"
+	_ "crypto/sha256" // register hash
+	_ "crypto/sha512" // register hash
+	"errors"
+	"io"
+	"strings"
+)
+
+// HMACToken implements a simple abstraction around generating tokens using
+// the hmac algorithm with a configurable hash (e.g. SHA-256, SHA-384 or
+// SHA-512).
+type HMACToken struct {
+	Hash      crypto.Hash
+	Key       []byte
+	Signature []byte
+}
+
+// HMACTokenFromKey will return a new hmac token that is constructed using the
+// specified hash, secret and key.
+//
+// Note: The secret and the token key should both at least have a length of 16
+// characters to be considered unguessable.
+func HMACTokenFromKey(hash crypto.Hash, secret []byte, key []byte) *HMACToken {
+	return &HMACToken{
+		Hash:      hash,
+		Key:       key,
+		Signature: hmacSignature(hash, secret, key),
+	}
+}
+
+// GenerateHMACToken will return a new hmac token that is constructed using
+// the specified hash, secret and random key of the specified length.
+//
+// Note: The secret and the to be generated token key should both at least have
+// a length of 16 characters to be considered unguessable.
+func GenerateHMACToken(hash crypto.Hash, secret []byte, length int) (*HMACToken, error) {
+	// check hash
+	if !hash.Available() {
+		return nil, errors.New("hash function is not available")
+	}
+
+	// generate key
+	key, err := generateKey(length)
+	if err != nil {
+		return nil, err
+	}
+
+	return HMACTokenFromKey(hash, secret, key), nil
+}
+
+// MustGenerateHMACToken will generate a token using GenerateHMACToken and
+// panic instead of returning an error.
+func MustGenerateHMACToken(hash crypto.Hash, secret []byte, length int) *HMACToken {
+	token, err := GenerateHMACToken(hash, secret, length)
+	if err != nil {
+		panic(err)
+	}
+
+	return token
+}
+
+// ParseHMACToken will parse a token that is in its string representation and
+// has been signed using the specified hash.
+func ParseHMACToken(hash crypto.Hash, secret []byte, str string) (*HMACToken, error) {
+	// check hash
+	if !hash.Available() {
+		return nil, errors.New("hash function is not available")
+	}
+
+	// split token
+	key, signature, err := splitToken(str)
+	if err != nil {
+		return nil, err
+	}
+
+	// construct token
+	token := &HMACToken{
+		Hash:      hash,
+		Key:       key,
+		Signature: signature,
+	}
+
+	// validate signatures
+	if !token.Valid(secret) {
+		return nil, errors.New("invalid token supplied")
+	}
+
+	return token, nil
+}
+
+// Valid returns true when the token's key matches its signature.
+func (t *HMACToken) Valid(secret []byte) bool {
+	return t.Equal(hmacSignature(t.Hash, secret, t.Key))
+}
+
+// Equal returns true then the specified signature is the same as the tokens
+// signature.
+//
+// Note: This method should be used over just comparing the byte slices as it
+// computed in constant time and limits time based attacks.
+func (t *HMACToken) Equal(signature []byte) bool {
+	return hmac.Equal(t.Signature, signature)
+}
+
+// KeyString returns a string (base64) representation of the key.
+func (t *HMACToken) KeyString() string {
+	return b64.EncodeToString(t.Key)
+}
+
+// SignatureString returns a string (base64) representation of the signature.
+func (t *HMACToken) SignatureString() string {
+	return b64.EncodeToString(t.Signature)
+}
+
+// String returns a string representation of the whole token.
+func (t *HMACToken) String() string {
+	return t.KeyString() + "." + t.SignatureString()
+}
+
+func hmacSignature(hash crypto.Hash, secret, key []byte) []byte {
+	// create hash
+	h := hmac.New(hash.New, secret)
+
+	// hash key - implementation does never return an error
+	_, _ = h.Write(key)
+
+	return h.Sum(nil)
+}
+
+func generateKey(length int) ([]byte, error) {
+	// prepare key
+	key := make([]byte, length)
+
+	// read random bytes
+	_, err := io.ReadFull(randSource, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+func splitToken(str string) ([]byte, []byte, error) {
+	// split dot separated key and signature
+	s := strings.Split(str, ".")
+	if len(s) != 2 {
+		return nil, nil, errors.New("a token must have two segments separated by a dot")
+	}
+
+	// decode key
+	key, err := b64.DecodeString(s[0])
+	if err != nil {
+		return nil, nil, errors.New("token key is not base64 encoded")
+	}
+
+	// decode signature
+	signature, err := b64.DecodeString(s[1])
+	if err != nil {
+		return nil, nil, errors.New("token signature is not base64 encoded")
+	}
+
+	return key, signature, nil
+}
@@ -0,0 +1,76 @@
+package oauth2
+
+import (
+	"crypto"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHMACToken(t *testing.T) {
+	for _, hash := range []crypto.Hash{crypto.SHA256, crypto.SHA384, crypto.SHA512} {
+		token1, err := GenerateHMACToken(hash, testSecret, 16)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, token1.Key)
+		assert.Len(t, token1.Signature, hash.Size())
+		assert.NotEmpty(t, token1.String())
+
+		token2, err := ParseHMACToken(hash, testSecret, token1.String())
+		assert.NoError(t, err)
+		assert.Equal(t, token1.Key, token2.Key)
+		assert.Equal(t, token1.Signature, token2.Signature)
+
+		token2, err = ParseHMACToken(hash, testSecret, token1.String()+"foo")
+		assert.Error(t, err)
+		assert.Nil(t, token2)
+	}
+
+	token1 := MustGenerateHMACToken(crypto.SHA512, testSecret, 16)
+	token2, err := ParseHMACToken(crypto.SHA256, testSecret, token1.String())
+	assert.Error(t, err)
+	assert.Nil(t, token2)
+}
+
+func TestHMACTokenCompatibility(t *testing.T) {
+	token1 := MustGenerateHS256Token(testSecret, 16)
+	token2 := HMACTokenFromKey(crypto.SHA256, testSecret, token1.Key)
+	assert.Equal(t, token1.String(), token2.String())
+}
+
+func TestParseHMACToken(t *testing.T) {
+	token, err := ParseHMACToken(crypto.SHA512, testSecret, "")
+	assert.Error(t, err)
+	assert.Nil(t, token)
+
+	token, err = ParseHMACToken(crypto.SHA512, testSecret, "%.foo")
+	assert.Error(t, err)
+	assert.Nil(t, token)
+
+	token, err = ParseHMACToken(crypto.SHA512, testSecret, "foo.%")
+	assert.Error(t, err)
+	assert.Nil(t, token)
+
+	token, err = ParseHMACToken(crypto.MD4, testSecret, "foo.bar")
+	assert.EqualError(t, err, "hash function is not available")
+	assert.Nil(t, token)
+}
+
+func TestGenerateHMACTokenError(t *testing.T) {
+	token, err := GenerateHMACToken(crypto.MD4, testSecret, 16)
+	assert.EqualError(t, err, "hash function is not available")
+	assert.Nil(t, token)
+
+	currentSource := randSource
+	randSource = strings.NewReader("")
+
+	token, err = GenerateHMACToken(crypto.SHA384, testSecret, 16)
+	assert.Error(t, err)
+	assert.Nil(t, token)
+
+	assert.Panics(t, func() {
+		MustGenerateHMACToken(crypto.SHA384, testSecret, 16)
+	})
+
+	randSource = currentSource
+}
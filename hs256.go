@@ -1,13 +1,11 @@
 package oauth2
 
 import (
+	"crypto"
 	"crypto/hmac"
 	"crypto/rand"
-	"crypto/sha256"
 	"encoding/base64"
 	"errors"
-	"io"
-	"strings"
 )
 
 var randSource = rand.Reader
@@ -27,22 +25,10 @@ type HS256Token struct {
 // Note: The secret and the token key should both at least have a length of 16
 // characters to be considered unguessable.
 func HS256TokenFromKey(secret []byte, key []byte) *HS256Token {
-	// create hash
-	hash := hmac.New(sha256.New, secret)
-
-	// hash key - implementation does never return an error
-	_, _ = hash.Write(key)
-
-	// get signature
-	signature := hash.Sum(nil)
-
-	// construct token
-	token := &HS256Token{
+	return &HS256Token{
 		Key:       key,
-		Signature: signature,
+		Signature: hmacSignature(crypto.SHA256, secret, key),
 	}
-
-	return token
 }
 
 // GenerateHS256Token will return a new hmac-sha256 token that is constructed
@@ -51,11 +37,8 @@ func HS256TokenFromKey(secret []byte, key []byte) *HS256Token {
 // Note: The secret and the to be generated token key should both at least have
 // a length of 16 characters to be considered unguessable.
 func GenerateHS256Token(secret []byte, length int) (*HS256Token, error) {
-	// prepare key
-	key := make([]byte, length)
-
-	// read random bytes
-	_, err := io.ReadFull(randSource, key)
+	// generate key
+	key, err := generateKey(length)
 	if err != nil {
 		return nil, err
 	}
@@ -80,22 +63,10 @@ func MustGenerateHS256Token(secret []byte, length int) *HS256Token {
 
 // ParseHS256Token will parse a token that is in its string representation.
 func ParseHS256Token(secret []byte, str string) (*HS256Token, error) {
-	// split dot separated key and signature
-	s := strings.Split(str, ".")
-	if len(s) != 2 {
-		return nil, errors.New("a token must have two segments separated by a dot")
-	}
-
-	// decode key
-	key, err := b64.DecodeString(s[0])
+	// split token
+	key, signature, err := splitToken(str)
 	if err != nil {
-		return nil, errors.New("token key is not base64 encoded")
-	}
-
-	// decode signature
-	signature, err := b64.DecodeString(s[1])
-	if err != nil {
-		return nil, errors.New("token signature is not base64 encoded")
+		return nil, err
 	}
 
 	// construct token
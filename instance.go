@@ -0,0 +1,352 @@
+package oauth2
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// InstanceProofHeader is the header that carries a client instance proof.
+// Proofs use the format of DPoP proofs (RFC 9449) and are only checked at
+// the token endpoint.
+const InstanceProofHeader = "DPoP"
+
+// InstanceProofType is the type header of client instance proofs.
+const InstanceProofType = "dpop+jwt"
+
+// InstanceProof is a verified proof that a token request was signed by the
+// key of a client instance.
+type InstanceProof struct {
+	// The JWK SHA-256 thumbprint (RFC 7638) of the instance key.
+	Thumbprint string
+
+	// The unique identifier, HTTP method, HTTP URI and issue time of the
+	// proof.
+	ID       string
+	Method   string
+	URI      string
+	IssuedAt time.Time
+
+	// The hash of the access token presented with the proof (ath), if any.
+	AccessTokenHash string
+}
+
+type instanceProofHeader struct {
+	Algorithm string      `json:"alg"`
+	Type      string      `json:"typ"`
+	Key       *JSONWebKey `json:"jwk"`
+}
+
+// GenerateInstanceProof will generate a proof for a request with the specified
+// method and URI that is signed using the ECDSA P-256 key of a client instance
+// (ES256).
+func GenerateInstanceProof(key *ecdsa.PrivateKey, method, uri string, issuedAt time.Time) (string, error) {
+	return generateInstanceProof(key, method, uri, "", issuedAt)
+}
+
+// GenerateResourceProof will generate a proof like GenerateInstanceProof that
+// additionally carries the hash of the access token presented with a request
+// to a protected resource.
+func GenerateResourceProof(key *ecdsa.PrivateKey, method, uri, accessToken string, issuedAt time.Time) (string, error) {
+	return generateInstanceProof(key, method, uri, accessToken, issuedAt)
+}
+
+// AccessTokenHash returns the base64url encoded SHA-256 hash of the specified
+// access token as used by proofs (ath).
+func AccessTokenHash(accessToken string) string {
+	sum := sha256.Sum256([]byte(accessToken))
+	return b64.EncodeToString(sum[:])
+}
+
+func generateInstanceProof(key *ecdsa.PrivateKey, method, uri, accessToken string, issuedAt time.Time) (string, error) {
+	// check curve
+	if key.Curve.Params().Name != "P-256" {
+		return "", errors.New("unsupported curve")
+	}
+
+	// generate id
+	id, err := generateKey(16)
+	if err != nil {
+		return "", err
+	}
+
+	// encode header
+	header, err := json.Marshal(map[string]interface{}{
+		"alg": "ES256",
+		"typ": InstanceProofType,
+		"jwk": map[string]string{
+			"kty": "EC",
+			"crv": "P-256",
+			"x":   b64.EncodeToString(padBytes(key.X.Bytes(), 32)),
+			"y":   b64.EncodeToString(padBytes(key.Y.Bytes(), 32)),
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// prepare claims
+	claims := map[string]interface{}{
+		"jti": b64.EncodeToString(id),
+		"htm": method,
+		"htu": uri,
+		"iat": issuedAt.Unix(),
+	}
+
+	// add access token hash
+	if accessToken != "" {
+		claims["ath"] = AccessTokenHash(accessToken)
+	}
+
+	// encode payload
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	// prepare signing input
+	input := b64.EncodeToString(header) + "." + b64.EncodeToString(payload)
+
+	// sign input
+	digest := sha256.Sum256([]byte(input))
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		return "", err
+	}
+
+	// encode signature
+	signature := append(padBytes(r.Bytes(), 32), padBytes(s.Bytes(), 32)...)
+
+	return input + "." + b64.EncodeToString(signature), nil
+}
+
+// ParseInstanceProof will parse the specified proof and verify its signature
+// using the embedded key. The method, URI and issue time must be checked by
+// the caller (see ProofChecker).
+func ParseInstanceProof(str string) (*InstanceProof, error) {
+	// split segments
+	s := strings.Split(str, ".")
+	if len(s) != 3 {
+		return nil, errors.New("a JWT must have three segments separated by a dot")
+	}
+
+	// decode header
+	data, err := b64.DecodeString(s[0])
+	if err != nil {
+		return nil, errors.New("JWT header is not base64 encoded")
+	}
+
+	// parse header
+	var header instanceProofHeader
+	err = json.Unmarshal(data, &header)
+	if err != nil {
+		return nil, errors.New("JWT header is not valid json")
+	}
+
+	// check type
+	if header.Type != InstanceProofType {
+		return nil, errors.New("JWT type mismatch")
+	}
+
+	// check key
+	if header.Key == nil {
+		return nil, errors.New("JWT key missing")
+	}
+
+	// get key
+	key, err := header.Key.publicKey()
+	if err != nil {
+		return nil, err
+	}
+
+	// decode signature
+	signature, err := b64.DecodeString(s[2])
+	if err != nil {
+		return nil, errors.New("JWT signature is not base64 encoded")
+	}
+
+	// verify signature
+	err = verifySignature(header.Algorithm, key, []byte(s[0]+"."+s[1]), signature)
+	if err != nil {
+		return nil, err
+	}
+
+	// decode payload
+	payload, err := b64.DecodeString(s[1])
+	if err != nil {
+		return nil, errors.New("JWT payload is not base64 encoded")
+	}
+
+	// parse claims
+	var claims Claims
+	err = json.Unmarshal(payload, &claims)
+	if err != nil {
+		return nil, errors.New("JWT payload is not valid json")
+	}
+
+	// check id
+	if claims.GetString("jti") == "" {
+		return nil, errors.New("JWT id missing")
+	}
+
+	// compute thumbprint
+	thumbprint, err := header.Key.thumbprint()
+	if err != nil {
+		return nil, err
+	}
+
+	return &InstanceProof{
+		Thumbprint: thumbprint,
+		ID:         claims.GetString("jti"),
+		Method:     claims.GetString("htm"),
+		URI:        claims.GetString("htu"),
+		IssuedAt:   claims.GetTime("iat"),
+
+		AccessTokenHash: claims.GetString("ath"),
+	}, nil
+}
+
+func (k JSONWebKey) thumbprint() (string, error) {
+	// get required members
+	var members map[string]string
+	switch k.KeyType {
+	case "RSA":
+		members = map[string]string{"e": k.E, "kty": k.KeyType, "n": k.N}
+	case "EC":
+		members = map[string]string{"crv": k.Curve, "kty": k.KeyType, "x": k.X, "y": k.Y}
+	default:
+		return "", errors.New("unsupported key type")
+	}
+
+	// encode members (keys are sorted)
+	data, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+
+	// hash members
+	sum := sha256.Sum256(data)
+
+	return b64.EncodeToString(sum[:]), nil
+}
+
+// ProofChecker checks that instance proofs match the request they have been
+// presented with, are fresh and are not replayed. The zero value is ready to
+// use and a checker is safe for concurrent use.
+type ProofChecker struct {
+	ids   map[string]time.Time
+	mutex sync.Mutex
+}
+
+// Check will verify that the proof has been created for a request with the
+// specified method and URL, has been issued within the maximum age around the
+// specified time and has not been presented before. The URI of the proof is
+// compared by scheme, host and path while query and fragment are ignored as
+// required by RFC 9449. The maximum age defaults to five minutes.
+func (c *ProofChecker) Check(proof *InstanceProof, method string, uri *url.URL, now time.Time, maxAge time.Duration) error {
+	// check method and uri
+	if proof.Method != method || !matchProofURI(proof.URI, uri) {
+		return errors.New("request mismatch")
+	}
+
+	// get max age
+	if maxAge == 0 {
+		maxAge = 5 * time.Minute
+	}
+
+	// check issue time
+	if proof.IssuedAt.Before(now.Add(-maxAge)) || proof.IssuedAt.After(now.Add(maxAge)) {
+		return errors.New("stale proof")
+	}
+
+	// acquire mutex
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// prepare map
+	if c.ids == nil {
+		c.ids = map[string]time.Time{}
+	}
+
+	// remove expired ids
+	for id, expiry := range c.ids {
+		if expiry.Before(now) {
+			delete(c.ids, id)
+		}
+	}
+
+	// check replay
+	if _, ok := c.ids[proof.ID]; ok {
+		return errors.New("replayed proof")
+	}
+
+	// record id
+	c.ids[proof.ID] = proof.IssuedAt.Add(maxAge)
+
+	return nil
+}
+
+// ProofURL returns the URL of the specified request that proofs are checked
+// against. The scheme is "https" for secure requests and "http" otherwise,
+// the host is taken from the Host header.
+func ProofURL(r *http.Request, secure bool) *url.URL {
+	// get scheme
+	scheme := "http"
+	if secure {
+		scheme = "https"
+	}
+
+	return &url.URL{
+		Scheme: scheme,
+		Host:   r.Host,
+		Path:   r.URL.Path,
+	}
+}
+
+func matchProofURI(str string, uri *url.URL) bool {
+	// parse uri
+	proofURI, err := url.Parse(str)
+	if err != nil || uri == nil {
+		return false
+	}
+
+	// compare scheme, host and path
+	return strings.EqualFold(proofURI.Scheme, uri.Scheme) &&
+		proofHost(proofURI) == proofHost(uri) &&
+		proofURI.Path == uri.Path
+}
+
+func proofHost(uri *url.URL) string {
+	// get host and port
+	host := strings.ToLower(uri.Hostname())
+	port := uri.Port()
+
+	// drop default port
+	scheme := strings.ToLower(uri.Scheme)
+	if port == "" || scheme == "http" && port == "80" || scheme == "https" && port == "443" {
+		return host
+	}
+
+	return net.JoinHostPort(host, port)
+}
+
+func padBytes(data []byte, size int) []byte {
+	// check length
+	if len(data) >= size {
+		return data
+	}
+
+	// pad with zeroes
+	buf := make([]byte, size)
+	copy(buf[size-len(data):], data)
+
+	return buf
+}
@@ -0,0 +1,192 @@
+package oauth2
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/256dpi/oauth2/v2/oauth2test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstanceProof(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	now := time.Now().Truncate(time.Second)
+
+	str, err := GenerateInstanceProof(key, "POST", "http://auth.server/token", now)
+	assert.NoError(t, err)
+
+	proof1, err := ParseInstanceProof(str)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, proof1.Thumbprint)
+	assert.NotEmpty(t, proof1.ID)
+	assert.Equal(t, "POST", proof1.Method)
+	assert.Equal(t, "http://auth.server/token", proof1.URI)
+	assert.True(t, now.Equal(proof1.IssuedAt))
+
+	str, err = GenerateInstanceProof(key, "POST", "http://auth.server/token", now)
+	assert.NoError(t, err)
+
+	proof2, err := ParseInstanceProof(str)
+	assert.NoError(t, err)
+	assert.Equal(t, proof1.Thumbprint, proof2.Thumbprint)
+	assert.NotEqual(t, proof1.ID, proof2.ID)
+
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	str, err = GenerateInstanceProof(otherKey, "POST", "http://auth.server/token", now)
+	assert.NoError(t, err)
+
+	proof3, err := ParseInstanceProof(str)
+	assert.NoError(t, err)
+	assert.NotEqual(t, proof1.Thumbprint, proof3.Thumbprint)
+
+	_, err = ParseInstanceProof("foo")
+	assert.EqualError(t, err, "a JWT must have three segments separated by a dot")
+
+	_, err = ParseInstanceProof(str[:len(str)-4] + "AAAA")
+	assert.EqualError(t, err, "invalid JWT signature")
+
+	_, err = ParseInstanceProof(signTestJWT(t, "ES256", "ec", key, Claims{"jti": "foo"}))
+	assert.EqualError(t, err, "JWT type mismatch")
+
+	str, err = GenerateResourceProof(key, "GET", "http://resource.server/api", "foo", now)
+	assert.NoError(t, err)
+
+	proof4, err := ParseInstanceProof(str)
+	assert.NoError(t, err)
+	assert.Equal(t, proof1.Thumbprint, proof4.Thumbprint)
+	assert.Equal(t, AccessTokenHash("foo"), proof4.AccessTokenHash)
+	assert.Empty(t, proof1.AccessTokenHash)
+
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	assert.NoError(t, err)
+
+	_, err = GenerateInstanceProof(p384, "POST", "http://auth.server/token", now)
+	assert.EqualError(t, err, "unsupported curve")
+}
+
+func TestAccessTokenHash(t *testing.T) {
+	assert.Equal(t, "fUHyO2r2Z3DZ53EsNrWBb0xWXoaNy59IiKCAqksmQEo", AccessTokenHash("Kz~8mXK1EalYznwH-LC-1fBAo.4Ljp~zsPE_NeO.gxU"))
+}
+
+func TestServerInstanceProofs(t *testing.T) {
+	server := newTestServer()
+	server.Config.InstanceProofs = true
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	proof := func(key *ecdsa.PrivateKey, issuedAt time.Time) string {
+		str, err := GenerateInstanceProof(key, "POST", "http://example.com/oauth2/token", issuedAt)
+		assert.NoError(t, err)
+		return str
+	}
+
+	refresh := func(token, proof string) *oauth2test.Response {
+		return oauth2test.Do(server, &oauth2test.Request{
+			Method: "POST",
+			Path:   "/oauth2/token",
+			Header: map[string]string{
+				InstanceProofHeader: proof,
+			},
+			Form: map[string]string{
+				"grant_type":    RefreshTokenGrantType,
+				"client_id":     "client2",
+				"refresh_token": token,
+			},
+		})
+	}
+
+	res := oauth2test.Do(server, &oauth2test.Request{
+		Method: "POST",
+		Path:   "/oauth2/token",
+		Header: map[string]string{
+			InstanceProofHeader: proof(key, time.Now()),
+		},
+		Form: map[string]string{
+			"grant_type": PasswordGrantType,
+			"client_id":  "client2",
+			"username":   "user1",
+			"password":   "foo",
+			"scope":      "foo",
+		},
+	})
+	assert.Equal(t, http.StatusOK, res.Status)
+	refreshToken := res.String("refresh_token")
+	assert.NotEmpty(t, refreshToken)
+
+	res = refresh(refreshToken, "")
+	assert.Equal(t, http.StatusBadRequest, res.Status)
+	assert.Equal(t, "invalid_grant", res.String("error"))
+
+	res = refresh(refreshToken, proof(otherKey, time.Now()))
+	assert.Equal(t, http.StatusBadRequest, res.Status)
+	assert.Equal(t, "invalid_grant", res.String("error"))
+
+	res = refresh(refreshToken, proof(key, time.Now().Add(-time.Hour)))
+	assert.Equal(t, http.StatusBadRequest, res.Status)
+	assert.Equal(t, "invalid_request", res.String("error"))
+
+	replayed := proof(key, time.Now())
+	res = refresh(refreshToken, replayed)
+	assert.Equal(t, http.StatusOK, res.Status)
+	refreshToken = res.String("refresh_token")
+	assert.NotEmpty(t, refreshToken)
+
+	res = refresh(refreshToken, replayed)
+	assert.Equal(t, http.StatusBadRequest, res.Status)
+	assert.Equal(t, "invalid_request", res.String("error"))
+
+	res = refresh(refreshToken, "")
+	assert.Equal(t, http.StatusBadRequest, res.Status)
+	assert.Equal(t, "invalid_grant", res.String("error"))
+
+	res = refresh(refreshToken, proof(key, time.Now()))
+	assert.Equal(t, http.StatusOK, res.Status)
+}
+
+func TestProofChecker(t *testing.T) {
+	var checker ProofChecker
+
+	now := time.Now()
+	uri := &url.URL{Scheme: "https", Host: "example.com", Path: "/oauth2/token"}
+
+	proof := func(id, uri string) *InstanceProof {
+		return &InstanceProof{ID: id, Method: "POST", URI: uri, IssuedAt: now}
+	}
+
+	err := checker.Check(proof("1", "https://EXAMPLE.com:443/oauth2/token?foo=bar"), "POST", uri, now, 0)
+	assert.NoError(t, err)
+
+	err = checker.Check(proof("1", "https://example.com/oauth2/token"), "POST", uri, now, 0)
+	assert.EqualError(t, err, "replayed proof")
+
+	err = checker.Check(proof("2", "https://example.com/oauth2/token"), "GET", uri, now, 0)
+	assert.EqualError(t, err, "request mismatch")
+
+	err = checker.Check(proof("3", "http://example.com/oauth2/token"), "POST", uri, now, 0)
+	assert.EqualError(t, err, "request mismatch")
+
+	err = checker.Check(proof("4", "https://example.com:8443/oauth2/token"), "POST", uri, now, 0)
+	assert.EqualError(t, err, "request mismatch")
+
+	err = checker.Check(proof("5", "https://other.com/oauth2/token"), "POST", uri, now, 0)
+	assert.EqualError(t, err, "request mismatch")
+
+	err = checker.Check(proof("6", "https://example.com/oauth2/revoke"), "POST", uri, now, 0)
+	assert.EqualError(t, err, "request mismatch")
+
+	err = checker.Check(proof("7", "https://example.com/oauth2/token"), "POST", uri, now.Add(time.Minute), time.Second)
+	assert.EqualError(t, err, "stale proof")
+}
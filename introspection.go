@@ -18,6 +18,8 @@ type IntrospectionRequest struct {
 	TokenTypeHint string
 	ClientID      string
 	ClientSecret  string
+	BearerToken   string
+	AuthMethod    ClientAuthMethod
 }
 
 // ParseIntrospectionRequest parses an incoming request and returns an
@@ -45,9 +47,18 @@ func ParseIntrospectionRequest(r *http.Request) (*IntrospectionRequest, error) {
 	tokenTypeHint := r.PostForm.Get("token_type_hint")
 
 	// get client id and secret
-	clientID, clientSecret, ok := r.BasicAuth()
-	if !ok {
-		return nil, InvalidRequest("missing or invalid HTTP authorization header")
+	clientID, clientSecret, authMethod := ParseClientCredentials(r)
+
+	// otherwise get bearer token
+	var bearerToken string
+	if clientID == "" {
+		bearerToken, err = ParseBearerToken(r)
+		if err != nil {
+			return nil, InvalidRequest("missing or invalid HTTP authorization header")
+		}
+
+		// clear auth method
+		authMethod = ""
 	}
 
 	return &IntrospectionRequest{
@@ -55,26 +66,33 @@ func ParseIntrospectionRequest(r *http.Request) (*IntrospectionRequest, error) {
 		TokenTypeHint: tokenTypeHint,
 		ClientID:      clientID,
 		ClientSecret:  clientSecret,
+		BearerToken:   bearerToken,
+		AuthMethod:    authMethod,
 	}, nil
 }
 
 // IntrospectionResponse is a response returned by the token introspection
 // endpoint.
 type IntrospectionResponse struct {
-	Active     bool   `json:"active"`
-	Scope      string `json:"scope,omitempty"`
-	ClientID   string `json:"client_id,omitempty"`
-	Username   string `json:"username,omitempty"`
-	TokenType  string `json:"token_type,omitempty"`
-	ExpiresAt  int64  `json:"exp,omitempty"`
-	IssuedAt   int64  `json:"iat,omitempty"`
-	NotBefore  int64  `json:"nbf,omitempty"`
-	Subject    string `json:"sub,omitempty"`
-	Audience   string `json:"aud,omitempty"`
-	Issuer     string `json:"iss,omitempty"`
-	Identifier string `json:"jti,omitempty"`
-
-	Extra map[string]interface{} `json:"extra,omitempty"`
+	Active     bool     `json:"active"`
+	Scope      string   `json:"scope,omitempty"`
+	ClientID   string   `json:"client_id,omitempty"`
+	Username   string   `json:"username,omitempty"`
+	TokenType  string   `json:"token_type,omitempty"`
+	ExpiresAt  int64    `json:"exp,omitempty"`
+	IssuedAt   int64    `json:"iat,omitempty"`
+	NotBefore  int64    `json:"nbf,omitempty"`
+	Subject    string   `json:"sub,omitempty"`
+	Audience   Audience `json:"aud,omitempty"`
+	Issuer     string   `json:"iss,omitempty"`
+	Identifier string   `json:"jti,omitempty"`
+	RevokedAt  int64    `json:"revoked_at,omitempty"`
+
+	Confirmation *Confirmation `json:"cnf,omitempty"`
+
+	AuthorizationDetails []AuthorizationDetail `json:"authorization_details,omitempty"`
+
+	Extra Claims `json:"extra,omitempty"`
 }
 
 // NewIntrospectionResponse constructs an IntrospectionResponse.
@@ -88,6 +106,70 @@ func NewIntrospectionResponse(active bool, scope, clientID, username, tokenType
 	}
 }
 
+// Claims returns the claims of the introspected token. Extra claims are
+// included unless they conflict with the standard claims.
+func (r *IntrospectionResponse) Claims() Claims {
+	// prepare claims
+	claims := Claims{}
+
+	// add extra claims
+	for name, value := range r.Extra {
+		claims.Set(name, value)
+	}
+
+	// add string claims
+	for name, value := range map[string]string{
+		"client_id":  r.ClientID,
+		"username":   r.Username,
+		"token_type": r.TokenType,
+		"sub":        r.Subject,
+		"iss":        r.Issuer,
+		"jti":        r.Identifier,
+	} {
+		if value != "" {
+			claims.Set(name, value)
+		}
+	}
+
+	// use username as subject if missing
+	if r.Subject == "" && r.Username != "" {
+		claims.Set("sub", r.Username)
+	}
+
+	// add scope
+	if r.Scope != "" {
+		claims.Set("scope", r.Scope)
+	}
+
+	// add times
+	for name, value := range map[string]int64{
+		"exp": r.ExpiresAt,
+		"iat": r.IssuedAt,
+		"nbf": r.NotBefore,
+	} {
+		if value != 0 {
+			claims.Set(name, value)
+		}
+	}
+
+	// add audience
+	if len(r.Audience) > 0 {
+		claims.SetAudience(r.Audience)
+	}
+
+	// add confirmation
+	if r.Confirmation != nil {
+		claims.Set("cnf", r.Confirmation)
+	}
+
+	// add authorization details
+	if len(r.AuthorizationDetails) > 0 {
+		claims.Set("authorization_details", r.AuthorizationDetails)
+	}
+
+	return claims
+}
+
 // WriteIntrospectionResponse will write a response to the response writer.
 func WriteIntrospectionResponse(w http.ResponseWriter, r *IntrospectionResponse) error {
 	// check token type
@@ -119,6 +201,9 @@ func IntrospectionRequestValues(r IntrospectionRequest) url.Values {
 		values["token_type_hint"] = slice[1:2]
 	}
 
+	// add client credentials if sent in the body
+	addClientCredentials(values, r.ClientID, r.ClientSecret, r.AuthMethod)
+
 	return values
 }
 
@@ -133,9 +218,11 @@ func BuildIntrospectionRequest(uri string, r IntrospectionRequest) (*http.Reques
 		return nil, err
 	}
 
-	// set basic auth if available
-	if r.ClientID != "" || r.ClientSecret != "" {
+	// set basic auth or bearer token if available
+	if useBasicAuth(r.ClientID, r.ClientSecret, r.AuthMethod) {
 		req.SetBasicAuth(r.ClientID, r.ClientSecret)
+	} else if r.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+r.BearerToken)
 	}
 
 	// set content type
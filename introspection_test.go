@@ -36,6 +36,20 @@ func TestParseIntrospectionRequestFull(t *testing.T) {
 	assert.Equal(t, "bar", req.ClientSecret)
 }
 
+func TestParseIntrospectionRequestBearer(t *testing.T) {
+	r := newRequest(map[string]string{
+		"token": "foo",
+	})
+	r.Header.Set("Authorization", "Bearer bar")
+
+	req, err := ParseIntrospectionRequest(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", req.Token)
+	assert.Equal(t, "", req.ClientID)
+	assert.Equal(t, "", req.ClientSecret)
+	assert.Equal(t, "bar", req.BearerToken)
+}
+
 func TestParseIntrospectionRequestErrors(t *testing.T) {
 	r1, _ := http.NewRequest("GET", "", nil)
 	r2, _ := http.NewRequest("POST", "", nil)
@@ -99,6 +113,21 @@ func TestWriteIntrospectionResponse(t *testing.T) {
 		"token_type": "access_token"
 	}`, rec.Body.String())
 
+	res.Audience = Audience{"foo", "bar"}
+
+	rec = httptest.NewRecorder()
+
+	err = WriteIntrospectionResponse(rec, res)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"active": true,
+		"scope": "foo",
+		"client_id": "bar",
+		"username": "baz",
+		"token_type": "access_token",
+		"aud": ["foo", "bar"]
+	}`, rec.Body.String())
+
 	res = &IntrospectionResponse{}
 
 	rec = httptest.NewRecorder()
@@ -138,6 +167,7 @@ func TestIntrospectionRequestBuild(t *testing.T) {
 		TokenTypeHint: "hint",
 		ClientID:      "client-id",
 		ClientSecret:  "client-secret",
+		AuthMethod:    ClientSecretBasic,
 	}
 	req, err := BuildIntrospectionRequest("http://auth.server/introspect", rr1)
 	assert.NoError(t, err)
@@ -147,3 +177,17 @@ func TestIntrospectionRequestBuild(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, rr1, *rr2)
 }
+
+func TestIntrospectionRequestBuildBearer(t *testing.T) {
+	rr1 := IntrospectionRequest{
+		Token:       "token",
+		BearerToken: "bearer-token",
+	}
+	req, err := BuildIntrospectionRequest("http://auth.server/introspect", rr1)
+	assert.NoError(t, err)
+	assert.Equal(t, "Bearer bearer-token", req.Header.Get("Authorization"))
+
+	rr2, err := ParseIntrospectionRequest(req)
+	assert.NoError(t, err)
+	assert.Equal(t, rr1, *rr2)
+}
@@ -0,0 +1,103 @@
+package oauth2
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// JWTContentType is the content type of responses that carry a JWS in compact
+// serialization.
+const JWTContentType = "application/jwt"
+
+type jwsHeader struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ,omitempty"`
+	KeyID     string `json:"kid,omitempty"`
+}
+
+// SignJWS will encode the specified object as json and return a JWS in compact
+// serialization that is signed with the specified key using the hmac-sha256
+// algorithm (HS256).
+func SignJWS(key []byte, obj interface{}) (string, error) {
+	return signJWS(key, "JWT", obj)
+}
+
+func signJWS(key []byte, typ string, obj interface{}) (string, error) {
+	// encode header
+	header, err := json.Marshal(jwsHeader{
+		Algorithm: "HS256",
+		Type:      typ,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// encode payload
+	payload, err := json.Marshal(obj)
+	if err != nil {
+		return "", err
+	}
+
+	// prepare signing input
+	input := b64.EncodeToString(header) + "." + b64.EncodeToString(payload)
+
+	// compute signature
+	hash := hmac.New(sha256.New, key)
+	_, _ = hash.Write([]byte(input))
+
+	return input + "." + b64.EncodeToString(hash.Sum(nil)), nil
+}
+
+// VerifyJWS will verify the specified JWS in compact serialization using the
+// specified key and decode its payload into the provided object.
+func VerifyJWS(key []byte, str string, obj interface{}) error {
+	// split segments
+	s := strings.Split(str, ".")
+	if len(s) != 3 {
+		return errors.New("a JWS must have three segments separated by a dot")
+	}
+
+	// decode header
+	data, err := b64.DecodeString(s[0])
+	if err != nil {
+		return errors.New("JWS header is not base64 encoded")
+	}
+
+	// parse header
+	var header jwsHeader
+	err = json.Unmarshal(data, &header)
+	if err != nil {
+		return errors.New("JWS header is not valid json")
+	}
+
+	// check algorithm
+	if header.Algorithm != "HS256" {
+		return errors.New("unsupported JWS algorithm")
+	}
+
+	// decode signature
+	signature, err := b64.DecodeString(s[2])
+	if err != nil {
+		return errors.New("JWS signature is not base64 encoded")
+	}
+
+	// compute signature
+	hash := hmac.New(sha256.New, key)
+	_, _ = hash.Write([]byte(s[0] + "." + s[1]))
+
+	// validate signature
+	if !hmac.Equal(hash.Sum(nil), signature) {
+		return errors.New("invalid JWS signature")
+	}
+
+	// decode payload
+	payload, err := b64.DecodeString(s[1])
+	if err != nil {
+		return errors.New("JWS payload is not base64 encoded")
+	}
+
+	return json.Unmarshal(payload, obj)
+}
@@ -0,0 +1,253 @@
+package oauth2
+
+import (
+	"encoding/hex"
+	"errors"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// A Key is a secret that is used to sign and verify tokens.
+type Key struct {
+	// The unique identifier of the key.
+	ID string
+
+	// The secret of the key.
+	Secret []byte
+
+	// The time from which the key is used to sign new tokens.
+	NotBefore time.Time
+
+	// The time after which the key is retired and cannot be used to verify
+	// tokens anymore. A zero value means that the key has not been retired yet.
+	NotAfter time.Time
+}
+
+// Active returns whether the key may be used to sign tokens at the specified
+// time. Retired keys remain active until they expire, but newer keys take
+// precedence when selecting the current key.
+func (k Key) Active(now time.Time) bool {
+	return k.Valid(now)
+}
+
+// Valid returns whether the key may be used to verify tokens at the specified
+// time.
+func (k Key) Valid(now time.Time) bool {
+	return !k.NotBefore.After(now) && (k.NotAfter.IsZero() || k.NotAfter.After(now))
+}
+
+// KeyringConfig is used to configure a keyring.
+type KeyringConfig struct {
+	// The length of generated secrets.
+	SecretLength int
+
+	// The interval after which a new key is generated and activated.
+	RotationInterval time.Duration
+
+	// The duration retired keys can still be used to verify tokens. It must
+	// be at least as long as the longest token lifespan (defaults to 7 days,
+	// the default refresh token lifespan).
+	RetentionPeriod time.Duration
+
+	// The callback that is called with all keys whenever the keys changed. It
+	// can be used to persist the keys.
+	OnChange func([]Key) error
+
+	// The source from which the keys are fetched instead of generating them.
+	// Rotation is then managed by the source and the keyring only caches the
+	// fetched keys.
+	Source SecretSource
+
+	// The duration fetched keys are cached before they are fetched again
+	// (defaults to 5 minutes).
+	CacheDuration time.Duration
+}
+
+// Keyring manages the keys used to sign and verify tokens. New keys are
+// generated and old keys are retired according to the configured schedule.
+type Keyring struct {
+	config  KeyringConfig
+	keys    []Key
+	fetched time.Time
+	mutex   sync.Mutex
+}
+
+// NewKeyring creates and returns a new keyring using the specified keys. The
+// keys may have been persisted using the OnChange callback.
+func NewKeyring(config KeyringConfig, keys ...Key) *Keyring {
+	// set default secret length
+	if config.SecretLength == 0 {
+		config.SecretLength = 32
+	}
+
+	// set default retention period
+	if config.RetentionPeriod == 0 {
+		config.RetentionPeriod = 7 * 24 * time.Hour
+	}
+
+	// set default cache duration
+	if config.CacheDuration == 0 {
+		config.CacheDuration = 5 * time.Minute
+	}
+
+	// prepare keyring
+	keyring := &Keyring{
+		config: config,
+		keys:   append([]Key{}, keys...),
+	}
+
+	// sort keys
+	keyring.sort()
+
+	return keyring
+}
+
+// Keys returns a copy of all keys.
+func (k *Keyring) Keys() []Key {
+	// acquire mutex
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	return append([]Key{}, k.keys...)
+}
+
+// Current returns the key that should be used to sign tokens at the specified
+// time. The keyring is rotated if necessary.
+func (k *Keyring) Current(now time.Time) (Key, error) {
+	// acquire mutex
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	// rotate keys if necessary
+	err := k.rotate(now, false)
+	if err != nil {
+		return Key{}, err
+	}
+
+	// find newest active key
+	for i := len(k.keys) - 1; i >= 0; i-- {
+		if k.keys[i].Active(now) {
+			return k.keys[i], nil
+		}
+	}
+
+	return Key{}, errors.New("no active key")
+}
+
+// Valid returns all keys that can be used to verify tokens at the specified
+// time, starting with the newest key.
+func (k *Keyring) Valid(now time.Time) []Key {
+	// acquire mutex
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	// refresh keys from source, stale keys are used on errors
+	if k.config.Source != nil {
+		_ = k.fetch(now, false)
+	}
+
+	// collect keys
+	var list []Key
+	for i := len(k.keys) - 1; i >= 0; i-- {
+		if k.keys[i].Valid(now) {
+			list = append(list, k.keys[i])
+		}
+	}
+
+	return list
+}
+
+// Rotate will immediately generate and activate a new key and retire the
+// currently active keys. If a source is configured, the keys are fetched again
+// instead.
+func (k *Keyring) Rotate(now time.Time) error {
+	// acquire mutex
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	return k.rotate(now, true)
+}
+
+func (k *Keyring) rotate(now time.Time, force bool) error {
+	// fetch keys if a source is configured
+	if k.config.Source != nil {
+		return k.fetch(now, force)
+	}
+
+	// prepare flag
+	changed := false
+
+	// remove expired keys
+	for i := 0; i < len(k.keys); i++ {
+		if !k.keys[i].NotAfter.IsZero() && !k.keys[i].NotAfter.After(now) {
+			k.keys = append(k.keys[:i], k.keys[i+1:]...)
+			changed = true
+			i--
+		}
+	}
+
+	// check if the newest key is due for rotation
+	if !force && len(k.keys) > 0 {
+		newest := k.keys[len(k.keys)-1]
+		if k.config.RotationInterval <= 0 || newest.NotBefore.Add(k.config.RotationInterval).After(now) {
+			return k.changed(changed)
+		}
+	}
+
+	// generate key
+	key, err := k.generate(now)
+	if err != nil {
+		return err
+	}
+
+	// retire active keys
+	for i := range k.keys {
+		if k.keys[i].NotAfter.IsZero() {
+			k.keys[i].NotAfter = now.Add(k.config.RetentionPeriod)
+		}
+	}
+
+	// add key
+	k.keys = append(k.keys, key)
+
+	return k.changed(true)
+}
+
+func (k *Keyring) generate(now time.Time) (Key, error) {
+	// generate id
+	id := make([]byte, 8)
+	_, err := io.ReadFull(randSource, id)
+	if err != nil {
+		return Key{}, err
+	}
+
+	// generate secret
+	secret := make([]byte, k.config.SecretLength)
+	_, err = io.ReadFull(randSource, secret)
+	if err != nil {
+		return Key{}, err
+	}
+
+	return Key{
+		ID:        hex.EncodeToString(id),
+		Secret:    secret,
+		NotBefore: now,
+	}, nil
+}
+
+func (k *Keyring) changed(changed bool) error {
+	// check flag and callback
+	if !changed || k.config.OnChange == nil {
+		return nil
+	}
+
+	return k.config.OnChange(append([]Key{}, k.keys...))
+}
+
+func (k *Keyring) sort() {
+	sort.SliceStable(k.keys, func(i, j int) bool {
+		return k.keys[i].NotBefore.Before(k.keys[j].NotBefore)
+	})
+}
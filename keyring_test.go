@@ -0,0 +1,125 @@
+package oauth2
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyring(t *testing.T) {
+	var persisted []Key
+	keyring := NewKeyring(KeyringConfig{
+		RotationInterval: time.Hour,
+		RetentionPeriod:  2 * time.Hour,
+		OnChange: func(keys []Key) error {
+			persisted = keys
+			return nil
+		},
+	})
+
+	now := time.Now()
+
+	key1, err := keyring.Current(now)
+	assert.NoError(t, err)
+	assert.Len(t, key1.Secret, 32)
+	assert.NotEmpty(t, key1.ID)
+	assert.Equal(t, persisted, keyring.Keys())
+
+	key2, err := keyring.Current(now.Add(30 * time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, key1, key2)
+
+	key3, err := keyring.Current(now.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.NotEqual(t, key1.ID, key3.ID)
+	assert.Len(t, persisted, 2)
+
+	valid := keyring.Valid(now.Add(time.Hour))
+	assert.Len(t, valid, 2)
+	assert.Equal(t, key3.ID, valid[0].ID)
+	assert.Equal(t, key1.ID, valid[1].ID)
+
+	key4, err := keyring.Current(now.Add(3 * time.Hour))
+	assert.NoError(t, err)
+	assert.NotEqual(t, key3.ID, key4.ID)
+	assert.Len(t, persisted, 2)
+	assert.Equal(t, key3.ID, persisted[0].ID)
+
+	err = keyring.Rotate(now.Add(3 * time.Hour))
+	assert.NoError(t, err)
+	assert.Len(t, keyring.Valid(now.Add(3*time.Hour)), 3)
+}
+
+func TestKeyringLoad(t *testing.T) {
+	now := time.Now()
+
+	keyring := NewKeyring(KeyringConfig{}, Key{
+		ID:        "2",
+		Secret:    []byte("bar"),
+		NotBefore: now.Add(-time.Hour),
+	}, Key{
+		ID:        "1",
+		Secret:    []byte("foo"),
+		NotBefore: now.Add(-2 * time.Hour),
+		NotAfter:  now.Add(time.Hour),
+	})
+
+	key, err := keyring.Current(now)
+	assert.NoError(t, err)
+	assert.Equal(t, "2", key.ID)
+	assert.Len(t, keyring.Valid(now), 2)
+	assert.Len(t, keyring.Valid(now.Add(time.Hour)), 1)
+}
+
+func TestKeyringScheduledRetirement(t *testing.T) {
+	now := time.Now()
+
+	keyring := NewKeyring(KeyringConfig{
+		Source: SecretSourceFunc(func() ([]Key, error) {
+			return []Key{{
+				ID:        "1",
+				Secret:    []byte("foo"),
+				NotBefore: now.Add(-time.Hour),
+				NotAfter:  now.Add(time.Hour),
+			}}, nil
+		}),
+	})
+
+	key, err := keyring.Current(now)
+	assert.NoError(t, err)
+	assert.Equal(t, "1", key.ID)
+
+	_, err = keyring.Current(now.Add(time.Hour))
+	assert.Error(t, err)
+}
+
+func TestKeyringDefaultRetention(t *testing.T) {
+	now := time.Now()
+
+	keyring := NewKeyring(KeyringConfig{})
+
+	key1, err := keyring.Current(now)
+	assert.NoError(t, err)
+
+	err = keyring.Rotate(now)
+	assert.NoError(t, err)
+
+	valid := keyring.Valid(now.Add(24 * time.Hour))
+	assert.Len(t, valid, 2)
+	assert.Equal(t, key1.ID, valid[1].ID)
+	assert.Len(t, keyring.Valid(now.Add(7*24*time.Hour)), 1)
+}
+
+func TestKeyringError(t *testing.T) {
+	currentSource := randSource
+	randSource = strings.NewReader("")
+
+	keyring := NewKeyring(KeyringConfig{})
+
+	_, err := keyring.Current(time.Now())
+	assert.Error(t, err)
+
+	randSource = currentSource
+}
@@ -0,0 +1,97 @@
+package oauth2
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Authenticator authenticates requests to protected resources that carry a
+// bearer token. It is implemented by Server, Validator and
+// IntrospectionVerifier.
+type Authenticator interface {
+	Authenticate(w http.ResponseWriter, r *http.Request, required Scope) (Claims, bool)
+}
+
+// ValidateBearer returns a middleware that authenticates requests using the
+// provided authenticator. The claims of the token are made available to the
+// next handler using ClaimsFromContext.
+func ValidateBearer(authenticator Authenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// authenticate request
+			claims, ok := authenticator.Authenticate(w, r, nil)
+			if !ok {
+				return
+			}
+
+			// call next handler
+			next.ServeHTTP(w, r.WithContext(ContextWithClaims(r.Context(), claims)))
+		})
+	}
+}
+
+// Protect returns a middleware that authenticates requests using the provided
+// authenticator and requires the bearer token to include the specified scope.
+// It combines ValidateBearer and RequireScope in a single step.
+func Protect(authenticator Authenticator, scope ...string) func(http.Handler) http.Handler {
+	// prepare scope
+	required := Scope(scope)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// authenticate request
+			claims, ok := authenticator.Authenticate(w, r, required)
+			if !ok {
+				return
+			}
+
+			// call next handler
+			next.ServeHTTP(w, r.WithContext(ContextWithClaims(r.Context(), claims)))
+		})
+	}
+}
+
+// RequireScope returns a middleware that requires the claims of a previously
+// validated bearer token to include the specified scope.
+func RequireScope(scope ...string) func(http.Handler) http.Handler {
+	// prepare scope
+	required := Scope(scope)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// get claims
+			claims, ok := ClaimsFromContext(r.Context())
+			if !ok {
+				_ = WriteBearerError(w, ProtectedResource())
+				return
+			}
+
+			// validate scope
+			if !claims.GetScope().Includes(required) {
+				_ = WriteBearerError(w, InsufficientScope(required.String()))
+				return
+			}
+
+			// call next handler
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ServeEndpoints returns a middleware that serves the endpoints of the server
+// for requests below the specified path prefix. All other requests are passed
+// to the next handler.
+func ServeEndpoints(server *Server, prefix string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// check prefix
+			if !strings.HasPrefix(r.URL.Path, prefix) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// serve endpoint
+			server.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package oauth2
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware(t *testing.T) {
+	server := newTestServer()
+
+	var claims Claims
+	api := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, _ = ClaimsFromContext(r.Context())
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	protected := ValidateBearer(server)(RequireScope("foo")(api))
+	handler := ServeEndpoints(server, "/oauth2/")(protected)
+
+	res1, err := server.issueTokens(false, Scope{"foo"}, "client1", "user1", "")
+	assert.NoError(t, err)
+	res2, err := server.issueTokens(false, Scope{"bar"}, "client1", "user1", "")
+	assert.NoError(t, err)
+
+	req := httptest.NewRequest("GET", "/api", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, `Bearer realm="OAuth2"`, rec.Header().Get("WWW-Authenticate"))
+
+	req.Header.Set("Authorization", "Bearer "+res2.AccessToken)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Equal(t, `Bearer error="insufficient_scope", scope="foo"`, rec.Header().Get("WWW-Authenticate"))
+
+	req.Header.Set("Authorization", "Bearer "+res1.AccessToken)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, "client1", claims.GetString("client_id"))
+	assert.Equal(t, "user1", claims.GetString("sub"))
+
+	req = httptest.NewRequest("GET", "/oauth2/unknown", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = httptest.NewRecorder()
+	RequireScope("foo")(api).ServeHTTP(rec, httptest.NewRequest("GET", "/api", nil))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestProtect(t *testing.T) {
+	server := newTestServer()
+
+	var claims Claims
+	handler := Protect(server, "foo")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, _ = ClaimsFromContext(r.Context())
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	res1, err := server.issueTokens(false, Scope{"foo"}, "client1", "user1", "")
+	assert.NoError(t, err)
+	res2, err := server.issueTokens(false, Scope{"bar"}, "client1", "user1", "")
+	assert.NoError(t, err)
+
+	req := httptest.NewRequest("GET", "/api", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, `Bearer realm="OAuth2"`, rec.Header().Get("WWW-Authenticate"))
+
+	req.Header.Set("Authorization", "Bearer foo")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Contains(t, rec.Header().Get("WWW-Authenticate"), `error="invalid_token"`)
+
+	req.Header.Set("Authorization", "Bearer "+res2.AccessToken)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Equal(t, `Bearer error="insufficient_scope", scope="foo"`, rec.Header().Get("WWW-Authenticate"))
+
+	req.Header.Set("Authorization", "Bearer "+res1.AccessToken)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, "user1", claims.GetString("sub"))
+	assert.Equal(t, Scope{"foo"}, claims.GetScope())
+}
@@ -0,0 +1,86 @@
+package oauth2
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"net/http"
+)
+
+// Confirmation holds the confirmation (cnf) of a sender-constrained token.
+type Confirmation struct {
+	// The thumbprint of the certificate the token is bound to (RFC 8705).
+	CertificateThumbprint string `json:"x5t#S256,omitempty"`
+
+	// The thumbprint of the key the token is bound to (RFC 9449).
+	KeyThumbprint string `json:"jkt,omitempty"`
+}
+
+// CertificateThumbprint returns the base64url encoded SHA-256 thumbprint of the
+// DER encoding of the specified certificate (x5t#S256) as defined by RFC 8705.
+func CertificateThumbprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return b64.EncodeToString(sum[:])
+}
+
+// ClientCertificate returns the certificate presented by the client on the TLS
+// connection of the request or nil if none has been presented.
+func ClientCertificate(r *http.Request) *x509.Certificate {
+	// check connection
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return nil
+	}
+
+	return r.TLS.PeerCertificates[0]
+}
+
+// VerifiedClientCertificate returns whether the certificate presented by the
+// client on the TLS connection of the request has been verified against the
+// trusted certificate authorities of the server.
+func VerifiedClientCertificate(r *http.Request) bool {
+	return r.TLS != nil && len(r.TLS.PeerCertificates) > 0 && len(r.TLS.VerifiedChains) > 0
+}
+
+// MatchClientCertificate matches the specified certificate against the TLS
+// client authentication settings of a client and returns the used method.
+// Using the PKI method (tls_client_auth), the certificate is matched by its
+// subject distinguished name, but only if its chain has been verified by the
+// TLS configuration of the server (see VerifiedClientCertificate). Using the
+// self-signed method (self_signed_tls_client_auth), the certificate is matched
+// by its thumbprint.
+func MatchClientCertificate(cert *x509.Certificate, verified bool, subject string, thumbprints []string) (ClientAuthMethod, bool) {
+	// check certificate
+	if cert == nil {
+		return "", false
+	}
+
+	// check subject of verified certificates
+	if verified && subject != "" && cert.Subject.String() == subject {
+		return TLSClientAuth, true
+	}
+
+	// check thumbprints
+	if containsString(thumbprints, CertificateThumbprint(cert)) {
+		return SelfSignedTLSClientAuth, true
+	}
+
+	return "", false
+}
+
+// CheckCertificateBinding checks whether the certificate presented with the
+// request matches the thumbprint of the certificate a token is bound to. It
+// will return an Error if the token is bound and the certificate is missing
+// or does not match.
+func CheckCertificateBinding(r *http.Request, thumbprint string) error {
+	// check binding
+	if thumbprint == "" {
+		return nil
+	}
+
+	// check certificate
+	cert := ClientCertificate(r)
+	if cert == nil || CertificateThumbprint(cert) != thumbprint {
+		return InvalidToken("certificate mismatch")
+	}
+
+	return nil
+}
@@ -0,0 +1,264 @@
+package oauth2
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/256dpi/oauth2/v2/oauth2test"
+	"github.com/stretchr/testify/assert"
+)
+
+func generateTestCertificate(t *testing.T, name string) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+
+	return cert
+}
+
+func withCertificate(handler http.Handler, cert *x509.Certificate) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if cert != nil {
+			r.TLS = &tls.ConnectionState{
+				PeerCertificates: []*x509.Certificate{cert},
+				VerifiedChains:   [][]*x509.Certificate{{cert}},
+			}
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+func withUnverifiedCertificate(handler http.Handler, cert *x509.Certificate) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+func TestMatchClientCertificate(t *testing.T) {
+	cert := generateTestCertificate(t, "client1")
+	assert.NotEmpty(t, CertificateThumbprint(cert))
+	assert.Equal(t, CertificateThumbprint(cert), CertificateThumbprint(cert))
+
+	method, ok := MatchClientCertificate(cert, true, "CN=client1", nil)
+	assert.True(t, ok)
+	assert.Equal(t, TLSClientAuth, method)
+
+	_, ok = MatchClientCertificate(cert, false, "CN=client1", nil)
+	assert.False(t, ok)
+
+	method, ok = MatchClientCertificate(cert, false, "", []string{CertificateThumbprint(cert)})
+	assert.True(t, ok)
+	assert.Equal(t, SelfSignedTLSClientAuth, method)
+
+	_, ok = MatchClientCertificate(cert, true, "CN=client2", []string{"foo"})
+	assert.False(t, ok)
+
+	_, ok = MatchClientCertificate(nil, true, "CN=client1", nil)
+	assert.False(t, ok)
+}
+
+func TestCheckCertificateBinding(t *testing.T) {
+	cert := generateTestCertificate(t, "client1")
+
+	req := httptest.NewRequest("GET", "/", nil)
+	assert.NoError(t, CheckCertificateBinding(req, ""))
+	assert.Equal(t, InvalidToken("certificate mismatch"), CheckCertificateBinding(req, CertificateThumbprint(cert)))
+
+	req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+	assert.NoError(t, CheckCertificateBinding(req, CertificateThumbprint(cert)))
+	assert.Equal(t, InvalidToken("certificate mismatch"), CheckCertificateBinding(req, "foo"))
+}
+
+func TestServerCertificateBoundTokens(t *testing.T) {
+	server := newTestServer()
+	server.Config.CertificateBoundTokens = true
+
+	cert := generateTestCertificate(t, "client1")
+	otherCert := generateTestCertificate(t, "client2")
+
+	server.Clients["client1"].TLSCertificates = []string{CertificateThumbprint(cert)}
+
+	// missing certificate
+	res := oauth2test.Do(withCertificate(server, nil), &oauth2test.Request{
+		Method: "POST",
+		Path:   "/oauth2/token",
+		Form: map[string]string{
+			"grant_type": ClientCredentialsGrantType,
+			"client_id":  "client1",
+			"scope":      "foo",
+		},
+	})
+	assert.Equal(t, http.StatusUnauthorized, res.Status)
+	assert.Equal(t, "invalid_client", res.String("error"))
+
+	// other certificate
+	res = oauth2test.Do(withCertificate(server, otherCert), &oauth2test.Request{
+		Method: "POST",
+		Path:   "/oauth2/token",
+		Form: map[string]string{
+			"grant_type": ClientCredentialsGrantType,
+			"client_id":  "client1",
+			"scope":      "foo",
+		},
+	})
+	assert.Equal(t, http.StatusUnauthorized, res.Status)
+	assert.Equal(t, "invalid_client", res.String("error"))
+
+	// matching certificate
+	res = oauth2test.Do(withCertificate(server, cert), &oauth2test.Request{
+		Method: "POST",
+		Path:   "/oauth2/token",
+		Form: map[string]string{
+			"grant_type": ClientCredentialsGrantType,
+			"client_id":  "client1",
+			"scope":      "foo",
+		},
+	})
+	assert.Equal(t, http.StatusOK, res.Status)
+	accessToken := res.String("access_token")
+	assert.NotEmpty(t, accessToken)
+
+	// introspection
+	res = oauth2test.Do(withCertificate(server, cert), &oauth2test.Request{
+		Method: "POST",
+		Path:   "/oauth2/introspect",
+		Form: map[string]string{
+			"token":     accessToken,
+			"client_id": "client1",
+		},
+	})
+	assert.Equal(t, http.StatusOK, res.Status)
+	assert.True(t, res.Bool("active"))
+	assert.Equal(t, map[string]interface{}{
+		"x5t#S256": CertificateThumbprint(cert),
+	}, res.JSON["cnf"])
+
+	authenticate := func(cert *x509.Certificate) (Claims, *oauth2test.Response) {
+		var claims Claims
+		res := oauth2test.Do(withCertificate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, _ = server.Authenticate(w, r, Scope{"foo"})
+		}), cert), &oauth2test.Request{
+			Method: "GET",
+			Path:   "/api",
+			Header: map[string]string{
+				"Authorization": "Bearer " + accessToken,
+			},
+		})
+		return claims, res
+	}
+
+	// resource request
+	claims, res := authenticate(cert)
+	assert.Equal(t, http.StatusOK, res.Status)
+	assert.Equal(t, map[string]interface{}{
+		"x5t#S256": CertificateThumbprint(cert),
+	}, claims["cnf"])
+
+	// resource request with other certificate
+	_, res = authenticate(otherCert)
+	assert.Equal(t, http.StatusUnauthorized, res.Status)
+	assert.Equal(t, `Bearer error="invalid_token", error_description="certificate mismatch"`, res.Header.Get("WWW-Authenticate"))
+
+	// resource request without certificate
+	_, res = authenticate(nil)
+	assert.Equal(t, http.StatusUnauthorized, res.Status)
+
+	// unbound token
+	server.Config.CertificateBoundTokens = false
+	res = oauth2test.Do(withCertificate(server, cert), &oauth2test.Request{
+		Method: "POST",
+		Path:   "/oauth2/token",
+		Form: map[string]string{
+			"grant_type": ClientCredentialsGrantType,
+			"client_id":  "client1",
+			"scope":      "foo",
+		},
+	})
+	assert.Equal(t, http.StatusOK, res.Status)
+	accessToken = res.String("access_token")
+
+	_, res = authenticate(nil)
+	assert.Equal(t, http.StatusOK, res.Status)
+}
+
+func TestServerTLSClientAuth(t *testing.T) {
+	server := newTestServer()
+	server.Config.ClientAuthMethods = []ClientAuthMethod{TLSClientAuth}
+
+	cert := generateTestCertificate(t, "client1")
+
+	server.Clients["client1"].TLSSubject = "CN=client1"
+
+	res := oauth2test.Do(withCertificate(server, cert), &oauth2test.Request{
+		Method: "POST",
+		Path:   "/oauth2/token",
+		Form: map[string]string{
+			"grant_type": ClientCredentialsGrantType,
+			"client_id":  "client1",
+			"scope":      "foo",
+		},
+	})
+	assert.Equal(t, http.StatusOK, res.Status)
+
+	forged := generateTestCertificate(t, "client1")
+	server.Config.ClientAuthMethods = []ClientAuthMethod{TLSClientAuth, SelfSignedTLSClientAuth}
+
+	res = oauth2test.Do(withUnverifiedCertificate(server, forged), &oauth2test.Request{
+		Method: "POST",
+		Path:   "/oauth2/token",
+		Form: map[string]string{
+			"grant_type": ClientCredentialsGrantType,
+			"client_id":  "client1",
+			"scope":      "foo",
+		},
+	})
+	assert.Equal(t, http.StatusBadRequest, res.Status)
+	assert.Equal(t, "invalid_client", res.String("error"))
+
+	server.Config.ClientAuthMethods = []ClientAuthMethod{TLSClientAuth}
+
+	res = oauth2test.Do(server, &oauth2test.Request{
+		Method:   "POST",
+		Path:     "/oauth2/token",
+		Username: "client1",
+		Password: "foo",
+		Form: map[string]string{
+			"grant_type": ClientCredentialsGrantType,
+			"scope":      "foo",
+		},
+	})
+	assert.Equal(t, http.StatusUnauthorized, res.Status)
+	assert.Equal(t, "unsupported client authentication method", res.String("error_description"))
+
+	res = oauth2test.Do(withCertificate(server, cert), &oauth2test.Request{
+		Method: "POST",
+		Path:   "/oauth2/revoke",
+		Form: map[string]string{
+			"token":     server.Config.MustGenerate().String(),
+			"client_id": "client1",
+		},
+	})
+	assert.Equal(t, http.StatusOK, res.Status)
+}
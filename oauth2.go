@@ -6,9 +6,13 @@
 package oauth2
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/url"
+	"strings"
+	"unicode"
 )
 
 // The known OAuth2 grant types.
@@ -19,6 +23,15 @@ const (
 	RefreshTokenGrantType      = "refresh_token"
 )
 
+// GuestGrantType is the extension grant type used to obtain short-lived guest
+// access tokens without resource owner credentials.
+const GuestGrantType = "urn:256dpi:oauth2:grant-type:guest"
+
+// PreAuthorizedCodeGrantType is the extension grant type used to exchange
+// administratively issued codes for access tokens as defined by OpenID for
+// Verifiable Credential Issuance.
+const PreAuthorizedCodeGrantType = "urn:ietf:params:oauth:grant-type:pre-authorized_code"
+
 // KnownGrantType returns true if the grant type is a known grant type
 // (e.g. password, client credentials, authorization code or refresh token).
 func KnownGrantType(str string) bool {
@@ -68,27 +81,162 @@ func KnownTokenType(str string) bool {
 	return false
 }
 
+// TokenTypeHint is a hint about the type of token submitted for revocation or
+// introspection.
+type TokenTypeHint string
+
+// The known OAuth2 token type hints.
+const (
+	AccessTokenHint  TokenTypeHint = AccessToken
+	RefreshTokenHint TokenTypeHint = RefreshToken
+)
+
+// ParseTokenTypeHint parses and returns the token type hint. An empty string
+// yields an empty hint. It will return an Error if the hint is not known.
+func ParseTokenTypeHint(str string) (TokenTypeHint, error) {
+	// check empty
+	if str == "" {
+		return "", nil
+	}
+
+	// check hint
+	if !KnownTokenType(str) {
+		return "", UnsupportedTokenType("")
+	}
+
+	return TokenTypeHint(str), nil
+}
+
+// ClientAuthMethod denotes the method a client used to authenticate at the
+// token, revocation or introspection endpoint.
+type ClientAuthMethod string
+
+// The known client authentication methods.
+const (
+	ClientSecretBasic ClientAuthMethod = "client_secret_basic"
+	ClientSecretPost  ClientAuthMethod = "client_secret_post"
+	NoClientAuth      ClientAuthMethod = "none"
+
+	// The mutual TLS client authentication methods (RFC 8705). The client
+	// only sends its id and presents a certificate on the TLS connection.
+	TLSClientAuth           ClientAuthMethod = "tls_client_auth"
+	SelfSignedTLSClientAuth ClientAuthMethod = "self_signed_tls_client_auth"
+)
+
+// KnownClientAuthMethod returns true if the client authentication method is a
+// known method (e.g. client secret basic, client secret post or none).
+func KnownClientAuthMethod(method ClientAuthMethod) bool {
+	switch method {
+	case ClientSecretBasic, ClientSecretPost, NoClientAuth, TLSClientAuth, SelfSignedTLSClientAuth:
+		return true
+	}
+
+	return false
+}
+
+// IsTLSClientAuthMethod returns true if the client authentication method is a
+// mutual TLS method.
+func IsTLSClientAuthMethod(method ClientAuthMethod) bool {
+	return method == TLSClientAuth || method == SelfSignedTLSClientAuth
+}
+
+// ParseClientCredentials returns the client id and secret of the provided
+// request along with the used authentication method. Credentials are read from
+// the HTTP Basic authorization header or the "client_id" and "client_secret"
+// form parameters. The form of the request must already be parsed. Requests
+// that only carry a client id are reported without authentication, servers
+// may check a presented client certificate using MatchClientCertificate.
+func ParseClientCredentials(r *http.Request) (string, string, ClientAuthMethod) {
+	// get basic credentials
+	clientID, clientSecret, ok := r.BasicAuth()
+	if ok {
+		return clientID, clientSecret, ClientSecretBasic
+	}
+
+	// get form credentials
+	clientID = r.PostForm.Get("client_id")
+	clientSecret = r.PostForm.Get("client_secret")
+	if clientSecret != "" {
+		return clientID, clientSecret, ClientSecretPost
+	}
+
+	return clientID, "", NoClientAuth
+}
+
+func addClientCredentials(values url.Values, clientID, clientSecret string, method ClientAuthMethod) {
+	// check method
+	if method != ClientSecretPost && method != NoClientAuth && !IsTLSClientAuthMethod(method) {
+		return
+	}
+
+	// set client id if available
+	if clientID != "" {
+		values.Set("client_id", clientID)
+	}
+
+	// set client secret if available
+	if method == ClientSecretPost && clientSecret != "" {
+		values.Set("client_secret", clientSecret)
+	}
+}
+
+func useBasicAuth(clientID, clientSecret string, method ClientAuthMethod) bool {
+	return method != ClientSecretPost && method != NoClientAuth && !IsTLSClientAuthMethod(method) && (clientID != "" || clientSecret != "")
+}
+
 // Write will encode the specified object as json and write a response to the
 // response writer as specified by the OAuth2 spec.
 func Write(w http.ResponseWriter, obj interface{}, status int) error {
-	// set required headers
-	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
-	w.Header().Set("Cache-Control", "no-store")
-	w.Header().Set("Pragma", "no-cache")
+	return WriteToSink(HTTPSink{w}, obj, status)
+}
 
-	// set status
-	w.WriteHeader(status)
+// WriteToSink will write a response like Write to the specified sink.
+func WriteToSink(sink ResponseSink, obj interface{}, status int) error {
+	// encode document
+	var body bytes.Buffer
+	err := json.NewEncoder(&body).Encode(obj)
+	if err != nil {
+		return err
+	}
 
-	// write error document
-	err := json.NewEncoder(w).Encode(obj)
+	// set required headers
+	sink.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	sink.Header().Set("Cache-Control", "no-store")
+	sink.Header().Set("Pragma", "no-cache")
 
-	return err
+	return sink.WriteResponse(status, body.Bytes())
 }
 
 // WriteRedirect will either add the specified parameters to the query of the
 // specified uri or encode them and it as the fragment as specified by the
-// OAuth2 spec.
+// OAuth2 spec. Control characters are removed from the parameter values to
+// prevent header splitting and an error is returned if the uri contains any.
+// The redirect is written using the 303 See Other status.
 func WriteRedirect(w http.ResponseWriter, uri string, params map[string]string, useFragment bool) error {
+	return WriteRedirectStatus(w, uri, params, useFragment, http.StatusSeeOther)
+}
+
+// WriteRedirectStatus will write a redirect like WriteRedirect using the
+// specified status. Only 302 Found and 303 See Other are allowed, other
+// statuses fall back to 303 See Other which prevents the user agent from
+// resubmitting a POST request.
+func WriteRedirectStatus(w http.ResponseWriter, uri string, params map[string]string, useFragment bool, status int) error {
+	return WriteRedirectToSink(HTTPSink{w}, uri, params, useFragment, status)
+}
+
+// WriteRedirectToSink will write a redirect like WriteRedirectStatus to the
+// specified sink.
+func WriteRedirectToSink(sink ResponseSink, uri string, params map[string]string, useFragment bool, status int) error {
+	// check status
+	if status != http.StatusFound {
+		status = http.StatusSeeOther
+	}
+
+	// check redirect uri
+	if containsControl(uri) {
+		return errors.New("redirect URI contains control characters")
+	}
+
 	// parse redirect uri
 	redirectURI, err := url.ParseRequestURI(uri)
 	if err != nil {
@@ -102,7 +250,7 @@ func WriteRedirect(w http.ResponseWriter, uri string, params map[string]string,
 
 		// add parameters
 		for k, v := range params {
-			f.Add(k, v)
+			f.Add(k, stripControl(v))
 		}
 
 		// encode fragment
@@ -113,7 +261,7 @@ func WriteRedirect(w http.ResponseWriter, uri string, params map[string]string,
 
 		// add parameters
 		for k, v := range params {
-			q.Add(k, v)
+			q.Add(k, stripControl(v))
 		}
 
 		// reset query
@@ -121,20 +269,28 @@ func WriteRedirect(w http.ResponseWriter, uri string, params map[string]string,
 	}
 
 	// set location
-	w.Header().Add("Location", redirectURI.String())
+	sink.Header().Add("Location", redirectURI.String())
 
 	// prevent caching
-	w.Header().Set("Cache-Control", "no-store")
-	w.Header().Set("Pragma", "no-cache")
+	sink.Header().Set("Cache-Control", "no-store")
+	sink.Header().Set("Pragma", "no-cache")
 
 	// prevent referrer leakage
-	w.Header().Set("Referrer-Policy", "origin")
+	sink.Header().Set("Referrer-Policy", "origin")
 
-	// write redirect
-	w.WriteHeader(http.StatusSeeOther)
+	return sink.WriteResponse(status, nil)
+}
 
-	// finish response
-	_, err = w.Write(nil)
+func containsControl(str string) bool {
+	return strings.IndexFunc(str, unicode.IsControl) >= 0
+}
+
+func stripControl(str string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
 
-	return err
+		return r
+	}, str)
 }
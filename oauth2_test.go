@@ -79,6 +79,48 @@ func TestRedirect(t *testing.T) {
 
 	err := WriteRedirect(rec, "foo", nil, false)
 	assert.Error(t, err)
+
+	rec = httptest.NewRecorder()
+
+	err = WriteRedirect(rec, "http://example.com\r\nSet-Cookie: foo=bar", nil, false)
+	assert.Error(t, err)
+	assert.Empty(t, rec.Header())
+}
+
+func TestRedirectStatus(t *testing.T) {
+	for status, expected := range map[int]int{
+		0:                            http.StatusSeeOther,
+		http.StatusFound:             http.StatusFound,
+		http.StatusSeeOther:          http.StatusSeeOther,
+		http.StatusBadRequest:        http.StatusSeeOther,
+		http.StatusTemporaryRedirect: http.StatusSeeOther,
+	} {
+		rec := httptest.NewRecorder()
+
+		err := WriteRedirectStatus(rec, "http://example.com", map[string]string{"foo": "bar"}, false, status)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, rec.Code)
+		assert.Equal(t, "http://example.com?foo=bar", rec.Header().Get("Location"))
+	}
+}
+
+func TestRedirectControlCharacters(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	err := WriteRedirect(rec, "http://example.com", map[string]string{
+		"state":             "foo\r\nSet-Cookie: bar=baz",
+		"error_description": "foo\x00\x1b\u0085bar",
+	}, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "http://example.com?error_description=foobar&state=fooSet-Cookie%3A+bar%3Dbaz", rec.Header().Get("Location"))
+
+	rec = httptest.NewRecorder()
+
+	err = WriteRedirect(rec, "http://example.com", map[string]string{
+		"scope": "foo\nbar",
+	}, true)
+	assert.NoError(t, err)
+	assert.Equal(t, "http://example.com#scope=foobar", rec.Header().Get("Location"))
 }
 
 func TestRedirectQuery(t *testing.T) {
@@ -129,3 +171,21 @@ func TestRedirectFragment(t *testing.T) {
 		},
 	}, rec.Header())
 }
+
+func TestParseTokenTypeHint(t *testing.T) {
+	hint, err := ParseTokenTypeHint("")
+	assert.NoError(t, err)
+	assert.Equal(t, TokenTypeHint(""), hint)
+
+	hint, err = ParseTokenTypeHint(AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, AccessTokenHint, hint)
+
+	hint, err = ParseTokenTypeHint(RefreshToken)
+	assert.NoError(t, err)
+	assert.Equal(t, RefreshTokenHint, hint)
+
+	hint, err = ParseTokenTypeHint("foo")
+	assert.Equal(t, UnsupportedTokenType(""), err)
+	assert.Equal(t, TokenTypeHint(""), hint)
+}
@@ -0,0 +1,165 @@
+// Package oauth2client provides helpers to obtain tokens from any OAuth2
+// server using the password, client credentials, authorization code (with
+// PKCE) and refresh token flows. It builds on the low-level oauth2.Client
+// and returns server errors as oauth2.Error values.
+package oauth2client
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+
+	"github.com/256dpi/oauth2/v2"
+)
+
+// Config is used to configure a client.
+type Config struct {
+	// The endpoints of the server.
+	oauth2.ClientConfig
+
+	// The authorization endpoint of the server, e.g. "/oauth2/authorize".
+	AuthorizationEndpoint string
+
+	// The credentials of the client and the method used to present them.
+	// Defaults to HTTP basic authentication.
+	ClientID     string
+	ClientSecret string
+	AuthMethod   oauth2.ClientAuthMethod
+
+	// The redirect URI used with the authorization code flow.
+	RedirectURI string
+}
+
+// Default will return a default configuration for a server at the specified
+// base URI and the specified client credentials.
+func Default(baseURI, clientID, clientSecret string) Config {
+	return Config{
+		ClientConfig:          oauth2.Default(baseURI),
+		AuthorizationEndpoint: "/oauth2/authorize",
+		ClientID:              clientID,
+		ClientSecret:          clientSecret,
+	}
+}
+
+// Client performs token flows against an OAuth2 server.
+type Client struct {
+	config Config
+	client *oauth2.Client
+}
+
+// New will create and return a new client.
+func New(config Config) *Client {
+	return NewWithClient(config, new(http.Client))
+}
+
+// NewWithClient will create and return a new client using the provided HTTP
+// client.
+func NewWithClient(config Config, client *http.Client) *Client {
+	return &Client{
+		config: config,
+		client: oauth2.NewClientWithClient(config.ClientConfig, client),
+	}
+}
+
+// Password will obtain tokens using the resource owner password credentials
+// flow.
+func (c *Client) Password(username, password string, scope oauth2.Scope) (*oauth2.TokenResponse, error) {
+	return c.authenticate(oauth2.TokenRequest{
+		GrantType: oauth2.PasswordGrantType,
+		Scope:     scope,
+		Username:  username,
+		Password:  password,
+	})
+}
+
+// ClientCredentials will obtain tokens using the client credentials flow.
+func (c *Client) ClientCredentials(scope oauth2.Scope) (*oauth2.TokenResponse, error) {
+	return c.authenticate(oauth2.TokenRequest{
+		GrantType: oauth2.ClientCredentialsGrantType,
+		Scope:     scope,
+	})
+}
+
+// Refresh will obtain new tokens using the specified refresh token. The scope
+// may be empty to request the originally granted scope.
+func (c *Client) Refresh(refreshToken string, scope oauth2.Scope) (*oauth2.TokenResponse, error) {
+	return c.authenticate(oauth2.TokenRequest{
+		GrantType:    oauth2.RefreshTokenGrantType,
+		Scope:        scope,
+		RefreshToken: refreshToken,
+	})
+}
+
+// AuthorizationURL will return the URL the resource owner should be sent to
+// for authorizing the client using the authorization code flow. It also
+// returns the generated PKCE code verifier that must be kept until the code is
+// exchanged.
+func (c *Client) AuthorizationURL(state string, scope oauth2.Scope) (string, string, error) {
+	// generate verifier
+	verifier, err := GenerateCodeVerifier()
+	if err != nil {
+		return "", "", err
+	}
+
+	// parse endpoint
+	uri, err := url.Parse(c.config.BaseURI + c.config.AuthorizationEndpoint)
+	if err != nil {
+		return "", "", err
+	}
+
+	// prepare query
+	query := uri.Query()
+	query.Set("response_type", oauth2.CodeResponseType)
+	query.Set("client_id", c.config.ClientID)
+	query.Set("code_challenge", oauth2.S256CodeChallenge(verifier))
+	query.Set("code_challenge_method", oauth2.S256CodeChallengeMethod)
+
+	// set optional parameters
+	if c.config.RedirectURI != "" {
+		query.Set("redirect_uri", c.config.RedirectURI)
+	}
+	if !scope.Empty() {
+		query.Set("scope", scope.String())
+	}
+	if state != "" {
+		query.Set("state", state)
+	}
+
+	// set query
+	uri.RawQuery = query.Encode()
+
+	return uri.String(), verifier, nil
+}
+
+// Exchange will obtain tokens by exchanging the specified authorization code
+// and the code verifier returned by AuthorizationURL.
+func (c *Client) Exchange(code, verifier string) (*oauth2.TokenResponse, error) {
+	return c.authenticate(oauth2.TokenRequest{
+		GrantType:    oauth2.AuthorizationCodeGrantType,
+		Code:         code,
+		CodeVerifier: verifier,
+		RedirectURI:  c.config.RedirectURI,
+	})
+}
+
+// GenerateCodeVerifier will generate a random PKCE code verifier.
+func GenerateCodeVerifier() (string, error) {
+	// read random bytes
+	buf := make([]byte, 32)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func (c *Client) authenticate(trq oauth2.TokenRequest) (*oauth2.TokenResponse, error) {
+	// set credentials
+	trq.ClientID = c.config.ClientID
+	trq.ClientSecret = c.config.ClientSecret
+	trq.AuthMethod = c.config.AuthMethod
+
+	return c.client.Authenticate(trq)
+}
@@ -0,0 +1,156 @@
+package oauth2client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/256dpi/oauth2/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func withServer(cb func(base string, srv *oauth2.Server)) {
+	srv := oauth2.NewServer(oauth2.DefaultServerConfig([]byte("secret"), oauth2.Scope{"foo", "bar"}))
+	srv.Clients["client1"] = &oauth2.ServerEntity{
+		Secret:       "foo",
+		RedirectURI:  "http://example.com/callback",
+		Confidential: true,
+	}
+	srv.Users["user1"] = &oauth2.ServerEntity{
+		Secret: "foo",
+	}
+
+	server := httptest.NewServer(srv)
+	defer server.Close()
+
+	cb(server.URL, srv)
+}
+
+func TestPassword(t *testing.T) {
+	withServer(func(base string, srv *oauth2.Server) {
+		client := New(Default(base, "client1", "foo"))
+
+		trs, err := client.Password("user1", "foo", oauth2.Scope{"foo"})
+		assert.NoError(t, err)
+		assert.Equal(t, oauth2.BearerAccessTokenType, trs.TokenType)
+		assert.NotEmpty(t, trs.AccessToken)
+		assert.NotEmpty(t, trs.RefreshToken)
+		assert.Equal(t, oauth2.Scope{"foo"}, trs.Scope)
+
+		trs, err = client.Password("user1", "bar", oauth2.Scope{"foo"})
+		assert.Equal(t, &oauth2.Error{
+			Name:   "access_denied",
+			Status: http.StatusForbidden,
+		}, err)
+		assert.Nil(t, trs)
+	})
+}
+
+func TestClientCredentials(t *testing.T) {
+	withServer(func(base string, srv *oauth2.Server) {
+		config := Default(base, "client1", "foo")
+		config.AuthMethod = oauth2.ClientSecretPost
+
+		trs, err := New(config).ClientCredentials(oauth2.Scope{"foo", "bar"})
+		assert.NoError(t, err)
+		assert.NotEmpty(t, trs.AccessToken)
+		assert.Equal(t, oauth2.Scope{"foo", "bar"}, trs.Scope)
+
+		trs, err = New(Default(base, "client1", "bar")).ClientCredentials(nil)
+		assert.Error(t, err)
+		assert.Equal(t, "invalid_client", err.(*oauth2.Error).Name)
+		assert.Nil(t, trs)
+	})
+}
+
+func TestRefresh(t *testing.T) {
+	withServer(func(base string, srv *oauth2.Server) {
+		client := New(Default(base, "client1", "foo"))
+
+		trs1, err := client.Password("user1", "foo", oauth2.Scope{"foo", "bar"})
+		assert.NoError(t, err)
+
+		trs2, err := client.Refresh(trs1.RefreshToken, oauth2.Scope{"foo"})
+		assert.NoError(t, err)
+		assert.NotEqual(t, trs1.AccessToken, trs2.AccessToken)
+		assert.Equal(t, oauth2.Scope{"foo"}, trs2.Scope)
+
+		_, err = client.Refresh(trs1.RefreshToken, nil)
+		assert.Error(t, err)
+		assert.Equal(t, "invalid_grant", err.(*oauth2.Error).Name)
+	})
+}
+
+func authorize(t *testing.T, str string) url.Values {
+	// parse url
+	uri, err := url.Parse(str)
+	assert.NoError(t, err)
+
+	// prepare form
+	form := uri.Query()
+	form.Set("username", "user1")
+	form.Set("password", "foo")
+
+	// authorize as resource owner
+	req, err := http.NewRequest("POST", uri.Scheme+"://"+uri.Host+uri.Path, strings.NewReader(form.Encode()))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	res, err := http.DefaultTransport.RoundTrip(req)
+	assert.NoError(t, err)
+	_ = res.Body.Close()
+	assert.Equal(t, http.StatusSeeOther, res.StatusCode)
+
+	// parse location
+	location, err := url.Parse(res.Header.Get("Location"))
+	assert.NoError(t, err)
+
+	return location.Query()
+}
+
+func TestAuthorizationCode(t *testing.T) {
+	withServer(func(base string, srv *oauth2.Server) {
+		config := Default(base, "client1", "foo")
+		config.RedirectURI = "http://example.com/callback"
+		client := New(config)
+
+		str, verifier, err := client.AuthorizationURL("xyz", oauth2.Scope{"foo"})
+		assert.NoError(t, err)
+		assert.True(t, oauth2.ValidCodeVerifier(verifier))
+
+		uri, err := url.Parse(str)
+		assert.NoError(t, err)
+		assert.Equal(t, "/oauth2/authorize", uri.Path)
+		assert.Equal(t, url.Values{
+			"response_type":         {"code"},
+			"client_id":             {"client1"},
+			"redirect_uri":          {"http://example.com/callback"},
+			"scope":                 {"foo"},
+			"state":                 {"xyz"},
+			"code_challenge":        {oauth2.S256CodeChallenge(verifier)},
+			"code_challenge_method": {"S256"},
+		}, uri.Query())
+
+		query := authorize(t, str)
+		assert.Equal(t, "xyz", query.Get("state"))
+
+		other, err := GenerateCodeVerifier()
+		assert.NoError(t, err)
+
+		trs, err := client.Exchange(query.Get("code"), other)
+		assert.Error(t, err)
+		assert.Equal(t, "invalid_grant", err.(*oauth2.Error).Name)
+		assert.Nil(t, trs)
+
+		str, verifier, err = client.AuthorizationURL("xyz", oauth2.Scope{"foo"})
+		assert.NoError(t, err)
+
+		query = authorize(t, str)
+
+		trs, err = client.Exchange(query.Get("code"), verifier)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, trs.AccessToken)
+		assert.Equal(t, oauth2.Scope{"foo"}, trs.Scope)
+	})
+}
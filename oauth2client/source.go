@@ -0,0 +1,205 @@
+package oauth2client
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/256dpi/oauth2/v2"
+)
+
+// DefaultExpiryMargin is the default margin before the expiry of an access
+// token at which a token source obtains a new token.
+const DefaultExpiryMargin = 10 * time.Second
+
+// TokenSource caches an access token and obtains a new one before it expires.
+// New tokens are obtained using the refresh token if available. Sources created
+// without a token use the client credentials flow otherwise or if the refresh
+// token has been rejected. Sources created with a token never switch to client
+// credentials as this would replace the resource owner of the token. A token
+// source is safe for concurrent use.
+type TokenSource struct {
+	// The margin before the expiry at which a new token is obtained.
+	// Defaults to DefaultExpiryMargin.
+	Margin time.Duration
+
+	// The clock used to check the expiry. Defaults to the system clock.
+	Clock oauth2.Clock
+
+	client    *Client
+	scope     oauth2.Scope
+	token     *oauth2.TokenResponse
+	expiry    time.Time
+	pending   bool
+	delegated bool
+	mutex     sync.Mutex
+}
+
+// TokenSource will return a token source that requests tokens with the
+// specified scope. The optional token is used until it expires, which allows
+// continuing a password or authorization code flow using the refresh token.
+// The expiry of the token is calculated using the clock on the first call of
+// Token. If a token is provided, errors of the refresh are returned and the
+// source does not fall back to the client credentials flow.
+func (c *Client) TokenSource(scope oauth2.Scope, token *oauth2.TokenResponse) *TokenSource {
+	// prepare source
+	source := &TokenSource{
+		client: c,
+		scope:  scope,
+	}
+
+	// set token
+	if token != nil {
+		source.token = copyToken(token)
+		source.pending = true
+		source.delegated = true
+	}
+
+	return source
+}
+
+// Token will return a copy of the cached access token or obtain a new one if
+// it is missing or about to expire.
+func (s *TokenSource) Token() (*oauth2.TokenResponse, error) {
+	// acquire mutex
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// get margin
+	margin := s.Margin
+	if margin == 0 {
+		margin = DefaultExpiryMargin
+	}
+
+	// get time
+	now := s.now()
+
+	// set expiry of initial token
+	if s.pending {
+		s.set(s.token, now)
+		s.pending = false
+	}
+
+	// return cached token if still valid
+	if s.token != nil && (s.expiry.IsZero() || now.Add(margin).Before(s.expiry)) {
+		return copyToken(s.token), nil
+	}
+
+	// refresh token if available
+	var token *oauth2.TokenResponse
+	var err error
+	if s.token != nil && s.token.RefreshToken != "" {
+		token, err = s.client.Refresh(s.token.RefreshToken, s.scope)
+		if err != nil && (s.delegated || !rejectedGrant(err)) {
+			return nil, err
+		}
+
+		// keep refresh token if not rotated
+		if err == nil && token.RefreshToken == "" {
+			token.RefreshToken = s.token.RefreshToken
+		}
+	}
+
+	// check delegation
+	if token == nil && s.delegated {
+		return nil, errors.New("token expired and cannot be refreshed")
+	}
+
+	// otherwise, use client credentials
+	if token == nil {
+		token, err = s.client.ClientCredentials(s.scope)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// set token
+	s.set(token, now)
+
+	return copyToken(token), nil
+}
+
+// Wrap will return a round tripper that adds the access token as a bearer
+// token to all requests before passing them to the specified round tripper.
+// If the round tripper is nil, http.DefaultTransport is used.
+func (s *TokenSource) Wrap(rt http.RoundTripper) http.RoundTripper {
+	// set default
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+
+	return &transport{source: s, base: rt}
+}
+
+func (s *TokenSource) set(token *oauth2.TokenResponse, now time.Time) {
+	// set token
+	s.token = token
+
+	// set expiry
+	s.expiry = time.Time{}
+	if token.ExpiresIn > 0 {
+		s.expiry = now.Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+}
+
+func rejectedGrant(err error) bool {
+	// check error
+	var anError *oauth2.Error
+
+	return errors.As(err, &anError) && anError.Name == "invalid_grant"
+}
+
+func copyToken(token *oauth2.TokenResponse) *oauth2.TokenResponse {
+	// copy token
+	res := *token
+
+	// copy scope
+	if token.Scope != nil {
+		res.Scope = append(oauth2.Scope{}, token.Scope...)
+	}
+
+	// copy authorization details
+	if token.AuthorizationDetails != nil {
+		res.AuthorizationDetails = make([]oauth2.AuthorizationDetail, len(token.AuthorizationDetails))
+		for i, detail := range token.AuthorizationDetails {
+			detail.Locations = append([]string(nil), detail.Locations...)
+			detail.Actions = append([]string(nil), detail.Actions...)
+			detail.DataTypes = append([]string(nil), detail.DataTypes...)
+			detail.Privileges = append([]string(nil), detail.Privileges...)
+			res.AuthorizationDetails[i] = detail
+		}
+	}
+
+	return &res
+}
+
+func (s *TokenSource) now() time.Time {
+	// use clock if available
+	if s.Clock != nil {
+		return s.Clock.Now()
+	}
+
+	return time.Now()
+}
+
+type transport struct {
+	source *TokenSource
+	base   http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// get token
+	token, err := t.source.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	// clone request
+	req = req.Clone(req.Context())
+
+	// set header
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,169 @@
+package oauth2client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/256dpi/oauth2/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenSourceClientCredentials(t *testing.T) {
+	withServer(func(base string, srv *oauth2.Server) {
+		source := New(Default(base, "client1", "foo")).TokenSource(oauth2.Scope{"foo"}, nil)
+
+		trs1, err := source.Token()
+		assert.NoError(t, err)
+		assert.NotEmpty(t, trs1.AccessToken)
+
+		trs2, err := source.Token()
+		assert.NoError(t, err)
+		assert.Equal(t, trs1.AccessToken, trs2.AccessToken)
+
+		source.Clock = oauth2.ClockFunc(func() time.Time {
+			return time.Now().Add(time.Hour)
+		})
+
+		trs3, err := source.Token()
+		assert.NoError(t, err)
+		assert.NotEqual(t, trs1.AccessToken, trs3.AccessToken)
+	})
+}
+
+func TestTokenSourceRefresh(t *testing.T) {
+	withServer(func(base string, srv *oauth2.Server) {
+		client := New(Default(base, "client1", "foo"))
+
+		trs1, err := client.Password("user1", "foo", oauth2.Scope{"foo"})
+		assert.NoError(t, err)
+
+		source := client.TokenSource(oauth2.Scope{"foo"}, trs1)
+
+		trs2, err := source.Token()
+		assert.NoError(t, err)
+		assert.Equal(t, trs1.AccessToken, trs2.AccessToken)
+
+		source.Margin = 2 * time.Hour
+
+		trs3, err := source.Token()
+		assert.NoError(t, err)
+		assert.NotEqual(t, trs1.AccessToken, trs3.AccessToken)
+		assert.NotEmpty(t, trs3.RefreshToken)
+
+		trs3.AccessToken = "foo"
+		trs3.Scope[0] = "bar"
+
+		source.Margin = 0
+
+		trs4, err := source.Token()
+		assert.NoError(t, err)
+		assert.NotEqual(t, "foo", trs4.AccessToken)
+		assert.Equal(t, oauth2.Scope{"foo"}, trs4.Scope)
+
+		for key := range srv.RefreshTokens {
+			delete(srv.RefreshTokens, key)
+		}
+
+		source.Margin = 2 * time.Hour
+
+		_, err = source.Token()
+		assert.Error(t, err)
+		assert.Equal(t, "invalid_grant", err.(*oauth2.Error).Name)
+		assert.Len(t, srv.AccessTokens, 2)
+	})
+}
+
+func TestTokenSourceRejectedRefresh(t *testing.T) {
+	withServer(func(base string, srv *oauth2.Server) {
+		source := New(Default(base, "client1", "foo")).TokenSource(oauth2.Scope{"foo"}, nil)
+
+		trs1, err := source.Token()
+		assert.NoError(t, err)
+		assert.NotEmpty(t, trs1.RefreshToken)
+
+		for key := range srv.RefreshTokens {
+			delete(srv.RefreshTokens, key)
+		}
+
+		source.Margin = 2 * time.Hour
+
+		trs2, err := source.Token()
+		assert.NoError(t, err)
+		assert.NotEqual(t, trs1.AccessToken, trs2.AccessToken)
+		assert.NotEqual(t, trs1.RefreshToken, trs2.RefreshToken)
+	})
+}
+
+func TestTokenSourceExpiredDelegation(t *testing.T) {
+	withServer(func(base string, srv *oauth2.Server) {
+		client := New(Default(base, "client1", "foo"))
+
+		trs1, err := client.Password("user1", "foo", oauth2.Scope{"foo"})
+		assert.NoError(t, err)
+		trs1.RefreshToken = ""
+
+		source := client.TokenSource(oauth2.Scope{"foo"}, trs1)
+		source.Margin = 2 * time.Hour
+
+		_, err = source.Token()
+		assert.EqualError(t, err, "token expired and cannot be refreshed")
+	})
+}
+
+func TestTokenSourceInitialExpiry(t *testing.T) {
+	withServer(func(base string, srv *oauth2.Server) {
+		client := New(Default(base, "client1", "foo"))
+
+		trs1, err := client.Password("user1", "foo", oauth2.Scope{"foo"})
+		assert.NoError(t, err)
+
+		source := client.TokenSource(oauth2.Scope{"foo"}, trs1)
+		source.Clock = oauth2.ClockFunc(func() time.Time {
+			return time.Now().Add(-24 * time.Hour)
+		})
+
+		trs2, err := source.Token()
+		assert.NoError(t, err)
+		assert.Equal(t, trs1.AccessToken, trs2.AccessToken)
+
+		source.Clock = nil
+
+		trs3, err := source.Token()
+		assert.NoError(t, err)
+		assert.NotEqual(t, trs1.AccessToken, trs3.AccessToken)
+	})
+}
+
+func TestTokenSourceWrap(t *testing.T) {
+	withServer(func(base string, srv *oauth2.Server) {
+		source := New(Default(base, "client1", "foo")).TokenSource(oauth2.Scope{"foo"}, nil)
+
+		api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if srv.Authorize(w, r, oauth2.Scope{"foo"}) {
+				w.WriteHeader(http.StatusNoContent)
+			}
+		}))
+		defer api.Close()
+
+		client := &http.Client{Transport: source.Wrap(nil)}
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				res, err := client.Get(api.URL)
+				assert.NoError(t, err)
+				assert.Equal(t, http.StatusNoContent, res.StatusCode)
+				_ = res.Body.Close()
+			}()
+		}
+		wg.Wait()
+
+		assert.Len(t, srv.AccessTokens, 1)
+	})
+}
@@ -0,0 +1,51 @@
+// Package bearer provides the function signatures of the bearer package of the
+// first version implemented on top of the current oauth2 package.
+package bearer
+
+import (
+	"net/http"
+
+	"github.com/256dpi/oauth2/v2"
+)
+
+// TokenType is the bearer token type.
+const TokenType = oauth2.BearerAccessTokenType
+
+// NewTokenResponse creates and returns a new token response that carries a
+// bearer token.
+func NewTokenResponse(token string, expiresIn int) *oauth2.TokenResponse {
+	return oauth2.NewBearerTokenResponse(token, expiresIn)
+}
+
+// ParseToken parses and returns the bearer token from the authorization
+// header of the request.
+func ParseToken(r *http.Request) (string, error) {
+	return oauth2.ParseBearerToken(r)
+}
+
+// InvalidRequest constructs an invalid request error.
+func InvalidRequest(description string) *oauth2.Error {
+	return oauth2.InvalidRequest(description)
+}
+
+// InvalidToken constructs an invalid token error.
+func InvalidToken(description string) *oauth2.Error {
+	return oauth2.InvalidToken(description)
+}
+
+// InsufficientScope constructs an insufficient scope error.
+func InsufficientScope(necessaryScope string) *oauth2.Error {
+	return oauth2.InsufficientScope(necessaryScope)
+}
+
+// ProtectedResource constructs an error that indicates that the requested
+// resource needs authentication.
+func ProtectedResource() *oauth2.Error {
+	return oauth2.ProtectedResource()
+}
+
+// WriteError will write the specified error as a bearer challenge to the
+// response writer.
+func WriteError(w http.ResponseWriter, err error) error {
+	return oauth2.WriteBearerError(w, err)
+}
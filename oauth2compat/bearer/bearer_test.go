@@ -0,0 +1,32 @@
+package bearer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewTokenResponse(t *testing.T) {
+	res := NewTokenResponse("foo", 1)
+	assert.Equal(t, TokenType, res.TokenType)
+	assert.Equal(t, "foo", res.AccessToken)
+	assert.Equal(t, 1, res.ExpiresIn)
+}
+
+func TestParseToken(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer foo")
+
+	token, err := ParseToken(req)
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", token)
+}
+
+func TestWriteError(t *testing.T) {
+	rec := httptest.NewRecorder()
+	assert.NoError(t, WriteError(rec, InvalidToken("foo")))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, `Bearer error="invalid_token", error_description="foo"`, rec.Header().Get("WWW-Authenticate"))
+}
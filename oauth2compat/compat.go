@@ -0,0 +1,99 @@
+// Package oauth2compat provides the function signatures of the first version
+// of the package implemented on top of the current API. It allows projects
+// that use the old API style, where the state is passed to every error
+// builder and errors are redirected explicitly, to upgrade incrementally.
+//
+// New code should use the oauth2 package directly.
+package oauth2compat
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/256dpi/oauth2/v2"
+)
+
+// Error is an alias for oauth2.Error.
+type Error = oauth2.Error
+
+// InvalidRequest constructs an invalid request error with the specified state.
+func InvalidRequest(state, description string) *Error {
+	return withState(oauth2.InvalidRequest(description), state)
+}
+
+// InvalidClient constructs an invalid client error with the specified state.
+func InvalidClient(state, description string) *Error {
+	return withState(oauth2.InvalidClient(description), state)
+}
+
+// InvalidGrant constructs an invalid grant error with the specified state.
+func InvalidGrant(state, description string) *Error {
+	return withState(oauth2.InvalidGrant(description), state)
+}
+
+// InvalidScope constructs an invalid scope error with the specified state.
+func InvalidScope(state, description string) *Error {
+	return withState(oauth2.InvalidScope(description), state)
+}
+
+// UnauthorizedClient constructs an unauthorized client error with the
+// specified state.
+func UnauthorizedClient(state, description string) *Error {
+	return withState(oauth2.UnauthorizedClient(description), state)
+}
+
+// UnsupportedGrantType constructs an unsupported grant type error with the
+// specified state.
+func UnsupportedGrantType(state, description string) *Error {
+	return withState(oauth2.UnsupportedGrantType(description), state)
+}
+
+// UnsupportedResponseType constructs an unsupported response type error with
+// the specified state.
+func UnsupportedResponseType(state, description string) *Error {
+	return withState(oauth2.UnsupportedResponseType(description), state)
+}
+
+// AccessDenied constructs an access denied error with the specified state.
+func AccessDenied(state, description string) *Error {
+	return withState(oauth2.AccessDenied(description), state)
+}
+
+// ServerError constructs a server error with the specified state.
+func ServerError(state, description string) *Error {
+	return withState(oauth2.ServerError(description), state)
+}
+
+// TemporarilyUnavailable constructs a temporarily unavailable error with the
+// specified state.
+func TemporarilyUnavailable(state, description string) *Error {
+	return withState(oauth2.TemporarilyUnavailable(description), state)
+}
+
+// WriteError will write the specified error to the response writer. It is
+// equivalent to oauth2.WriteError.
+func WriteError(w http.ResponseWriter, err error) error {
+	return oauth2.WriteError(w, err)
+}
+
+// RedirectError will redirect the specified error to the specified URI by
+// adding its parameters to the query or fragment. Errors that do not wrap an
+// Error are redirected as server errors.
+func RedirectError(w http.ResponseWriter, uri string, useFragment bool, err error) error {
+	// ensure complex error
+	var anError *Error
+	if !errors.As(err, &anError) {
+		anError = oauth2.ServerError("").SetCause(err)
+	}
+
+	// copy error
+	redirect := *anError
+	redirect.SetRedirect(uri, anError.State, useFragment)
+
+	return oauth2.WriteError(w, &redirect)
+}
+
+func withState(err *Error, state string) *Error {
+	err.State = state
+	return err
+}
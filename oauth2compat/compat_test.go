@@ -0,0 +1,59 @@
+package oauth2compat
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorBuilders(t *testing.T) {
+	for _, err := range []*Error{
+		InvalidRequest("state", "foo"),
+		InvalidClient("state", "foo"),
+		InvalidGrant("state", "foo"),
+		InvalidScope("state", "foo"),
+		UnauthorizedClient("state", "foo"),
+		UnsupportedGrantType("state", "foo"),
+		UnsupportedResponseType("state", "foo"),
+		AccessDenied("state", "foo"),
+		ServerError("state", "foo"),
+		TemporarilyUnavailable("state", "foo"),
+	} {
+		assert.Equal(t, "state", err.State, err.Name)
+		assert.Equal(t, "foo", err.Description, err.Name)
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	rec := httptest.NewRecorder()
+	assert.NoError(t, WriteError(rec, InvalidRequest("state", "foo")))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.JSONEq(t, `{
+		"error": "invalid_request",
+		"error_description": "foo",
+		"state": "state"
+	}`, rec.Body.String())
+}
+
+func TestRedirectError(t *testing.T) {
+	err := InvalidRequest("state", "foo")
+
+	rec := httptest.NewRecorder()
+	assert.NoError(t, RedirectError(rec, "http://example.com", false, err))
+	assert.Equal(t, http.StatusSeeOther, rec.Code)
+	assert.Equal(t, "http://example.com?error=invalid_request&error_description=foo&state=state", rec.Header().Get("Location"))
+	assert.Empty(t, err.RedirectURI)
+
+	rec = httptest.NewRecorder()
+	assert.NoError(t, RedirectError(rec, "http://example.com", true, err))
+	assert.Equal(t, http.StatusSeeOther, rec.Code)
+	assert.Equal(t, "http://example.com#error=invalid_request&error_description=foo&state=state", rec.Header().Get("Location"))
+
+	rec = httptest.NewRecorder()
+	assert.NoError(t, RedirectError(rec, "http://example.com", false, errors.New("foo")))
+	assert.Equal(t, http.StatusSeeOther, rec.Code)
+	assert.Equal(t, "http://example.com?error=server_error", rec.Header().Get("Location"))
+}
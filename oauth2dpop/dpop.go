@@ -0,0 +1,235 @@
+// Package oauth2dpop implements the demonstration of proof-of-possession
+// (DPoP) mechanism defined by RFC 9449 on top of the oauth2 package.
+//
+// Clients sign a proof for every request with a private key. Authorization
+// servers bind issued tokens to the thumbprint of the public key (cnf/jkt)
+// and resource servers only accept a bound token together with a matching
+// proof for the request.
+package oauth2dpop
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/256dpi/oauth2/v2"
+)
+
+// Header is the header that carries DPoP proofs.
+const Header = oauth2.InstanceProofHeader
+
+// TokenType is the DPoP access token type.
+const TokenType = "DPoP"
+
+// NewTokenResponse creates and returns a new token response that carries a
+// DPoP bound access token.
+func NewTokenResponse(token string, expiresIn int) *oauth2.TokenResponse {
+	return oauth2.NewTokenResponse(TokenType, token, expiresIn)
+}
+
+// Bind will bind the token represented by the specified claims to the key
+// with the specified thumbprint by setting the confirmation claim (cnf).
+func Bind(claims oauth2.Claims, thumbprint string) {
+	claims.Set("cnf", map[string]interface{}{
+		"jkt": thumbprint,
+	})
+}
+
+// Thumbprint returns the key thumbprint the token represented by the specified
+// claims is bound to. An empty string is returned if the token is not bound.
+func Thumbprint(claims oauth2.Claims) string {
+	// get confirmation
+	cnf, _ := claims["cnf"].(map[string]interface{})
+
+	// get thumbprint
+	jkt, _ := cnf["jkt"].(string)
+
+	return jkt
+}
+
+// ParseToken parses and returns the DPoP access token from the authorization
+// header of the request. It will return an Error if the extraction failed.
+func ParseToken(r *http.Request) (string, error) {
+	// read header
+	h := r.Header.Get("Authorization")
+	if h == "" {
+		return "", oauth2.ProtectedResource()
+	}
+
+	// split header
+	s := strings.SplitN(h, " ", 2)
+	if len(s) != 2 || !strings.EqualFold(s[0], TokenType) || s[1] == "" {
+		return "", oauth2.InvalidRequest("malformed authorization header")
+	}
+
+	return s[1], nil
+}
+
+// ParseProof parses and verifies the signature of the single proof carried by
+// the request. It will return an Error if the proof is missing or invalid.
+func ParseProof(r *http.Request) (*oauth2.InstanceProof, error) {
+	// get headers
+	values := r.Header[http.CanonicalHeaderKey(Header)]
+	if len(values) == 0 {
+		return nil, InvalidProof("missing proof")
+	} else if len(values) > 1 {
+		return nil, InvalidProof("multiple proofs")
+	}
+
+	// parse proof
+	proof, err := oauth2.ParseInstanceProof(values[0])
+	if err != nil {
+		return nil, InvalidProof(err.Error())
+	}
+
+	return proof, nil
+}
+
+// InvalidProof constructs an error that indicates that the provided proof is
+// missing, malformed or does not match the request.
+func InvalidProof(description string) *oauth2.Error {
+	return &oauth2.Error{
+		Status:      http.StatusBadRequest,
+		Name:        "invalid_dpop_proof",
+		Description: description,
+	}
+}
+
+// WriteError will write the specified error as a DPoP challenge to the
+// response writer. Invalid proof errors are written with an unauthorized
+// status as required for protected resources. The function will fall back and
+// write an internal server error if the specified error is not known.
+func WriteError(w http.ResponseWriter, err error) error {
+	// ensure complex error
+	var anError *oauth2.Error
+	if !errors.As(err, &anError) || anError.Status == http.StatusInternalServerError {
+		// write internal server error
+		w.WriteHeader(http.StatusInternalServerError)
+
+		// finish response
+		_, err = w.Write(nil)
+
+		return err
+	}
+
+	// get params
+	params := anError.Params()
+
+	// force at least one parameter
+	if params == "" {
+		params = `realm="OAuth2"`
+	}
+
+	// set header
+	w.Header().Set("WWW-Authenticate", TokenType+" "+params)
+
+	// get status
+	status := anError.Status
+	if anError.Name == "invalid_dpop_proof" {
+		status = http.StatusUnauthorized
+	}
+
+	// write header
+	w.WriteHeader(status)
+
+	// finish response
+	_, err = w.Write(nil)
+
+	return err
+}
+
+// A Verifier verifies the proofs of token and resource requests and prevents
+// their replay.
+type Verifier struct {
+	// The maximum difference between the issue time of a proof and the
+	// current time. Defaults to five minutes.
+	MaxAge time.Duration
+
+	// The function used to get the current time. Defaults to time.Now.
+	Clock func() time.Time
+
+	// The external base URL of the server (e.g. "https://api.example.com").
+	// If set, proofs must match its scheme and host instead of the ones of
+	// the request, which is required behind TLS terminating proxies.
+	BaseURL string
+
+	checker oauth2.ProofChecker
+}
+
+// VerifyTokenRequest will verify the proof of the specified token request and
+// return it. The thumbprint of the proof should be used to bind the issued
+// tokens.
+func (v *Verifier) VerifyTokenRequest(r *http.Request) (*oauth2.InstanceProof, error) {
+	// parse proof
+	proof, err := ParseProof(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// check proof
+	err = v.check(r, proof)
+	if err != nil {
+		return nil, err
+	}
+
+	return proof, nil
+}
+
+// VerifyResourceRequest will verify the proof of the specified resource
+// request and return it. The proof must be signed by the key with the
+// specified thumbprint the presented access token is bound to and must carry
+// the hash of the access token.
+func (v *Verifier) VerifyResourceRequest(r *http.Request, accessToken, thumbprint string) (*oauth2.InstanceProof, error) {
+	// parse proof
+	proof, err := ParseProof(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// check binding
+	if thumbprint == "" || proof.Thumbprint != thumbprint {
+		return nil, InvalidProof("key mismatch")
+	}
+
+	// check access token hash
+	if proof.AccessTokenHash != oauth2.AccessTokenHash(accessToken) {
+		return nil, InvalidProof("access token mismatch")
+	}
+
+	// check proof
+	err = v.check(r, proof)
+	if err != nil {
+		return nil, err
+	}
+
+	return proof, nil
+}
+
+func (v *Verifier) check(r *http.Request, proof *oauth2.InstanceProof) error {
+	// get request url
+	uri := oauth2.ProofURL(r, r.TLS != nil)
+	if v.BaseURL != "" {
+		base, err := url.Parse(v.BaseURL)
+		if err != nil {
+			return err
+		}
+		uri.Scheme = base.Scheme
+		uri.Host = base.Host
+	}
+
+	// get time
+	now := time.Now()
+	if v.Clock != nil {
+		now = v.Clock()
+	}
+
+	// check proof
+	err := v.checker.Check(proof, r.Method, uri, now, v.MaxAge)
+	if err != nil {
+		return InvalidProof(err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,171 @@
+package oauth2dpop
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/256dpi/oauth2/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewTokenResponse(t *testing.T) {
+	res := NewTokenResponse("foo", 1)
+	assert.Equal(t, TokenType, res.TokenType)
+	assert.Equal(t, "foo", res.AccessToken)
+	assert.Equal(t, 1, res.ExpiresIn)
+}
+
+func TestBind(t *testing.T) {
+	claims := oauth2.Claims{}
+	assert.Equal(t, "", Thumbprint(claims))
+
+	Bind(claims, "foo")
+	assert.Equal(t, "foo", Thumbprint(claims))
+}
+
+func TestParseToken(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	_, err := ParseToken(req)
+	assert.Equal(t, oauth2.ProtectedResource(), err)
+
+	req.Header.Set("Authorization", "Bearer foo")
+	_, err = ParseToken(req)
+	assert.Equal(t, oauth2.InvalidRequest("malformed authorization header"), err)
+
+	req.Header.Set("Authorization", "DPoP foo")
+	token, err := ParseToken(req)
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", token)
+}
+
+func TestVerifier(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	now := time.Now().Truncate(time.Second)
+
+	verifier := &Verifier{
+		Clock: func() time.Time {
+			return now
+		},
+	}
+
+	request := func(method, path, proof string) *http.Request {
+		req := httptest.NewRequest(method, path, nil)
+		if proof != "" {
+			req.Header.Set(Header, proof)
+		}
+		return req
+	}
+
+	// token request
+	str, err := oauth2.GenerateInstanceProof(key, "POST", "http://example.com/oauth2/token", now)
+	assert.NoError(t, err)
+
+	proof, err := verifier.VerifyTokenRequest(request("POST", "/oauth2/token", str))
+	assert.NoError(t, err)
+	assert.NotEmpty(t, proof.Thumbprint)
+
+	// replayed proof
+	_, err = verifier.VerifyTokenRequest(request("POST", "/oauth2/token", str))
+	assert.Equal(t, InvalidProof("replayed proof"), err)
+
+	// missing proof
+	_, err = verifier.VerifyTokenRequest(request("POST", "/oauth2/token", ""))
+	assert.Equal(t, InvalidProof("missing proof"), err)
+
+	// multiple proofs
+	req := request("POST", "/oauth2/token", str)
+	req.Header.Add(Header, str)
+	_, err = verifier.VerifyTokenRequest(req)
+	assert.Equal(t, InvalidProof("multiple proofs"), err)
+
+	// invalid proof
+	_, err = verifier.VerifyTokenRequest(request("POST", "/oauth2/token", "foo"))
+	assert.Equal(t, InvalidProof("a JWT must have three segments separated by a dot"), err)
+
+	// request mismatch
+	str, err = oauth2.GenerateInstanceProof(key, "POST", "http://example.com/oauth2/token", now)
+	assert.NoError(t, err)
+	_, err = verifier.VerifyTokenRequest(request("POST", "/oauth2/revoke", str))
+	assert.Equal(t, InvalidProof("request mismatch"), err)
+
+	// host mismatch
+	str, err = oauth2.GenerateInstanceProof(key, "POST", "http://other.com/oauth2/token", now)
+	assert.NoError(t, err)
+	_, err = verifier.VerifyTokenRequest(request("POST", "/oauth2/token", str))
+	assert.Equal(t, InvalidProof("request mismatch"), err)
+
+	// scheme mismatch
+	str, err = oauth2.GenerateInstanceProof(key, "POST", "https://example.com/oauth2/token", now)
+	assert.NoError(t, err)
+	_, err = verifier.VerifyTokenRequest(request("POST", "/oauth2/token", str))
+	assert.Equal(t, InvalidProof("request mismatch"), err)
+
+	// base url
+	verifier.BaseURL = "https://example.com"
+	_, err = verifier.VerifyTokenRequest(request("POST", "/oauth2/token", str))
+	assert.NoError(t, err)
+	verifier.BaseURL = ""
+
+	// stale proof
+	str, err = oauth2.GenerateInstanceProof(key, "POST", "http://example.com/oauth2/token", now.Add(-time.Hour))
+	assert.NoError(t, err)
+	_, err = verifier.VerifyTokenRequest(request("POST", "/oauth2/token", str))
+	assert.Equal(t, InvalidProof("stale proof"), err)
+
+	// resource request
+	str, err = oauth2.GenerateResourceProof(key, "GET", "http://example.com/api", "foo", now)
+	assert.NoError(t, err)
+	_, err = verifier.VerifyResourceRequest(request("GET", "/api", str), "foo", proof.Thumbprint)
+	assert.NoError(t, err)
+
+	// unbound token
+	str, err = oauth2.GenerateResourceProof(key, "GET", "http://example.com/api", "foo", now)
+	assert.NoError(t, err)
+	_, err = verifier.VerifyResourceRequest(request("GET", "/api", str), "foo", "")
+	assert.Equal(t, InvalidProof("key mismatch"), err)
+
+	// other key
+	str, err = oauth2.GenerateResourceProof(otherKey, "GET", "http://example.com/api", "foo", now)
+	assert.NoError(t, err)
+	_, err = verifier.VerifyResourceRequest(request("GET", "/api", str), "foo", proof.Thumbprint)
+	assert.Equal(t, InvalidProof("key mismatch"), err)
+
+	// other token
+	str, err = oauth2.GenerateResourceProof(key, "GET", "http://example.com/api", "bar", now)
+	assert.NoError(t, err)
+	_, err = verifier.VerifyResourceRequest(request("GET", "/api", str), "foo", proof.Thumbprint)
+	assert.Equal(t, InvalidProof("access token mismatch"), err)
+
+	// missing token hash
+	str, err = oauth2.GenerateInstanceProof(key, "GET", "http://example.com/api", now)
+	assert.NoError(t, err)
+	_, err = verifier.VerifyResourceRequest(request("GET", "/api", str), "foo", proof.Thumbprint)
+	assert.Equal(t, InvalidProof("access token mismatch"), err)
+}
+
+func TestWriteError(t *testing.T) {
+	rec := httptest.NewRecorder()
+	assert.NoError(t, WriteError(rec, InvalidProof("foo")))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, `DPoP error="invalid_dpop_proof", error_description="foo"`, rec.Header().Get("WWW-Authenticate"))
+
+	rec = httptest.NewRecorder()
+	assert.NoError(t, WriteError(rec, oauth2.InvalidToken("foo")))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, `DPoP error="invalid_token", error_description="foo"`, rec.Header().Get("WWW-Authenticate"))
+
+	rec = httptest.NewRecorder()
+	assert.NoError(t, WriteError(rec, oauth2.ProtectedResource()))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, `DPoP realm="OAuth2"`, rec.Header().Get("WWW-Authenticate"))
+}
@@ -0,0 +1,149 @@
+// Package oauth2lambda provides an adapter to run OAuth2 endpoints and
+// protected resources as AWS Lambda functions behind API Gateway HTTP APIs or
+// Lambda Function URLs.
+//
+// The types mirror the payload format version 2.0 and can be used directly as
+// handler arguments and return values with the official Lambda runtime.
+package oauth2lambda
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/256dpi/oauth2/v2"
+)
+
+// RequestHTTP holds the HTTP details of a request context.
+type RequestHTTP struct {
+	Method    string `json:"method"`
+	Path      string `json:"path"`
+	Protocol  string `json:"protocol"`
+	SourceIP  string `json:"sourceIp"`
+	UserAgent string `json:"userAgent"`
+}
+
+// RequestContext holds the context of a request.
+type RequestContext struct {
+	DomainName string      `json:"domainName"`
+	RequestID  string      `json:"requestId"`
+	HTTP       RequestHTTP `json:"http"`
+}
+
+// Request is an API Gateway HTTP API or Lambda Function URL request event.
+type Request struct {
+	Version         string            `json:"version"`
+	RawPath         string            `json:"rawPath"`
+	RawQueryString  string            `json:"rawQueryString"`
+	Cookies         []string          `json:"cookies,omitempty"`
+	Headers         map[string]string `json:"headers"`
+	RequestContext  RequestContext    `json:"requestContext"`
+	Body            string            `json:"body,omitempty"`
+	IsBase64Encoded bool              `json:"isBase64Encoded"`
+}
+
+// Response is an API Gateway HTTP API or Lambda Function URL response.
+type Response struct {
+	StatusCode      int               `json:"statusCode"`
+	Headers         map[string]string `json:"headers,omitempty"`
+	Cookies         []string          `json:"cookies,omitempty"`
+	Body            string            `json:"body"`
+	IsBase64Encoded bool              `json:"isBase64Encoded"`
+}
+
+// NewRequest will convert the provided request event to an HTTP request that
+// can be passed to the parsing functions and handlers.
+func NewRequest(ctx context.Context, event Request) (*http.Request, error) {
+	// prepare url
+	url := event.RawPath
+	if event.RawQueryString != "" {
+		url += "?" + event.RawQueryString
+	}
+
+	// get body
+	body := event.Body
+	if event.IsBase64Encoded {
+		data, err := base64.StdEncoding.DecodeString(body)
+		if err != nil {
+			return nil, err
+		}
+
+		body = string(data)
+	}
+
+	// create request
+	r, err := http.NewRequest(event.RequestContext.HTTP.Method, url, strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	// add headers
+	for k, v := range event.Headers {
+		r.Header.Set(k, v)
+	}
+
+	// add cookies
+	if len(event.Cookies) > 0 {
+		r.Header.Set("Cookie", strings.Join(event.Cookies, "; "))
+	}
+
+	// set host and remote address
+	r.Host = event.RequestContext.DomainName
+	r.RemoteAddr = event.RequestContext.HTTP.SourceIP
+	r.RequestURI = url
+
+	return r.WithContext(ctx), nil
+}
+
+// NewResponse will convert the provided response buffer to a response.
+func NewResponse(buf *oauth2.ResponseBuffer) Response {
+	// prepare response
+	res := Response{
+		StatusCode: buf.Status,
+		Headers:    map[string]string{},
+	}
+
+	// set implicit status
+	if res.StatusCode == 0 {
+		res.StatusCode = http.StatusOK
+	}
+
+	// add headers
+	for k, v := range buf.Headers {
+		if k == "Set-Cookie" {
+			res.Cookies = append(res.Cookies, v...)
+		} else {
+			res.Headers[k] = strings.Join(v, ", ")
+		}
+	}
+
+	// set body
+	if utf8.Valid(buf.Body.Bytes()) {
+		res.Body = buf.Body.String()
+	} else {
+		res.Body = base64.StdEncoding.EncodeToString(buf.Body.Bytes())
+		res.IsBase64Encoded = true
+	}
+
+	return res
+}
+
+// Handle will serve the provided request event using the specified handler and
+// return the written response.
+func Handle(ctx context.Context, handler http.Handler, event Request) (Response, error) {
+	// convert request
+	r, err := NewRequest(ctx, event)
+	if err != nil {
+		return Response{}, err
+	}
+
+	// prepare buffer
+	buf := oauth2.NewResponseBuffer()
+
+	// handle request
+	handler.ServeHTTP(buf, r)
+
+	return NewResponse(buf), nil
+}
@@ -0,0 +1,98 @@
+package oauth2lambda
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/256dpi/oauth2/v2"
+)
+
+func TestHandle(t *testing.T) {
+	server := oauth2.NewServer(oauth2.DefaultServerConfig([]byte("secret"), oauth2.Scope{"foo"}))
+	server.Clients["client"] = &oauth2.ServerEntity{
+		Secret:       "secret",
+		Confidential: true,
+	}
+
+	body := url.Values{
+		"grant_type": []string{oauth2.ClientCredentialsGrantType},
+		"scope":      []string{"foo"},
+	}.Encode()
+
+	res, err := Handle(context.Background(), server, Request{
+		Version: "2.0",
+		RawPath: "/oauth2/token",
+		Headers: map[string]string{
+			"content-type":  "application/x-www-form-urlencoded",
+			"authorization": "Basic " + base64.StdEncoding.EncodeToString([]byte("client:secret")),
+		},
+		RequestContext: RequestContext{
+			DomainName: "example.com",
+			HTTP: RequestHTTP{
+				Method: "POST",
+				Path:   "/oauth2/token",
+			},
+		},
+		Body:            base64.StdEncoding.EncodeToString([]byte(body)),
+		IsBase64Encoded: true,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "application/json;charset=UTF-8", res.Headers["Content-Type"])
+	assert.Equal(t, "no-store", res.Headers["Cache-Control"])
+	assert.Contains(t, res.Body, `"access_token"`)
+	assert.False(t, res.IsBase64Encoded)
+}
+
+func TestNewRequest(t *testing.T) {
+	r, err := NewRequest(context.Background(), Request{
+		RawPath:        "/oauth2/authorize",
+		RawQueryString: "client_id=foo&state=bar",
+		Cookies:        []string{"a=1", "b=2"},
+		Headers: map[string]string{
+			"accept": "application/json",
+		},
+		RequestContext: RequestContext{
+			DomainName: "example.com",
+			HTTP: RequestHTTP{
+				Method:   "GET",
+				SourceIP: "1.2.3.4",
+			},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "GET", r.Method)
+	assert.Equal(t, "example.com", r.Host)
+	assert.Equal(t, "1.2.3.4", r.RemoteAddr)
+	assert.Equal(t, "foo", r.URL.Query().Get("client_id"))
+	assert.Equal(t, "application/json", r.Header.Get("Accept"))
+	assert.Len(t, r.Cookies(), 2)
+
+	_, err = NewRequest(context.Background(), Request{
+		Body:            "%",
+		IsBase64Encoded: true,
+	})
+	assert.Error(t, err)
+}
+
+func TestNewResponse(t *testing.T) {
+	buf := oauth2.NewResponseBuffer()
+	buf.Header().Add("Set-Cookie", "a=1")
+	buf.Header().Add("Vary", "Accept")
+	buf.Header().Add("Vary", "Origin")
+	_, _ = buf.Write([]byte{0xff})
+
+	res := NewResponse(buf)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, []string{"a=1"}, res.Cookies)
+	assert.Equal(t, map[string]string{
+		"Vary": "Accept, Origin",
+	}, res.Headers)
+	assert.Equal(t, "/w==", res.Body)
+	assert.True(t, res.IsBase64Encoded)
+}
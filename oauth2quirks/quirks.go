@@ -0,0 +1,137 @@
+// Package oauth2quirks emulates well-known deviations of popular providers
+// from the OAuth2 spec on top of an existing server (e.g. oauth2.Server). It
+// allows testing client libraries against realistic non-conformant servers.
+package oauth2quirks
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/256dpi/oauth2/v2"
+)
+
+// Quirks configures the emulated deviations. They are only applied to JSON
+// responses of the token endpoint.
+type Quirks struct {
+	// Return the lifespan of access tokens as "expires" instead of
+	// "expires_in" (Facebook).
+	ExpiresField bool
+
+	// Return form encoded token responses unless the request accepts JSON
+	// (GitHub).
+	FormEncodedResponses bool
+
+	// Omit the "token_type" member of token responses.
+	OmitTokenType bool
+
+	// Separate the tokens of the granted scope with commas instead of
+	// spaces (GitHub, Facebook).
+	CommaSeparatedScope bool
+
+	// Return errors with a 200 OK status (GitHub).
+	ErrorsWithOK bool
+}
+
+// Facebook returns the quirks of the Facebook token endpoint.
+func Facebook() Quirks {
+	return Quirks{
+		ExpiresField:        true,
+		CommaSeparatedScope: true,
+	}
+}
+
+// GitHub returns the quirks of the GitHub token endpoint.
+func GitHub() Quirks {
+	return Quirks{
+		FormEncodedResponses: true,
+		CommaSeparatedScope:  true,
+		ErrorsWithOK:         true,
+	}
+}
+
+// Wrap will return a handler that applies the quirks to the responses of the
+// token endpoint (path ending in "/token") of the specified handler.
+func Wrap(handler http.Handler, quirks Quirks) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// check path
+		if !strings.HasSuffix(r.URL.Path, "/token") {
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		// capture response
+		res := oauth2.NewResponseBuffer()
+		handler.ServeHTTP(res, r)
+
+		// apply quirks
+		quirks.apply(res, strings.Contains(r.Header.Get("Accept"), "application/json"))
+
+		// write response
+		_ = res.Replay(w)
+	})
+}
+
+func (q Quirks) apply(res *oauth2.ResponseBuffer, acceptsJSON bool) {
+	// check content type
+	contentType, _, _ := mime.ParseMediaType(res.Headers.Get("Content-Type"))
+	if contentType != "application/json" {
+		return
+	}
+
+	// decode body
+	var body map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(res.Body.Bytes()))
+	dec.UseNumber()
+	if dec.Decode(&body) != nil {
+		return
+	}
+
+	// apply error status
+	if q.ErrorsWithOK && body["error"] != nil {
+		res.Status = http.StatusOK
+	}
+
+	// apply token response quirks
+	if body["access_token"] != nil {
+		// rename expires in
+		if q.ExpiresField && body["expires_in"] != nil {
+			body["expires"] = body["expires_in"]
+			delete(body, "expires_in")
+		}
+
+		// remove token type
+		if q.OmitTokenType {
+			delete(body, "token_type")
+		}
+
+		// separate scope with commas
+		if scope, ok := body["scope"].(string); ok && q.CommaSeparatedScope {
+			body["scope"] = strings.Replace(scope, " ", ",", -1)
+		}
+	}
+
+	// reset body
+	res.Body.Reset()
+	res.Headers.Del("Content-Length")
+
+	// encode form if requested
+	if q.FormEncodedResponses && !acceptsJSON {
+		values := url.Values{}
+		for key, value := range body {
+			values.Set(key, fmt.Sprint(value))
+		}
+
+		res.Headers.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+		res.Body.WriteString(values.Encode())
+
+		return
+	}
+
+	// encode json
+	_ = json.NewEncoder(&res.Body).Encode(body)
+}
@@ -0,0 +1,93 @@
+package oauth2quirks
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/256dpi/oauth2/v2"
+	"github.com/256dpi/oauth2/v2/oauth2test"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestServer() *oauth2.Server {
+	server := oauth2.NewServer(oauth2.DefaultServerConfig([]byte("secret"), oauth2.Scope{"foo", "bar"}))
+	server.Clients["client1"] = &oauth2.ServerEntity{
+		Secret:       "foo",
+		Confidential: true,
+	}
+
+	return server
+}
+
+func token(handler http.Handler, password string, header map[string]string) *oauth2test.Response {
+	return oauth2test.Do(handler, &oauth2test.Request{
+		Method:   "POST",
+		Path:     "/oauth2/token",
+		Header:   header,
+		Username: "client1",
+		Password: password,
+		Form: map[string]string{
+			"grant_type": oauth2.ClientCredentialsGrantType,
+			"scope":      "foo bar",
+		},
+	})
+}
+
+func TestWrap(t *testing.T) {
+	server := newTestServer()
+
+	res := token(Wrap(server, Quirks{}), "foo", nil)
+	assert.Equal(t, http.StatusOK, res.Status)
+	assert.Equal(t, "bearer", res.String("token_type"))
+	assert.Equal(t, "foo bar", res.String("scope"))
+	assert.NotZero(t, res.Float("expires_in"))
+
+	res = token(Wrap(server, Quirks{OmitTokenType: true}), "foo", nil)
+	assert.Equal(t, http.StatusOK, res.Status)
+	assert.NotEmpty(t, res.String("access_token"))
+	assert.Nil(t, res.JSON["token_type"])
+
+	res = oauth2test.Do(Wrap(server, GitHub()), &oauth2test.Request{
+		Method: "GET",
+		Path:   "/oauth2/introspect",
+	})
+	assert.Equal(t, http.StatusBadRequest, res.Status)
+}
+
+func TestFacebook(t *testing.T) {
+	server := newTestServer()
+
+	res := token(Wrap(server, Facebook()), "foo", nil)
+	assert.Equal(t, http.StatusOK, res.Status)
+	assert.Equal(t, "bearer", res.String("token_type"))
+	assert.Equal(t, "foo,bar", res.String("scope"))
+	assert.NotZero(t, res.Float("expires"))
+	assert.Nil(t, res.JSON["expires_in"])
+}
+
+func TestGitHub(t *testing.T) {
+	server := newTestServer()
+
+	res := token(Wrap(server, GitHub()), "foo", nil)
+	assert.Equal(t, http.StatusOK, res.Status)
+	assert.Equal(t, "application/x-www-form-urlencoded; charset=utf-8", res.Header.Get("Content-Type"))
+
+	values, err := url.ParseQuery(res.Body)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, values.Get("access_token"))
+	assert.Equal(t, "bearer", values.Get("token_type"))
+	assert.Equal(t, "foo,bar", values.Get("scope"))
+
+	res = token(Wrap(server, GitHub()), "foo", map[string]string{
+		"Accept": "application/json",
+	})
+	assert.Equal(t, http.StatusOK, res.Status)
+	assert.Equal(t, "foo,bar", res.String("scope"))
+
+	res = token(Wrap(server, GitHub()), "bar", map[string]string{
+		"Accept": "application/json",
+	})
+	assert.Equal(t, http.StatusOK, res.Status)
+	assert.Equal(t, "invalid_client", res.String("error"))
+}
@@ -0,0 +1,101 @@
+package oauth2test
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+)
+
+// NewAuthorizationRequest returns a form encoded request for the authorization
+// endpoint that uses the confidential client, primary redirect URI and valid
+// scope of the spec. It also includes the valid authorization params and
+// headers. The specified params override the defaults, an empty value removes
+// the param.
+func NewAuthorizationRequest(spec *Spec, responseType string, params map[string]string) *http.Request {
+	// prepare params
+	params = extend(extend(spec.ValidAuthorizationParams, map[string]string{
+		"response_type": responseType,
+		"client_id":     spec.ConfidentialClientID,
+		"redirect_uri":  spec.PrimaryRedirectURI,
+		"scope":         spec.ValidScope,
+		"state":         "xyz",
+	}), params)
+
+	// create request
+	r := newFormRequest(spec.AuthorizeEndpoint, params)
+
+	// add headers
+	for k, v := range spec.ValidAuthorizationHeaders {
+		r.Header.Set(k, v)
+	}
+
+	return r
+}
+
+// NewTokenRequest returns a form encoded request for the token endpoint that
+// is authenticated with the confidential client of the spec using Basic auth.
+// The specified params override the defaults, an empty value removes the
+// param.
+func NewTokenRequest(spec *Spec, grantType string, params map[string]string) *http.Request {
+	// prepare params
+	params = extend(map[string]string{
+		"grant_type": grantType,
+		"scope":      spec.ValidScope,
+	}, params)
+
+	// create request
+	r := newFormRequest(spec.TokenEndpoint, params)
+
+	// set basic auth
+	r.SetBasicAuth(spec.ConfidentialClientID, spec.ConfidentialClientSecret)
+
+	return r
+}
+
+// PKCEParams returns the params for a PKCE challenge derived from the
+// specified verifier using the S256 method.
+func PKCEParams(verifier string) map[string]string {
+	// compute challenge
+	sum := sha256.Sum256([]byte(verifier))
+
+	return map[string]string{
+		"code_challenge":        base64.RawURLEncoding.EncodeToString(sum[:]),
+		"code_challenge_method": "S256",
+	}
+}
+
+// Perform will perform the specified request on the specified handler and
+// return the recorded response.
+func Perform(handler http.Handler, r *http.Request) *httptest.ResponseRecorder {
+	// prepare recorder
+	rec := httptest.NewRecorder()
+
+	// handle request
+	handler.ServeHTTP(rec, r)
+
+	return rec
+}
+
+func newFormRequest(path string, params map[string]string) *http.Request {
+	// prepare form
+	form := make(url.Values)
+	for k, v := range params {
+		if v != "" {
+			form.Set(k, v)
+		}
+	}
+
+	// create request
+	r, err := http.NewRequest("POST", path, strings.NewReader(form.Encode()))
+	if err != nil {
+		panic(err)
+	}
+
+	// set content type
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	return r
+}
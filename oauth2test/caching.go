@@ -0,0 +1,132 @@
+package oauth2test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// AssertNotCacheable asserts that the recorded response cannot be stored by
+// caches. It must forbid storage using the Cache-Control header and must not
+// carry validators like ETag or Last-Modified.
+func AssertNotCacheable(t *testing.T, r *httptest.ResponseRecorder) bool {
+	ok := assert.Contains(t, r.Header().Get("Cache-Control"), "no-store", debug(r))
+	ok = assert.Empty(t, r.Header().Get("ETag"), debug(r)) && ok
+	ok = assert.Empty(t, r.Header().Get("Last-Modified"), debug(r)) && ok
+
+	return ok
+}
+
+// CachingTest validates that no response of the endpoints can be cached and
+// that identical token requests yield distinct tokens.
+func CachingTest(t *testing.T, spec *Spec) {
+	// prepare token request
+	var tokenForm map[string]string
+	if spec.ClientCredentialsGrantSupport {
+		tokenForm = map[string]string{
+			"grant_type": "client_credentials",
+			"scope":      spec.ValidScope,
+		}
+	} else if spec.PasswordGrantSupport {
+		tokenForm = map[string]string{
+			"grant_type": "password",
+			"username":   spec.ResourceOwnerUsername,
+			"password":   spec.ResourceOwnerPassword,
+			"scope":      spec.ValidScope,
+		}
+	}
+
+	// check token responses
+	if tokenForm != nil {
+		var tokens []string
+		for i := 0; i < 2; i++ {
+			Do(spec.Handler, &Request{
+				Method:   "POST",
+				Path:     spec.TokenEndpoint,
+				Username: spec.ConfidentialClientID,
+				Password: spec.ConfidentialClientSecret,
+				Form:     tokenForm,
+				Callback: func(r *httptest.ResponseRecorder, rq *http.Request) {
+					assert.Equal(t, http.StatusOK, r.Code, debug(r))
+					AssertNotCacheable(t, r)
+					tokens = append(tokens, jsonFieldString(r, "access_token"))
+				},
+			})
+		}
+
+		// check tokens
+		assert.NotEmpty(t, tokens[0])
+		assert.NotEqual(t, tokens[0], tokens[1])
+	}
+
+	// check token error response
+	Do(spec.Handler, &Request{
+		Method:   "POST",
+		Path:     spec.TokenEndpoint,
+		Username: spec.ConfidentialClientID,
+		Password: spec.ConfidentialClientSecret,
+		Form: map[string]string{
+			"grant_type": "invalid",
+		},
+		Callback: func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusBadRequest, r.Code, debug(r))
+			AssertNotCacheable(t, r)
+		},
+	})
+
+	// check authorization responses
+	if spec.ImplicitGrantSupport || spec.AuthorizationCodeGrantSupport {
+		responseType := "code"
+		if !spec.AuthorizationCodeGrantSupport {
+			responseType = "token"
+		}
+
+		// check redirect
+		rec := Perform(spec.Handler, NewAuthorizationRequest(spec, responseType, nil))
+		assert.Equal(t, http.StatusSeeOther, rec.Code, debug(rec))
+		AssertNotCacheable(t, rec)
+
+		// check error
+		rec = Perform(spec.Handler, NewAuthorizationRequest(spec, responseType, map[string]string{
+			"redirect_uri": spec.InvalidRedirectURI,
+		}))
+		assert.Equal(t, http.StatusBadRequest, rec.Code, debug(rec))
+		AssertNotCacheable(t, rec)
+	}
+
+	// check introspection response
+	if spec.IntrospectionEndpoint != "" {
+		Do(spec.Handler, &Request{
+			Method:   "POST",
+			Path:     spec.IntrospectionEndpoint,
+			Username: spec.ConfidentialClientID,
+			Password: spec.ConfidentialClientSecret,
+			Form: map[string]string{
+				"token": spec.ValidToken,
+			},
+			Callback: func(r *httptest.ResponseRecorder, rq *http.Request) {
+				assert.Equal(t, http.StatusOK, r.Code, debug(r))
+				AssertNotCacheable(t, r)
+			},
+		})
+	}
+
+	// check revocation response
+	if spec.RevocationEndpoint != "" {
+		Do(spec.Handler, &Request{
+			Method:   "POST",
+			Path:     spec.RevocationEndpoint,
+			Username: spec.ConfidentialClientID,
+			Password: spec.ConfidentialClientSecret,
+			Form: map[string]string{
+				"token": spec.UnknownToken,
+			},
+			Callback: func(r *httptest.ResponseRecorder, rq *http.Request) {
+				assert.Equal(t, http.StatusOK, r.Code, debug(r))
+				AssertNotCacheable(t, r)
+			},
+		})
+	}
+}
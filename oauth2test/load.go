@@ -0,0 +1,180 @@
+package oauth2test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"time"
+)
+
+// LoadRequest describes a kind of request that is sent during a load test.
+type LoadRequest struct {
+	// The name used to report the results.
+	Name string
+
+	// The relative frequency of the request in the mix. Defaults to one.
+	Weight int
+
+	// The function that builds a new request.
+	Build func() *http.Request
+
+	// The expected status. Other statuses are counted as errors. Defaults to
+	// http.StatusOK.
+	Status int
+}
+
+// LoadConfig configures a load test.
+type LoadConfig struct {
+	// The number of concurrent workers. Defaults to eight.
+	Concurrency int
+
+	// The total number of requests. Defaults to 1000.
+	Requests int
+
+	// The mix of requests to send.
+	Mix []LoadRequest
+}
+
+// LoadResult contains the results for a single kind of request.
+type LoadResult struct {
+	Count  int
+	Errors int
+	P50    time.Duration
+	P90    time.Duration
+	P99    time.Duration
+	Max    time.Duration
+}
+
+// LoadReport contains the results of a load test.
+type LoadReport struct {
+	Requests   int
+	Errors     int
+	Duration   time.Duration
+	Throughput float64
+	Results    map[string]LoadResult
+}
+
+// TokenLoadRequest returns a load request for the token endpoint that uses
+// the specified grant type and params (see NewTokenRequest).
+func TokenLoadRequest(spec *Spec, grantType string, params map[string]string, weight int) LoadRequest {
+	return LoadRequest{
+		Name:   grantType,
+		Weight: weight,
+		Build: func() *http.Request {
+			return NewTokenRequest(spec, grantType, params)
+		},
+	}
+}
+
+// Load will send the configured mix of requests concurrently to the handler
+// and report the throughput and latency distribution.
+func Load(handler http.Handler, config LoadConfig) *LoadReport {
+	// set defaults
+	if config.Concurrency <= 0 {
+		config.Concurrency = 8
+	}
+	if config.Requests <= 0 {
+		config.Requests = 1000
+	}
+
+	// prepare schedule
+	var schedule []int
+	for i, req := range config.Mix {
+		weight := req.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+		for j := 0; j < weight; j++ {
+			schedule = append(schedule, i)
+		}
+	}
+	must(len(schedule) > 0, "load test requires a request mix")
+
+	// prepare measurements
+	latencies := map[string][]time.Duration{}
+	errors := map[string]int{}
+	var mutex sync.Mutex
+
+	// prepare jobs
+	jobs := make(chan int, config.Requests)
+	for i := 0; i < config.Requests; i++ {
+		jobs <- schedule[i%len(schedule)]
+	}
+	close(jobs)
+
+	// get start
+	start := time.Now()
+
+	// run workers
+	var wg sync.WaitGroup
+	for i := 0; i < config.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for job := range jobs {
+				// get request
+				req := config.Mix[job]
+				status := req.Status
+				if status == 0 {
+					status = http.StatusOK
+				}
+
+				// perform request
+				rec := httptest.NewRecorder()
+				t0 := time.Now()
+				handler.ServeHTTP(rec, req.Build())
+				latency := time.Since(t0)
+
+				// record measurement
+				mutex.Lock()
+				latencies[req.Name] = append(latencies[req.Name], latency)
+				if rec.Code != status {
+					errors[req.Name]++
+				}
+				mutex.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// prepare report
+	report := &LoadReport{
+		Requests: config.Requests,
+		Duration: time.Since(start),
+		Results:  map[string]LoadResult{},
+	}
+
+	// compute throughput
+	if report.Duration > 0 {
+		report.Throughput = float64(report.Requests) / report.Duration.Seconds()
+	}
+
+	// compute results
+	for name, list := range latencies {
+		// sort latencies
+		sort.Slice(list, func(a, b int) bool {
+			return list[a] < list[b]
+		})
+
+		// add result
+		report.Results[name] = LoadResult{
+			Count:  len(list),
+			Errors: errors[name],
+			P50:    percentile(list, 0.5),
+			P90:    percentile(list, 0.9),
+			P99:    percentile(list, 0.99),
+			Max:    list[len(list)-1],
+		}
+
+		// count errors
+		report.Errors += errors[name]
+	}
+
+	return report
+}
+
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	return sorted[int(float64(len(sorted)-1)*p)]
+}
@@ -0,0 +1,86 @@
+package oauth2test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// PKCETest validates the PKCE extension of the authorization code grant and
+// the configured state policy.
+func PKCETest(t *testing.T, spec *Spec) {
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+
+	// authorize with pkce
+	authorize := func(params map[string]string) *Response {
+		rec := Perform(spec.Handler, NewAuthorizationRequest(spec, "code", params))
+		return ParseResponse(rec)
+	}
+
+	// exchange code
+	exchange := func(code, verifier string) *Response {
+		rec := Perform(spec.Handler, NewTokenRequest(spec, "authorization_code", map[string]string{
+			"scope":         "",
+			"code":          code,
+			"redirect_uri":  spec.PrimaryRedirectURI,
+			"code_verifier": verifier,
+		}))
+		return ParseResponse(rec)
+	}
+
+	// invalid code verifier
+	res := authorize(PKCEParams(verifier))
+	assert.Equal(t, http.StatusSeeOther, res.Status)
+	assert.NotEmpty(t, res.Query["code"])
+	res = exchange(res.Query["code"], "invalid-verifier-invalid-verifier-invalid-verifier")
+	assert.Equal(t, http.StatusBadRequest, res.Status)
+	assert.Equal(t, "invalid_grant", res.String("error"))
+
+	// missing code verifier
+	res = authorize(PKCEParams(verifier))
+	assert.Equal(t, http.StatusSeeOther, res.Status)
+	res = exchange(res.Query["code"], "")
+	assert.Equal(t, http.StatusBadRequest, res.Status)
+	assert.Equal(t, "invalid_grant", res.String("error"))
+
+	// valid code verifier
+	res = authorize(PKCEParams(verifier))
+	assert.Equal(t, http.StatusSeeOther, res.Status)
+	assert.Equal(t, "xyz", res.Query["state"])
+	res = exchange(res.Query["code"], verifier)
+	assert.Equal(t, http.StatusOK, res.Status)
+	assert.NotEmpty(t, res.String("access_token"))
+
+	// unsupported code challenge method
+	res = authorize(extend(PKCEParams(verifier), map[string]string{
+		"code_challenge_method": "foo",
+	}))
+	assert.Equal(t, http.StatusBadRequest, res.Status)
+	assert.Equal(t, "invalid_request", res.String("error"))
+
+	// check state policy
+	if !spec.StateRequired {
+		return
+	}
+
+	// missing state without pkce
+	res = authorize(map[string]string{
+		"state": "",
+	})
+	assert.Equal(t, http.StatusSeeOther, res.Status)
+	assert.Equal(t, "invalid_request", res.Query["error"])
+
+	// missing state with pkce
+	res = authorize(extend(PKCEParams(verifier), map[string]string{
+		"state": "",
+	}))
+	assert.Equal(t, http.StatusSeeOther, res.Status)
+	if spec.StatelessPKCE {
+		assert.NotEmpty(t, res.Query["code"])
+		res = exchange(res.Query["code"], verifier)
+		assert.Equal(t, http.StatusOK, res.Status)
+	} else {
+		assert.Equal(t, "invalid_request", res.Query["error"])
+	}
+}
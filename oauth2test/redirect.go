@@ -0,0 +1,73 @@
+package oauth2test
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// RedirectURIQueryTest validates that the query parameters of a registered
+// redirect URI are preserved when responses are redirected.
+func RedirectURIQueryTest(t *testing.T, spec *Spec) {
+	// parse redirect uri
+	redirectURI, err := url.Parse(spec.QueryRedirectURI)
+	assert.NoError(t, err)
+	original := redirectURI.Query()
+
+	// check location
+	check := func(res *Response) url.Values {
+		location, err := url.Parse(res.Header.Get("Location"))
+		assert.NoError(t, err)
+		assert.Equal(t, redirectURI.Scheme, location.Scheme)
+		assert.Equal(t, redirectURI.Host, location.Host)
+		assert.Equal(t, redirectURI.Path, location.Path)
+		query := location.Query()
+		for key, values := range original {
+			assert.Equal(t, values, query[key])
+		}
+		return query
+	}
+
+	// prepare params
+	params := map[string]string{
+		"client_id":    spec.QueryRedirectClientID,
+		"redirect_uri": spec.QueryRedirectURI,
+	}
+
+	// access denied
+	res := ParseResponse(Perform(spec.Handler, NewAuthorizationRequest(spec, "code", extend(params, spec.InvalidAuthorizationParams))))
+	assert.Equal(t, http.StatusSeeOther, res.Status)
+	query := check(res)
+	assert.Equal(t, "access_denied", query.Get("error"))
+	assert.Equal(t, "xyz", query.Get("state"))
+
+	// authorization code
+	res = ParseResponse(Perform(spec.Handler, NewAuthorizationRequest(spec, "code", params)))
+	assert.Equal(t, http.StatusSeeOther, res.Status)
+	query = check(res)
+	assert.NotEmpty(t, query.Get("code"))
+	assert.Equal(t, "xyz", query.Get("state"))
+
+	// exchange code
+	req := NewTokenRequest(spec, "authorization_code", map[string]string{
+		"scope":        "",
+		"code":         query.Get("code"),
+		"redirect_uri": spec.QueryRedirectURI,
+	})
+	req.SetBasicAuth(spec.QueryRedirectClientID, spec.QueryRedirectClientSecret)
+	res = ParseResponse(Perform(spec.Handler, req))
+	assert.Equal(t, http.StatusOK, res.Status)
+	assert.NotEmpty(t, res.String("access_token"))
+
+	// implicit grant
+	if spec.ImplicitGrantSupport {
+		res = ParseResponse(Perform(spec.Handler, NewAuthorizationRequest(spec, "token", params)))
+		assert.Equal(t, http.StatusSeeOther, res.Status)
+		query = check(res)
+		assert.Empty(t, query.Get("access_token"))
+		assert.NotEmpty(t, res.Fragment["access_token"])
+		assert.Equal(t, "xyz", res.Fragment["state"])
+	}
+}
@@ -2,14 +2,17 @@ package oauth2test
 
 import (
 	"encoding/base64"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
 )
 
 // A Request is a convenience wrapper to specify test requests.
 type Request struct {
 	Method   string
+	Host     string
 	Path     string
 	Header   map[string]string
 	Form     map[string]string
@@ -18,14 +21,99 @@ type Request struct {
 	Callback func(*httptest.ResponseRecorder, *http.Request)
 }
 
-// Do will perform the specified request on the specified handler.
-func Do(handler http.Handler, req *Request) {
+// A Response is a parsed representation of a recorded response.
+type Response struct {
+	// The status, headers and raw body.
+	Status int
+	Header http.Header
+	Body   string
+
+	// The decoded JSON body.
+	JSON map[string]interface{}
+
+	// The query and fragment parameters of the location header.
+	Query    map[string]string
+	Fragment map[string]string
+
+	// The parameters of the authentication challenge.
+	Auth map[string]string
+}
+
+// String returns the string value of the specified JSON field.
+func (r *Response) String(field string) string {
+	str, _ := r.JSON[field].(string)
+	return str
+}
+
+// Float returns the numeric value of the specified JSON field.
+func (r *Response) Float(field string) float64 {
+	num, _ := r.JSON[field].(float64)
+	return num
+}
+
+// Bool returns the boolean value of the specified JSON field.
+func (r *Response) Bool(field string) bool {
+	ok, _ := r.JSON[field].(bool)
+	return ok
+}
+
+// ParseResponse will parse the specified recorded response.
+func ParseResponse(rec *httptest.ResponseRecorder) *Response {
+	// prepare response
+	res := &Response{
+		Status:   rec.Code,
+		Header:   rec.Header(),
+		Body:     rec.Body.String(),
+		JSON:     map[string]interface{}{},
+		Query:    map[string]string{},
+		Fragment: map[string]string{},
+		Auth:     map[string]string{},
+	}
+
+	// decode json
+	_ = json.Unmarshal(rec.Body.Bytes(), &res.JSON)
+
+	// parse location
+	if location, err := url.Parse(rec.Header().Get("Location")); err == nil {
+		for k := range location.Query() {
+			res.Query[k] = location.Query().Get(k)
+		}
+
+		fragment, _ := url.ParseQuery(location.Fragment)
+		for k := range fragment {
+			res.Fragment[k] = fragment.Get(k)
+		}
+	}
+
+	// parse authentication challenge
+	parts := strings.SplitN(rec.Header().Get("WWW-Authenticate"), " ", 2)
+	if len(parts) == 2 {
+		for _, part := range strings.Split(parts[1], ", ") {
+			values := strings.SplitN(part, "=", 2)
+			if len(values) == 2 {
+				res.Auth[values[0]] = strings.Trim(values[1], "\",")
+			}
+		}
+	}
+
+	return res
+}
+
+// Do will perform the specified request on the specified handler and return
+// the parsed response. The callback is optional.
+func Do(handler http.Handler, req *Request) *Response {
 	// create request
 	r, err := http.NewRequest(req.Method, req.Path, nil)
 	if err != nil {
 		panic(err)
 	}
 
+	// set host, defaults to the host used by httptest
+	r.Host = req.Host
+	if r.Host == "" {
+		r.Host = "example.com"
+	}
+
 	// add headers
 	for k, v := range req.Header {
 		r.Header.Set(k, v)
@@ -53,5 +141,9 @@ func Do(handler http.Handler, req *Request) {
 	handler.ServeHTTP(rec, r)
 
 	// call callback
-	req.Callback(rec, r)
+	if req.Callback != nil {
+		req.Callback(rec, r)
+	}
+
+	return ParseResponse(rec)
 }
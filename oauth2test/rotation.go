@@ -0,0 +1,88 @@
+package oauth2test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// RefreshTokenRotationTest validates that refresh tokens are rotated on use
+// and, if enabled, that reusing a rotated refresh token revokes all tokens
+// issued from it. If a grace period is configured, the test waits for it to
+// pass.
+func RefreshTokenRotationTest(t *testing.T, spec *Spec) {
+	// obtain tokens
+	var res *Response
+	if spec.PasswordGrantSupport {
+		res = ParseResponse(Perform(spec.Handler, NewTokenRequest(spec, "password", map[string]string{
+			"username": spec.ResourceOwnerUsername,
+			"password": spec.ResourceOwnerPassword,
+		})))
+	} else {
+		res = ParseResponse(Perform(spec.Handler, NewTokenRequest(spec, "client_credentials", nil)))
+	}
+	assert.Equal(t, http.StatusOK, res.Status)
+	oldRefreshToken := res.String("refresh_token")
+	assert.NotEmpty(t, oldRefreshToken)
+
+	// refresh tokens
+	refresh := func(refreshToken string) *Response {
+		rec := Perform(spec.Handler, NewTokenRequest(spec, "refresh_token", map[string]string{
+			"scope":         "",
+			"refresh_token": refreshToken,
+		}))
+		return ParseResponse(rec)
+	}
+
+	// new refresh token is issued
+	res = refresh(oldRefreshToken)
+	assert.Equal(t, http.StatusOK, res.Status)
+	newAccessToken := res.String("access_token")
+	newRefreshToken := res.String("refresh_token")
+	assert.NotEmpty(t, newRefreshToken)
+	assert.NotEqual(t, oldRefreshToken, newRefreshToken)
+
+	// old refresh token remains usable during grace period
+	if spec.RefreshTokenGracePeriod > 0 {
+		res = refresh(oldRefreshToken)
+		assert.Equal(t, http.StatusOK, res.Status)
+		assert.NotEmpty(t, res.String("refresh_token"))
+		assert.NotEqual(t, newRefreshToken, res.String("refresh_token"))
+
+		// await end of grace period
+		time.Sleep(spec.RefreshTokenGracePeriod)
+	}
+
+	// old refresh token stops working
+	res = refresh(oldRefreshToken)
+	assert.Equal(t, http.StatusBadRequest, res.Status)
+	assert.Equal(t, "invalid_grant", res.String("error"))
+
+	// check if reuse detection is enabled
+	if !spec.RefreshTokenReuseDetection {
+		res = refresh(newRefreshToken)
+		assert.Equal(t, http.StatusOK, res.Status)
+		return
+	}
+
+	// new refresh token has been revoked
+	res = refresh(newRefreshToken)
+	assert.Equal(t, http.StatusBadRequest, res.Status)
+	assert.Equal(t, "invalid_grant", res.String("error"))
+
+	// new access token has been revoked
+	Do(spec.Handler, &Request{
+		Method: "GET",
+		Path:   spec.ProtectedResource,
+		Header: map[string]string{
+			"Authorization": "Bearer " + newAccessToken,
+		},
+		Callback: func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusUnauthorized, r.Code, debug(r))
+			assert.Equal(t, "invalid_token", auth(r, "error"), debug(r))
+		},
+	})
+}
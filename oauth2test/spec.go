@@ -5,6 +5,7 @@ package oauth2test
 import (
 	"net/http"
 	"testing"
+	"time"
 )
 
 // Spec declares the needed info for testing an OAuth2 authentication server.
@@ -102,6 +103,38 @@ type Spec struct {
 	// If enabled the implementation is checked for properly revoking tokens
 	// if a code replay attack is carried out.
 	CodeReplayMitigation bool
+
+	// If enabled the authorization code grant is tested with PKCE.
+	//
+	// Note: Only needed if the authorization code grant is supported.
+	PKCESupport bool
+
+	// If enabled authorization requests without a state are expected to be
+	// rejected. If StatelessPKCE is enabled as well, requests that use PKCE
+	// are expected to be accepted without a state.
+	StateRequired bool
+	StatelessPKCE bool
+
+	// If enabled refresh tokens are expected to be rotated on use. If reuse
+	// detection is enabled as well, reusing a rotated refresh token is
+	// expected to revoke all tokens issued from it. If a grace period is set,
+	// a rotated refresh token is expected to remain usable during the period
+	// and to revoke all tokens issued from it when reused afterwards.
+	//
+	// Note: Only needed if the refresh token grant and the password or client
+	// credentials grant are supported.
+	RefreshTokenRotation       bool
+	RefreshTokenReuseDetection bool
+	RefreshTokenGracePeriod    time.Duration
+
+	// The details of a confidential client whose registered redirect URI
+	// includes query parameters (e.g. https://example.com/callback?env=prod).
+	//
+	// Note: Only needed to test the preservation of query parameters if the
+	// authorization code grant is supported.
+	QueryRedirectClientID     string
+	QueryRedirectClientSecret string
+	QueryRedirectURI          string
 }
 
 // Default returns a common used spec that can be taken as a basis.
@@ -193,6 +226,21 @@ func Run(t *testing.T, spec *Spec) {
 		t.Run("AuthorizationCodeGrantTest", func(t *testing.T) {
 			AuthorizationCodeGrantTest(t, spec)
 		})
+
+		if spec.PKCESupport {
+			t.Run("PKCETest", func(t *testing.T) {
+				PKCETest(t, spec)
+			})
+		}
+
+		if spec.QueryRedirectURI != "" {
+			must(spec.QueryRedirectClientID != "", "setting QueryRedirectClientID is required")
+			must(spec.QueryRedirectClientSecret != "", "setting QueryRedirectClientSecret is required")
+
+			t.Run("RedirectURIQueryTest", func(t *testing.T) {
+				RedirectURIQueryTest(t, spec)
+			})
+		}
 	}
 
 	if spec.RefreshTokenGrantSupport {
@@ -204,6 +252,15 @@ func Run(t *testing.T, spec *Spec) {
 		t.Run("RefreshTokenGrantTest", func(t *testing.T) {
 			RefreshTokenGrantTest(t, spec)
 		})
+
+		if spec.RefreshTokenRotation {
+			must(spec.PasswordGrantSupport || spec.ClientCredentialsGrantSupport,
+				"password or client credentials grant support is required for rotation tests")
+
+			t.Run("RefreshTokenRotationTest", func(t *testing.T) {
+				RefreshTokenRotationTest(t, spec)
+			})
+		}
 	}
 
 	if spec.IntrospectionEndpoint != "" {
@@ -217,4 +274,8 @@ func Run(t *testing.T, spec *Spec) {
 			RevocationEndpointTest(t, spec)
 		})
 	}
+
+	t.Run("CachingTest", func(t *testing.T) {
+		CachingTest(t, spec)
+	})
 }
@@ -0,0 +1,72 @@
+package oauth2
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+)
+
+// The known PKCE code challenge methods.
+const (
+	PlainCodeChallengeMethod = "plain"
+	S256CodeChallengeMethod  = "S256"
+)
+
+// KnownCodeChallengeMethod returns true if the code challenge method is a known
+// method (e.g. plain or S256).
+func KnownCodeChallengeMethod(str string) bool {
+	switch str {
+	case PlainCodeChallengeMethod, S256CodeChallengeMethod:
+		return true
+	}
+
+	return false
+}
+
+// ValidCodeVerifier returns whether the specified string is a valid code
+// verifier or code challenge as defined by RFC 7636.
+func ValidCodeVerifier(str string) bool {
+	// check length
+	if len(str) < 43 || len(str) > 128 {
+		return false
+	}
+
+	// check characters
+	for i := 0; i < len(str); i++ {
+		c := str[i]
+		if !(c >= 'A' && c <= 'Z') && !(c >= 'a' && c <= 'z') && !(c >= '0' && c <= '9') &&
+			c != '-' && c != '.' && c != '_' && c != '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// S256CodeChallenge returns the S256 code challenge for the specified
+// verifier.
+func S256CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return b64.EncodeToString(sum[:])
+}
+
+// VerifyCodeChallenge returns whether the specified verifier matches the code
+// challenge using the specified method.
+func VerifyCodeChallenge(challenge, method, verifier string) bool {
+	// check verifier
+	if !ValidCodeVerifier(verifier) {
+		return false
+	}
+
+	// compute challenge
+	var computed string
+	switch method {
+	case PlainCodeChallengeMethod, "":
+		computed = verifier
+	case S256CodeChallengeMethod:
+		computed = S256CodeChallenge(verifier)
+	default:
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(challenge)) == 1
+}
@@ -0,0 +1,36 @@
+package oauth2
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKnownCodeChallengeMethod(t *testing.T) {
+	assert.True(t, KnownCodeChallengeMethod("plain"))
+	assert.True(t, KnownCodeChallengeMethod("S256"))
+	assert.False(t, KnownCodeChallengeMethod("s256"))
+	assert.False(t, KnownCodeChallengeMethod(""))
+}
+
+func TestValidCodeVerifier(t *testing.T) {
+	assert.True(t, ValidCodeVerifier("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"))
+	assert.True(t, ValidCodeVerifier(strings.Repeat("a", 128)))
+	assert.False(t, ValidCodeVerifier(strings.Repeat("a", 42)))
+	assert.False(t, ValidCodeVerifier(strings.Repeat("a", 129)))
+	assert.False(t, ValidCodeVerifier(strings.Repeat("a", 42)+"+"))
+}
+
+func TestVerifyCodeChallenge(t *testing.T) {
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	challenge := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+
+	assert.Equal(t, challenge, S256CodeChallenge(verifier))
+	assert.True(t, VerifyCodeChallenge(challenge, "S256", verifier))
+	assert.True(t, VerifyCodeChallenge(verifier, "plain", verifier))
+	assert.True(t, VerifyCodeChallenge(verifier, "", verifier))
+	assert.False(t, VerifyCodeChallenge(challenge, "plain", verifier))
+	assert.False(t, VerifyCodeChallenge(challenge, "foo", verifier))
+	assert.False(t, VerifyCodeChallenge(challenge, "S256", "foo"))
+}
@@ -0,0 +1,31 @@
+package oauth2
+
+import "context"
+
+// PolicyInput is the input that is evaluated by an authorization policy.
+type PolicyInput struct {
+	// The claims of the presented access token.
+	Claims Claims
+
+	// The method and path of the request.
+	Method string
+	Path   string
+
+	// The scope required by the protected resource.
+	Scope Scope
+}
+
+// AuthorizationPolicy is evaluated when a request to a protected resource is
+// authorized. It can be used to integrate policy engines like OPA.
+type AuthorizationPolicy interface {
+	Evaluate(ctx context.Context, input PolicyInput) (bool, error)
+}
+
+// AuthorizationPolicyFunc is a function that implements the AuthorizationPolicy
+// interface.
+type AuthorizationPolicyFunc func(ctx context.Context, input PolicyInput) (bool, error)
+
+// Evaluate implements the AuthorizationPolicy interface.
+func (f AuthorizationPolicyFunc) Evaluate(ctx context.Context, input PolicyInput) (bool, error) {
+	return f(ctx, input)
+}
@@ -0,0 +1,65 @@
+package oauth2
+
+// TestProvider is a fully configured server with one confidential client, one
+// public client and one resource owner that can be used in test suites.
+type TestProvider struct {
+	*Server
+
+	// The credentials of the confidential client.
+	ConfidentialClientID     string
+	ConfidentialClientSecret string
+
+	// The ID of the public client.
+	PublicClientID string
+
+	// The redirect URI registered for both clients.
+	RedirectURI string
+
+	// The credentials of the resource owner.
+	Username string
+	Password string
+
+	// The scope that may be requested.
+	Scope Scope
+}
+
+// NewTestProvider creates and returns a new test provider. The server uses a
+// random secret and the default configuration.
+func NewTestProvider() *TestProvider {
+	// prepare provider
+	p := &TestProvider{
+		ConfidentialClientID:     "confidential",
+		ConfidentialClientSecret: "confidential-secret",
+		PublicClientID:           "public",
+		RedirectURI:              "https://client.example.com/callback",
+		Username:                 "user",
+		Password:                 "password",
+		Scope:                    Scope{"read", "write"},
+	}
+
+	// generate secret
+	secret, err := generateKey(32)
+	if err != nil {
+		panic(err)
+	}
+
+	// create server
+	p.Server = NewServer(DefaultServerConfig(secret, p.Scope))
+
+	// add clients
+	p.Clients[p.ConfidentialClientID] = &ServerEntity{
+		Secret:       p.ConfidentialClientSecret,
+		RedirectURI:  p.RedirectURI,
+		Confidential: true,
+	}
+	p.Clients[p.PublicClientID] = &ServerEntity{
+		RedirectURI: p.RedirectURI,
+	}
+
+	// add user
+	p.Users[p.Username] = &ServerEntity{
+		Secret: p.Password,
+	}
+
+	return p
+}
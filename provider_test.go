@@ -0,0 +1,45 @@
+package oauth2
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/256dpi/oauth2/v2/oauth2test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewTestProvider(t *testing.T) {
+	provider := NewTestProvider()
+	assert.NoError(t, provider.SelfCheck())
+
+	res := oauth2test.Do(provider, &oauth2test.Request{
+		Method:   "POST",
+		Path:     "/oauth2/token",
+		Username: provider.ConfidentialClientID,
+		Password: provider.ConfidentialClientSecret,
+		Form: map[string]string{
+			"grant_type": ClientCredentialsGrantType,
+			"scope":      provider.Scope.String(),
+		},
+	})
+	assert.Equal(t, http.StatusOK, res.Status)
+	assert.NotEmpty(t, res.String("access_token"))
+
+	res = oauth2test.Do(provider, &oauth2test.Request{
+		Method: "POST",
+		Path:   "/oauth2/token",
+		Form: map[string]string{
+			"grant_type": PasswordGrantType,
+			"client_id":  provider.PublicClientID,
+			"username":   provider.Username,
+			"password":   provider.Password,
+			"scope":      "read",
+		},
+	})
+	assert.Equal(t, http.StatusOK, res.Status)
+	assert.NotEmpty(t, res.String("access_token"))
+	assert.NotEmpty(t, res.String("refresh_token"))
+
+	other := NewTestProvider()
+	assert.NotEqual(t, provider.Config.Secret, other.Config.Secret)
+}
@@ -0,0 +1,55 @@
+package oauth2
+
+import (
+	"regexp"
+	"strings"
+)
+
+// tokenPattern matches strings that look like tokens or codes generated by the
+// token codecs (dot separated base64url segments) as well as long opaque
+// base64url strings.
+var tokenPattern = regexp.MustCompile(`[A-Za-z0-9_-]{16,}(?:\.[A-Za-z0-9_-]{16,})+|[A-Za-z0-9_-]{32,}`)
+
+// A Redactor masks credentials like tokens, authorization codes and secrets in
+// strings before they are presented to clients or written to logs.
+type Redactor struct {
+	// Additional values that are always masked, e.g. client secrets.
+	Secrets []string
+
+	// The replacement for masked values. Defaults to "[redacted]".
+	Mask string
+}
+
+// DefaultRedactor is used to redact the descriptions of errors written by the
+// server and the reason of server events if no other redactor is configured.
+var DefaultRedactor = &Redactor{}
+
+// Redact returns the provided string with all recognized tokens, codes and
+// configured secrets masked.
+func (r *Redactor) Redact(str string) string {
+	// get mask
+	mask := r.mask()
+
+	// mask secrets
+	if r != nil {
+		for _, secret := range r.Secrets {
+			if secret != "" {
+				str = strings.Replace(str, secret, mask, -1)
+			}
+		}
+	}
+
+	// mask tokens
+	str = tokenPattern.ReplaceAllString(str, mask)
+
+	return str
+}
+
+func (r *Redactor) mask() string {
+	// check mask
+	if r != nil && r.Mask != "" {
+		return r.Mask
+	}
+
+	return "[redacted]"
+}
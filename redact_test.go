@@ -0,0 +1,83 @@
+package oauth2
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactor(t *testing.T) {
+	token := MustGenerateHS256Token(testSecret, 16).String()
+
+	assert.Equal(t, "foo", DefaultRedactor.Redact("foo"))
+	assert.Equal(t, "unknown token [redacted]", DefaultRedactor.Redact("unknown token "+token))
+	assert.Equal(t, "code [redacted]", DefaultRedactor.Redact("code Zm9vYmFyYmF6cXV4Zm9vYmFyYmF6cXV4Zm9vYmFy"))
+
+	redactor := &Redactor{Secrets: []string{"s3cret"}, Mask: "***"}
+	assert.Equal(t, "secret *** and ***", redactor.Redact("secret s3cret and "+token))
+
+	var nilRedactor *Redactor
+	assert.Equal(t, "token [redacted]", nilRedactor.Redact("token "+token))
+}
+
+func TestErrorRedaction(t *testing.T) {
+	token := MustGenerateHS256Token(testSecret, 16).String()
+	err := InvalidGrant("unknown token " + token)
+
+	assert.Equal(t, "invalid_grant: unknown token "+token, err.Error())
+	assert.Equal(t, "unknown token "+token, err.Map()["error_description"])
+	assert.Equal(t, "unknown token [redacted]", err.Redact(DefaultRedactor).Description)
+
+	data, _ := json.Marshal(err)
+	assert.JSONEq(t, `{
+		"error": "invalid_grant",
+		"error_description": "unknown token `+token+`"
+	}`, string(data))
+
+	server := newTestServer()
+
+	rec := httptest.NewRecorder()
+	assert.NoError(t, server.writeError(rec, err))
+	assert.NotContains(t, rec.Body.String(), token)
+	assert.Contains(t, rec.Body.String(), "unknown token [redacted]")
+}
+
+func TestServerEventRedaction(t *testing.T) {
+	var events []ServerEvent
+	server := newTestServer()
+	server.Config.Redactor = &Redactor{Secrets: []string{"foo"}}
+	server.Config.EventHandler = ServerEventHandlerFunc(func(event ServerEvent) {
+		events = append(events, event)
+	})
+
+	server.emit(ServerEvent{Type: AuthenticationFailedEvent, Reason: "invalid secret foo"})
+	assert.Len(t, events, 1)
+	assert.Equal(t, "invalid secret [redacted]", events[0].Reason)
+}
+
+func TestServerErrorRedaction(t *testing.T) {
+	server := newTestServer()
+	server.Config.Redactor = &Redactor{Secrets: []string{"s3cret"}, Mask: "***"}
+
+	rec := httptest.NewRecorder()
+	assert.NoError(t, server.writeError(rec, InvalidGrant("invalid secret s3cret")))
+	assert.JSONEq(t, `{
+		"error": "invalid_grant",
+		"error_description": "invalid secret ***"
+	}`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	assert.NoError(t, server.writeBearerError(rec, InvalidToken("invalid secret s3cret")))
+	assert.Contains(t, rec.Header().Get("WWW-Authenticate"), `error_description="invalid secret ***"`)
+
+	ctx := server.requestContext(&AuthorizationRequest{
+		RedirectURI: "http://example.com/callback1",
+		State:       "xyz",
+	})
+
+	rec = httptest.NewRecorder()
+	assert.NoError(t, ctx.WriteError(rec, AccessDenied("invalid secret s3cret")))
+	assert.Contains(t, rec.Header().Get("Location"), "error_description=invalid+secret+%2A%2A%2A")
+}
@@ -0,0 +1,344 @@
+package oauth2
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// NormalizeRedirectURI will return the normalized form of the specified
+// redirect URI. The scheme and host are lowercased, internationalized host
+// names are converted to punycode, IPv6 literals are brought into their
+// canonical form, default ports are removed and an empty path is replaced with
+// a slash. The path and query are kept as is.
+func NormalizeRedirectURI(str string) (string, error) {
+	// parse uri
+	uri, err := url.Parse(str)
+	if err != nil {
+		return "", err
+	}
+
+	// check uri
+	if uri.Scheme == "" || uri.Host == "" || uri.Opaque != "" {
+		return "", errors.New("redirect URI is not absolute")
+	} else if uri.Fragment != "" {
+		return "", errors.New("redirect URI contains a fragment")
+	}
+
+	// normalize scheme
+	uri.Scheme = strings.ToLower(uri.Scheme)
+
+	// normalize host
+	host, err := normalizeHost(uri.Hostname())
+	if err != nil {
+		return "", err
+	}
+
+	// remove default ports
+	port := uri.Port()
+	if (uri.Scheme == "http" && port == "80") || (uri.Scheme == "https" && port == "443") {
+		port = ""
+	}
+
+	// set host
+	if port != "" {
+		uri.Host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		uri.Host = "[" + host + "]"
+	} else {
+		uri.Host = host
+	}
+
+	// set default path
+	if uri.Path == "" && uri.RawPath == "" {
+		uri.Path = "/"
+	}
+
+	return uri.String(), nil
+}
+
+// ValidateRedirectURI validates a redirect URI that is registered for a client
+// according to current best practices. The URI must be absolute and must not
+// contain a fragment or wildcards. Web clients must use the https scheme while
+// native apps may also use the http scheme with a loopback IP literal (RFC
+// 8252 section 7.3) or a private-use scheme in reverse domain name notation
+// (RFC 8252 section 7.1).
+func ValidateRedirectURI(str string) error {
+	// parse uri
+	uri, err := url.Parse(str)
+	if err != nil {
+		return err
+	}
+
+	// check uri
+	if uri.Scheme == "" || uri.Opaque != "" {
+		return errors.New("redirect URI is not absolute")
+	} else if uri.Fragment != "" || strings.HasSuffix(str, "#") {
+		return errors.New("redirect URI contains a fragment")
+	} else if strings.Contains(str, "*") {
+		return errors.New("redirect URI contains a wildcard")
+	}
+
+	// check scheme
+	switch scheme := strings.ToLower(uri.Scheme); {
+	case scheme == "https":
+		_, err = NormalizeRedirectURI(str)
+		return err
+	case scheme == "http":
+		if _, ok := loopbackRedirectURI(str); !ok {
+			return errors.New("redirect URI must use https unless it targets a loopback IP")
+		}
+		return nil
+	case strings.Contains(scheme, "."):
+		return nil
+	}
+
+	return errors.New("redirect URI has an unsupported scheme")
+}
+
+// MatchRedirectURI returns whether the requested redirect URI is equivalent to
+// the registered redirect URI once both have been normalized. The port of
+// loopback redirect URIs (http scheme with a loopback IP literal) may vary as
+// allowed by RFC 8252 section 7.3.
+func MatchRedirectURI(registered, requested string) bool {
+	// check exact match
+	if registered == requested {
+		return true
+	}
+
+	// normalize registered uri
+	registered, err := NormalizeRedirectURI(registered)
+	if err != nil {
+		return false
+	}
+
+	// normalize requested uri
+	requested, err = NormalizeRedirectURI(requested)
+	if err != nil {
+		return false
+	}
+
+	// check normalized match
+	if registered == requested {
+		return true
+	}
+
+	// check loopback match
+	registered, ok1 := loopbackRedirectURI(registered)
+	requested, ok2 := loopbackRedirectURI(requested)
+
+	return ok1 && ok2 && registered == requested
+}
+
+// RedirectParameters lists the parameters that may be added to the redirect
+// URI when responding to an authorization request (RFC 6749 sections 4.1.2,
+// 4.1.2.1 and 4.2.2, RFC 9207 and RFC 9396).
+var RedirectParameters = []string{
+	"code",
+	"state",
+	"iss",
+	"error",
+	"error_description",
+	"error_uri",
+	"access_token",
+	"token_type",
+	"expires_in",
+	"scope",
+	"authorization_details",
+}
+
+// FilterRedirectParameters returns a copy of the specified parameters that
+// only contains the parameters listed in RedirectParameters. It prevents
+// arbitrary values from being reflected to the client.
+func FilterRedirectParameters(params map[string]string) map[string]string {
+	// copy allowed parameters
+	filtered := make(map[string]string, len(params))
+	for key, value := range params {
+		if containsString(RedirectParameters, key) {
+			filtered[key] = value
+		}
+	}
+
+	return filtered
+}
+
+func plaintextRedirectURI(str string) bool {
+	// parse uri
+	uri, err := url.Parse(str)
+	if err != nil || strings.ToLower(uri.Scheme) != "http" {
+		return false
+	}
+
+	// check loopback
+	_, ok := loopbackRedirectURI(str)
+
+	return !ok
+}
+
+func loopbackRedirectURI(str string) (string, bool) {
+	// parse uri
+	uri, err := url.Parse(str)
+	if err != nil || strings.ToLower(uri.Scheme) != "http" {
+		return "", false
+	}
+
+	// check host
+	ip := net.ParseIP(uri.Hostname())
+	if ip == nil || !ip.IsLoopback() {
+		return "", false
+	}
+
+	// remove port
+	if strings.Contains(uri.Hostname(), ":") {
+		uri.Host = "[" + uri.Hostname() + "]"
+	} else {
+		uri.Host = uri.Hostname()
+	}
+
+	return uri.String(), true
+}
+
+func normalizeHost(host string) (string, error) {
+	// check host
+	if host == "" {
+		return "", errors.New("redirect URI is missing a host")
+	}
+
+	// handle ip addresses
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.String(), nil
+	}
+
+	// lowercase host
+	host = strings.ToLower(host)
+
+	// convert labels
+	labels := strings.Split(host, ".")
+	for i, label := range labels {
+		if !isASCII(label) {
+			labels[i] = "xn--" + punycodeEncode(label)
+		}
+	}
+
+	return strings.Join(labels, "."), nil
+}
+
+func isASCII(str string) bool {
+	for i := 0; i < len(str); i++ {
+		if str[i] >= 0x80 {
+			return false
+		}
+	}
+
+	return true
+}
+
+const (
+	punycodeBase        = 36
+	punycodeTMin        = 1
+	punycodeTMax        = 26
+	punycodeSkew        = 38
+	punycodeDamp        = 700
+	punycodeInitialBias = 72
+	punycodeInitialN    = 128
+)
+
+// punycodeEncode encodes the specified label as defined by RFC 3492.
+func punycodeEncode(label string) string {
+	// get runes
+	runes := []rune(label)
+
+	// copy basic code points
+	var out []byte
+	for _, r := range runes {
+		if r < 0x80 {
+			out = append(out, byte(r))
+		}
+	}
+
+	// add delimiter
+	b := len(out)
+	h := b
+	if b > 0 {
+		out = append(out, '-')
+	}
+
+	// encode remaining code points
+	n := rune(punycodeInitialN)
+	delta := 0
+	bias := punycodeInitialBias
+	for h < len(runes) {
+		// find next smallest code point
+		m := rune(0x7fffffff)
+		for _, r := range runes {
+			if r >= n && r < m {
+				m = r
+			}
+		}
+
+		// advance state
+		delta += int(m-n) * (h + 1)
+		n = m
+
+		// encode code points
+		for _, r := range runes {
+			if r < n {
+				delta++
+			}
+
+			if r == n {
+				q := delta
+				for k := punycodeBase; ; k += punycodeBase {
+					t := k - bias
+					if t < punycodeTMin {
+						t = punycodeTMin
+					} else if t > punycodeTMax {
+						t = punycodeTMax
+					}
+					if q < t {
+						break
+					}
+					out = append(out, punycodeDigit(t+(q-t)%(punycodeBase-t)))
+					q = (q - t) / (punycodeBase - t)
+				}
+				out = append(out, punycodeDigit(q))
+				bias = punycodeAdapt(delta, h+1, h == b)
+				delta = 0
+				h++
+			}
+		}
+
+		delta++
+		n++
+	}
+
+	return string(out)
+}
+
+func punycodeAdapt(delta, points int, first bool) int {
+	// scale delta
+	if first {
+		delta /= punycodeDamp
+	} else {
+		delta /= 2
+	}
+	delta += delta / points
+
+	// compute bias
+	k := 0
+	for delta > ((punycodeBase-punycodeTMin)*punycodeTMax)/2 {
+		delta /= punycodeBase - punycodeTMin
+		k += punycodeBase
+	}
+
+	return k + (punycodeBase-punycodeTMin+1)*delta/(delta+punycodeSkew)
+}
+
+func punycodeDigit(d int) byte {
+	if d < 26 {
+		return byte('a' + d)
+	}
+
+	return byte('0' + d - 26)
+}
@@ -0,0 +1,103 @@
+package oauth2
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeRedirectURI(t *testing.T) {
+	for _, item := range []struct {
+		in  string
+		out string
+	}{
+		{"http://example.com/callback", "http://example.com/callback"},
+		{"HTTP://Example.COM/callback", "http://example.com/callback"},
+		{"http://example.com:80/callback", "http://example.com/callback"},
+		{"https://example.com:443/callback", "https://example.com/callback"},
+		{"https://example.com:8443/callback", "https://example.com:8443/callback"},
+		{"http://example.com:443/callback", "http://example.com:443/callback"},
+		{"http://example.com", "http://example.com/"},
+		{"http://example.com/callback?foo=bar", "http://example.com/callback?foo=bar"},
+		{"http://[::1]/callback", "http://[::1]/callback"},
+		{"http://[0:0:0:0:0:0:0:1]:8080/callback", "http://[::1]:8080/callback"},
+		{"http://[2001:DB8::1]:80/callback", "http://[2001:db8::1]/callback"},
+		{"http://127.0.0.1:3000/callback", "http://127.0.0.1:3000/callback"},
+		{"https://münchen.de/callback", "https://xn--mnchen-3ya.de/callback"},
+		{"https://MÜNCHEN.de/callback", "https://xn--mnchen-3ya.de/callback"},
+		{"https://b%C3%BCcher.example/callback", "https://xn--bcher-kva.example/callback"},
+		{"https://xn--mnchen-3ya.de/callback", "https://xn--mnchen-3ya.de/callback"},
+		{"com.example.app://callback", "com.example.app://callback/"},
+	} {
+		out, err := NormalizeRedirectURI(item.in)
+		assert.NoError(t, err, item.in)
+		assert.Equal(t, item.out, out, item.in)
+	}
+
+	for _, str := range []string{
+		"",
+		"/callback",
+		"example.com/callback",
+		"http://example.com/callback#foo",
+		"mailto:foo@example.com",
+		"http://[::1/callback",
+	} {
+		_, err := NormalizeRedirectURI(str)
+		assert.Error(t, err, str)
+	}
+}
+
+func TestMatchRedirectURI(t *testing.T) {
+	assert.True(t, MatchRedirectURI("http://example.com/callback", "http://example.com/callback"))
+	assert.True(t, MatchRedirectURI("http://example.com/callback", "http://EXAMPLE.com:80/callback"))
+	assert.True(t, MatchRedirectURI("http://[::1]:8080/callback", "http://[0::1]:8080/callback"))
+	assert.True(t, MatchRedirectURI("https://xn--mnchen-3ya.de/callback", "https://münchen.de/callback"))
+	assert.False(t, MatchRedirectURI("http://example.com/callback", "http://example.com/Callback"))
+	assert.False(t, MatchRedirectURI("http://example.com/callback", "https://example.com/callback"))
+	assert.False(t, MatchRedirectURI("http://example.com/callback", "http://example.com:8080/callback"))
+	assert.False(t, MatchRedirectURI("http://example.com/callback", "http://example.com/callback?foo=bar"))
+	assert.False(t, MatchRedirectURI("http://example.com/callback", "invalid"))
+
+	assert.True(t, MatchRedirectURI("http://127.0.0.1/callback", "http://127.0.0.1:51004/callback"))
+	assert.True(t, MatchRedirectURI("http://127.0.0.1:8080/callback", "http://127.0.0.1:51004/callback"))
+	assert.True(t, MatchRedirectURI("http://[::1]/callback", "http://[::1]:51004/callback"))
+	assert.False(t, MatchRedirectURI("http://127.0.0.1/callback", "http://127.0.0.1:51004/other"))
+	assert.False(t, MatchRedirectURI("http://127.0.0.1/callback", "https://127.0.0.1:51004/callback"))
+	assert.False(t, MatchRedirectURI("http://localhost/callback", "http://localhost:51004/callback"))
+	assert.False(t, MatchRedirectURI("http://127.0.0.1/callback", "http://[::1]:51004/callback"))
+}
+
+func TestValidateRedirectURI(t *testing.T) {
+	for _, str := range []string{
+		"https://example.com/callback",
+		"https://example.com/callback?foo=bar",
+		"http://127.0.0.1/callback",
+		"http://127.0.0.1:8080/callback",
+		"http://[::1]/callback",
+		"com.example.app:/callback",
+	} {
+		assert.NoError(t, ValidateRedirectURI(str), str)
+	}
+
+	for str, msg := range map[string]string{
+		"/callback":                      "redirect URI is not absolute",
+		"https:example.com":              "redirect URI is not absolute",
+		"https://example.com/callback#":  "redirect URI contains a fragment",
+		"https://example.com/#foo":       "redirect URI contains a fragment",
+		"https://*.example.com/callback": "redirect URI contains a wildcard",
+		"https://example.com/*":          "redirect URI contains a wildcard",
+		"http://example.com/callback":    "redirect URI must use https unless it targets a loopback IP",
+		"http://localhost/callback":      "redirect URI must use https unless it targets a loopback IP",
+		"myapp:/callback":                "redirect URI has an unsupported scheme",
+		"https:///callback":              "redirect URI is not absolute",
+	} {
+		assert.EqualError(t, ValidateRedirectURI(str), msg)
+	}
+}
+
+func TestPunycodeEncode(t *testing.T) {
+	assert.Equal(t, "mnchen-3ya", punycodeEncode("münchen"))
+	assert.Equal(t, "bcher-kva", punycodeEncode("bücher"))
+	assert.Equal(t, "wgv71a119e", punycodeEncode("日本語"))
+	assert.Equal(t, "ihqwcrb4cv8a8dqg056pqjye", punycodeEncode("他们为什么不说中文"))
+}
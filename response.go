@@ -0,0 +1,108 @@
+package oauth2
+
+import (
+	"bytes"
+	"net/http"
+)
+
+// A ResponseSink receives the responses written by WriteToSink,
+// WriteRedirectToSink, WriteErrorToSink and the other functions with the
+// "ToSink" suffix. Transports that are not based on net/http (e.g. fasthttp or
+// serverless functions) can implement it to reuse the writing functions.
+type ResponseSink interface {
+	// Header returns the headers that are sent with the response.
+	Header() http.Header
+
+	// WriteResponse sends the headers, status and body.
+	WriteResponse(status int, body []byte) error
+}
+
+// HTTPSink adapts an http.ResponseWriter to the ResponseSink interface.
+type HTTPSink struct {
+	http.ResponseWriter
+}
+
+// WriteResponse implements the ResponseSink interface.
+func (s HTTPSink) WriteResponse(status int, body []byte) error {
+	// write status
+	s.WriteHeader(status)
+
+	// write body
+	_, err := s.Write(body)
+
+	return err
+}
+
+// A ResponseBuffer implements the http.ResponseWriter and ResponseSink
+// interfaces and buffers the written response. While a ResponseSink only
+// covers the writing functions, the buffer can also be passed to handlers like
+// the Server that expect an http.ResponseWriter. The buffered response can then
+// be converted by the transport or be replayed to an http.ResponseWriter.
+type ResponseBuffer struct {
+	Status  int
+	Headers http.Header
+	Body    bytes.Buffer
+}
+
+// NewResponseBuffer creates and returns a new response buffer.
+func NewResponseBuffer() *ResponseBuffer {
+	return &ResponseBuffer{
+		Headers: make(http.Header),
+	}
+}
+
+// Header implements the http.ResponseWriter interface.
+func (b *ResponseBuffer) Header() http.Header {
+	return b.Headers
+}
+
+// WriteHeader implements the http.ResponseWriter interface.
+func (b *ResponseBuffer) WriteHeader(status int) {
+	// keep first status
+	if b.Status == 0 {
+		b.Status = status
+	}
+}
+
+// Write implements the http.ResponseWriter interface.
+func (b *ResponseBuffer) Write(data []byte) (int, error) {
+	// set implicit status
+	if b.Status == 0 {
+		b.Status = http.StatusOK
+	}
+
+	return b.Body.Write(data)
+}
+
+// WriteResponse implements the ResponseSink interface.
+func (b *ResponseBuffer) WriteResponse(status int, body []byte) error {
+	// write status
+	b.WriteHeader(status)
+
+	// write body
+	_, err := b.Write(body)
+
+	return err
+}
+
+// Replay will replay the buffered response to the provided response writer.
+func (b *ResponseBuffer) Replay(w http.ResponseWriter) error {
+	// copy headers
+	for k, v := range b.Headers {
+		w.Header()[k] = v
+	}
+
+	// get status
+	status := b.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	// write status
+	w.WriteHeader(status)
+
+	// write body
+	_, err := w.Write(b.Body.Bytes())
+
+	return err
+}
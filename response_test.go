@@ -0,0 +1,90 @@
+package oauth2
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResponseBuffer(t *testing.T) {
+	buf := NewResponseBuffer()
+
+	err := WriteError(buf, InvalidRequest("foo"))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, buf.Status)
+	assert.Equal(t, "no-store", buf.Header().Get("Cache-Control"))
+	assert.JSONEq(t, `{
+		"error": "invalid_request",
+		"error_description": "foo"
+	}`, buf.Body.String())
+
+	rec := httptest.NewRecorder()
+
+	err = buf.Replay(rec)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, buf.Header(), rec.Header())
+	assert.Equal(t, buf.Body.String(), rec.Body.String())
+}
+
+func TestResponseBufferRedirect(t *testing.T) {
+	buf := NewResponseBuffer()
+
+	err := WriteRedirect(buf, "http://example.com", map[string]string{
+		"foo": "bar",
+	}, false)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusSeeOther, buf.Status)
+	assert.Equal(t, "http://example.com?foo=bar", buf.Header().Get("Location"))
+	assert.Equal(t, "", buf.Body.String())
+}
+
+func TestResponseSink(t *testing.T) {
+	var sink ResponseSink = NewResponseBuffer()
+
+	err := WriteErrorToSink(sink, InvalidRequest("foo"))
+	assert.NoError(t, err)
+
+	buf := sink.(*ResponseBuffer)
+	assert.Equal(t, http.StatusBadRequest, buf.Status)
+	assert.Equal(t, "application/json;charset=UTF-8", buf.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{
+		"error": "invalid_request",
+		"error_description": "foo"
+	}`, buf.Body.String())
+
+	rec := httptest.NewRecorder()
+
+	err = WriteRedirectToSink(HTTPSink{rec}, "http://example.com", map[string]string{
+		"foo": "bar",
+	}, true, http.StatusFound)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusFound, rec.Code)
+	assert.Equal(t, "http://example.com#foo=bar", rec.Header().Get("Location"))
+
+	rec = httptest.NewRecorder()
+
+	err = WriteToSink(HTTPSink{rec}, map[string]string{"foo": "bar"}, http.StatusCreated)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+	assert.JSONEq(t, `{"foo":"bar"}`, rec.Body.String())
+}
+
+func TestResponseBufferImplicitStatus(t *testing.T) {
+	buf := NewResponseBuffer()
+
+	_, err := buf.Write([]byte("foo"))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, buf.Status)
+
+	buf = NewResponseBuffer()
+
+	rec := httptest.NewRecorder()
+
+	err = buf.Replay(rec)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
@@ -13,6 +13,7 @@ type RevocationRequest struct {
 	TokenTypeHint string
 	ClientID      string
 	ClientSecret  string
+	AuthMethod    ClientAuthMethod
 }
 
 // ParseRevocationRequest parses an incoming request and returns a
@@ -40,8 +41,8 @@ func ParseRevocationRequest(r *http.Request) (*RevocationRequest, error) {
 	tokenTypeHint := r.PostForm.Get("token_type_hint")
 
 	// get client id and secret
-	clientID, clientSecret, ok := r.BasicAuth()
-	if !ok {
+	clientID, clientSecret, authMethod := ParseClientCredentials(r)
+	if clientID == "" {
 		return nil, InvalidRequest("missing or invalid HTTP authorization header")
 	}
 
@@ -50,9 +51,26 @@ func ParseRevocationRequest(r *http.Request) (*RevocationRequest, error) {
 		TokenTypeHint: tokenTypeHint,
 		ClientID:      clientID,
 		ClientSecret:  clientSecret,
+		AuthMethod:    authMethod,
 	}, nil
 }
 
+// WriteRevocationResponse will write a successful and non-cacheable response
+// to the response writer.
+func WriteRevocationResponse(w http.ResponseWriter) error {
+	// prevent caching
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+
+	// write header
+	w.WriteHeader(http.StatusOK)
+
+	// finish response
+	_, err := w.Write(nil)
+
+	return err
+}
+
 // RevocationRequestValues will return the form values for the provided request.
 func RevocationRequestValues(r RevocationRequest) url.Values {
 	// prepare slice
@@ -74,6 +92,9 @@ func RevocationRequestValues(r RevocationRequest) url.Values {
 		values["token_type_hint"] = slice[1:2]
 	}
 
+	// add client credentials if sent in the body
+	addClientCredentials(values, r.ClientID, r.ClientSecret, r.AuthMethod)
+
 	return values
 }
 
@@ -89,7 +110,7 @@ func BuildRevocationRequest(uri string, r RevocationRequest) (*http.Request, err
 	}
 
 	// set basic auth if available
-	if r.ClientID != "" || r.ClientSecret != "" {
+	if useBasicAuth(r.ClientID, r.ClientSecret, r.AuthMethod) {
 		req.SetBasicAuth(r.ClientID, r.ClientSecret)
 	}
 
@@ -2,6 +2,7 @@ package oauth2
 
 import (
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"testing"
 
@@ -71,6 +72,17 @@ func TestParseRevocationRequestErrors(t *testing.T) {
 	}
 }
 
+func TestWriteRevocationResponse(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	err := WriteRevocationResponse(rec)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+	assert.Equal(t, "no-cache", rec.Header().Get("Pragma"))
+	assert.Empty(t, rec.Body.String())
+}
+
 func TestRevocationRequestValues(t *testing.T) {
 	rr := RevocationRequest{}
 	assert.Equal(t, url.Values{}, RevocationRequestValues(rr))
@@ -93,6 +105,7 @@ func TestRevocationRequestBuild(t *testing.T) {
 		TokenTypeHint: "hint",
 		ClientID:      "client-id",
 		ClientSecret:  "client-secret",
+		AuthMethod:    ClientSecretBasic,
 	}
 	req, err := BuildRevocationRequest("http://auth.server/revoke", rr1)
 	assert.NoError(t, err)
@@ -102,3 +115,19 @@ func TestRevocationRequestBuild(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, rr1, *rr2)
 }
+
+func TestRevocationRequestBuildPost(t *testing.T) {
+	rr1 := RevocationRequest{
+		Token:        "token",
+		ClientID:     "client-id",
+		ClientSecret: "client-secret",
+		AuthMethod:   ClientSecretPost,
+	}
+	req, err := BuildRevocationRequest("http://auth.server/revoke", rr1)
+	assert.NoError(t, err)
+	assert.Empty(t, req.Header.Get("Authorization"))
+
+	rr2, err := ParseRevocationRequest(req)
+	assert.NoError(t, err)
+	assert.Equal(t, rr1, *rr2)
+}
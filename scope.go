@@ -5,6 +5,10 @@ import (
 	"strings"
 )
 
+// OfflineAccessScope is the scope that requests the issuance of a refresh token
+// as defined by the OpenID Connect spec.
+const OfflineAccessScope = "offline_access"
+
 // A Scope is received typically in an authorization and token request.
 type Scope []string
 
@@ -29,6 +33,63 @@ func ParseScope(str string) Scope {
 	return res
 }
 
+// ParseStrictScope parses the joined string representation of a scope like
+// ParseScope but returns an InvalidScope error if a scope token contains
+// characters that are not allowed by the OAuth2 spec.
+func ParseStrictScope(str string) (Scope, error) {
+	// prepare result
+	var res Scope
+
+	// process items
+	for _, item := range strings.Split(str, " ") {
+		// skip empty items
+		if item == "" {
+			continue
+		}
+
+		// validate item
+		if !ValidScopeToken(item) {
+			return nil, InvalidScope("malformed scope token")
+		}
+
+		res = append(res, item)
+	}
+
+	return res, nil
+}
+
+// ValidScopeToken returns true if the specified scope token is not empty and
+// only contains the characters allowed by the OAuth2 spec (%x21 / %x23-5B /
+// %x5D-7E). Spaces, double quotes, backslashes and control characters are not
+// allowed.
+func ValidScopeToken(str string) bool {
+	// check length
+	if str == "" {
+		return false
+	}
+
+	// check characters
+	for i := 0; i < len(str); i++ {
+		c := str[i]
+		if c < 0x21 || c == 0x22 || c == 0x5C || c > 0x7E {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Valid returns true if all scope tokens are valid.
+func (s Scope) Valid() bool {
+	for _, i := range s {
+		if !ValidScopeToken(i) {
+			return false
+		}
+	}
+
+	return true
+}
+
 // Contains returns true if the specified string is part of the scope.
 func (s Scope) Contains(str string) bool {
 	for _, i := range s {
@@ -51,6 +112,82 @@ func (s Scope) Includes(scope Scope) bool {
 	return true
 }
 
+// IncludesWith returns true if every token of the specified scope is implied
+// by a token of this scope according to the matcher. A nil matcher falls back
+// to exact matching.
+func (s Scope) IncludesWith(scope Scope, matcher ScopeMatcher) bool {
+	// check matcher
+	if matcher == nil {
+		return s.Includes(scope)
+	}
+
+	// check tokens
+	for _, required := range scope {
+		found := false
+		for _, granted := range s {
+			if matcher(granted, required) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// A ScopeMatcher returns true if the granted scope token implies the required
+// scope token.
+type ScopeMatcher func(granted, required string) bool
+
+// ExactScopeMatcher matches scope tokens that are equal.
+func ExactScopeMatcher(granted, required string) bool {
+	return granted == required
+}
+
+// WildcardScopeMatcher matches scope tokens that are equal or granted tokens
+// with a "*" suffix that implies all tokens with the same prefix, e.g.
+// "repo:*" implies "repo:status".
+func WildcardScopeMatcher(granted, required string) bool {
+	// check equality
+	if granted == required {
+		return true
+	}
+
+	// check wildcard
+	if strings.HasSuffix(granted, "*") {
+		return strings.HasPrefix(required, strings.TrimSuffix(granted, "*"))
+	}
+
+	return false
+}
+
+// HierarchicalScopeMatcher returns a matcher that matches scope tokens that are
+// equal or where the granted token is a parent of the required token in a
+// hierarchy delimited by the separator, e.g. "read" implies "read.users" using
+// the separator ".".
+func HierarchicalScopeMatcher(separator string) ScopeMatcher {
+	return func(granted, required string) bool {
+		return granted == required || strings.HasPrefix(required, granted+separator)
+	}
+}
+
+// AnyScopeMatcher returns a matcher that matches scope tokens if any of the
+// specified matchers matches them.
+func AnyScopeMatcher(matchers ...ScopeMatcher) ScopeMatcher {
+	return func(granted, required string) bool {
+		for _, matcher := range matchers {
+			if matcher(granted, required) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
 // Empty return true if the scope is empty.
 func (s Scope) Empty() bool {
 	return len(s) == 0
@@ -65,3 +202,18 @@ func (s Scope) String() string {
 func (s Scope) MarshalJSON() ([]byte, error) {
 	return json.Marshal(s.String())
 }
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (s *Scope) UnmarshalJSON(data []byte) error {
+	// decode string
+	var str string
+	err := json.Unmarshal(data, &str)
+	if err != nil {
+		return err
+	}
+
+	// parse scope
+	*s = ParseScope(str)
+
+	return nil
+}
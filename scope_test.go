@@ -24,6 +24,30 @@ func TestParseScope(t *testing.T) {
 	}
 }
 
+func TestParseStrictScope(t *testing.T) {
+	scope, err := ParseStrictScope(" foo  bar:baz ")
+	assert.NoError(t, err)
+	assert.Equal(t, Scope{"foo", "bar:baz"}, scope)
+
+	scope, err = ParseStrictScope("")
+	assert.NoError(t, err)
+	assert.Equal(t, Scope(nil), scope)
+
+	for _, str := range []string{"foo\tbar", "foo \"bar\"", "foo\\bar", "föö", "foo\nbar"} {
+		scope, err = ParseStrictScope(str)
+		assert.Nil(t, scope)
+		assert.Equal(t, "invalid_scope: malformed scope token", err.Error())
+	}
+}
+
+func TestScopeValid(t *testing.T) {
+	assert.True(t, Scope{}.Valid())
+	assert.True(t, Scope{"foo", "!#[]~"}.Valid())
+	assert.False(t, Scope{""}.Valid())
+	assert.False(t, Scope{"foo", "b ar"}.Valid())
+	assert.False(t, Scope{"\x7f"}.Valid())
+}
+
 func TestScopeContains(t *testing.T) {
 	s := Scope{"foo", "bar"}
 	assert.True(t, s.Contains("foo"))
@@ -40,6 +64,33 @@ func TestScopeIncludes(t *testing.T) {
 	assert.False(t, s2.Includes(s1))
 }
 
+func TestScopeIncludesWith(t *testing.T) {
+	s := Scope{"read", "repo:*", "admin"}
+	assert.True(t, s.IncludesWith(Scope{"read", "admin"}, nil))
+	assert.False(t, s.IncludesWith(Scope{"read.users"}, nil))
+	assert.False(t, s.IncludesWith(Scope{"read.users"}, ExactScopeMatcher))
+
+	hierarchical := HierarchicalScopeMatcher(".")
+	assert.True(t, s.IncludesWith(Scope{"read.users", "read.users.emails", "admin"}, hierarchical))
+	assert.False(t, s.IncludesWith(Scope{"reader"}, hierarchical))
+	assert.False(t, s.IncludesWith(Scope{"repo:status"}, hierarchical))
+
+	assert.True(t, s.IncludesWith(Scope{"repo:status", "repo:*"}, WildcardScopeMatcher))
+	assert.False(t, s.IncludesWith(Scope{"repo"}, WildcardScopeMatcher))
+	assert.False(t, s.IncludesWith(Scope{"read.users"}, WildcardScopeMatcher))
+
+	combined := AnyScopeMatcher(hierarchical, WildcardScopeMatcher)
+	assert.True(t, s.IncludesWith(Scope{"read.users", "repo:status"}, combined))
+	assert.False(t, s.IncludesWith(Scope{"write"}, combined))
+
+	custom := func(granted, required string) bool {
+		return granted == "admin"
+	}
+	assert.True(t, s.IncludesWith(Scope{"anything"}, custom))
+	assert.False(t, Scope{"read"}.IncludesWith(Scope{"anything"}, custom))
+	assert.True(t, Scope{}.IncludesWith(Scope{}, custom))
+}
+
 func TestScopeEmpty(t *testing.T) {
 	s0 := Scope{}
 	assert.True(t, s0.Empty())
@@ -53,3 +104,13 @@ func TestScopeMarshalJSON(t *testing.T) {
 	buf, _ := s.MarshalJSON()
 	assert.Equal(t, `"foo bar"`, string(buf))
 }
+
+func TestScopeUnmarshalJSON(t *testing.T) {
+	var s Scope
+	err := s.UnmarshalJSON([]byte(`"foo bar"`))
+	assert.NoError(t, err)
+	assert.Equal(t, Scope{"foo", "bar"}, s)
+
+	err = s.UnmarshalJSON([]byte(`1`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,81 @@
+package oauth2
+
+import (
+	"bytes"
+	"time"
+)
+
+// SecretSource provides the keys used to sign and verify tokens from an
+// external secret manager like HashiCorp Vault, AWS KMS or GCP KMS. This way
+// no plaintext secrets need to be present in the configuration.
+type SecretSource interface {
+	// FetchKeys should return all keys that are currently available. Keys
+	// that are retired should have their NotAfter field set.
+	FetchKeys() ([]Key, error)
+}
+
+// SecretSourceFunc is a function that implements the SecretSource interface.
+type SecretSourceFunc func() ([]Key, error)
+
+// FetchKeys implements the SecretSource interface.
+func (f SecretSourceFunc) FetchKeys() ([]Key, error) {
+	return f()
+}
+
+func (k *Keyring) fetch(now time.Time, force bool) error {
+	// check cache
+	if !force && !k.fetched.IsZero() && k.fetched.Add(k.config.CacheDuration).After(now) {
+		return nil
+	}
+
+	// fetch keys
+	keys, err := k.config.Source.FetchKeys()
+	if err != nil {
+		// keep stale keys and retry once the cache expires again
+		if !force && len(k.keys) > 0 {
+			k.fetched = now
+			return nil
+		}
+
+		return err
+	}
+
+	// set time
+	k.fetched = now
+
+	// check keys
+	if equalKeys(k.keys, keys) {
+		return nil
+	}
+
+	// set keys
+	k.keys = append([]Key{}, keys...)
+
+	// sort keys
+	k.sort()
+
+	return k.changed(true)
+}
+
+func equalKeys(a, b []Key) bool {
+	// check length
+	if len(a) != len(b) {
+		return false
+	}
+
+	// index keys
+	index := make(map[string]Key, len(a))
+	for _, key := range a {
+		index[key.ID] = key
+	}
+
+	// compare keys
+	for _, key := range b {
+		other, ok := index[key.ID]
+		if !ok || !bytes.Equal(other.Secret, key.Secret) || !other.NotBefore.Equal(key.NotBefore) || !other.NotAfter.Equal(key.NotAfter) {
+			return false
+		}
+	}
+
+	return true
+}
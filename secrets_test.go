@@ -0,0 +1,92 @@
+package oauth2
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyringSource(t *testing.T) {
+	now := time.Now()
+
+	var fetches int
+	var fail bool
+	keys := []Key{
+		{ID: "1", Secret: []byte("foo"), NotBefore: now.Add(-time.Hour)},
+	}
+
+	var changes [][]Key
+	keyring := NewKeyring(KeyringConfig{
+		Source: SecretSourceFunc(func() ([]Key, error) {
+			fetches++
+			if fail {
+				return nil, errors.New("unavailable")
+			}
+			return keys, nil
+		}),
+		CacheDuration: time.Minute,
+		OnChange: func(keys []Key) error {
+			changes = append(changes, keys)
+			return nil
+		},
+	})
+
+	key, err := keyring.Current(now)
+	assert.NoError(t, err)
+	assert.Equal(t, "1", key.ID)
+	assert.Equal(t, 1, fetches)
+	assert.Len(t, changes, 1)
+
+	key, err = keyring.Current(now.Add(30 * time.Second))
+	assert.NoError(t, err)
+	assert.Equal(t, "1", key.ID)
+	assert.Equal(t, 1, fetches)
+
+	keys = []Key{
+		{ID: "2", Secret: []byte("bar"), NotBefore: now},
+		{ID: "1", Secret: []byte("foo"), NotBefore: now.Add(-time.Hour), NotAfter: now.Add(time.Hour)},
+	}
+
+	key, err = keyring.Current(now.Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, "2", key.ID)
+	assert.Equal(t, 2, fetches)
+	assert.Len(t, changes, 2)
+	assert.Len(t, keyring.Valid(now.Add(time.Minute)), 2)
+
+	key, err = keyring.Current(now.Add(2 * time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, "2", key.ID)
+	assert.Equal(t, 3, fetches)
+	assert.Len(t, changes, 2)
+
+	fail = true
+
+	key, err = keyring.Current(now.Add(3 * time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, "2", key.ID)
+	assert.Equal(t, 4, fetches)
+
+	err = keyring.Rotate(now.Add(3 * time.Minute))
+	assert.Error(t, err)
+
+	fail = false
+
+	err = keyring.Rotate(now.Add(3 * time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, 6, fetches)
+}
+
+func TestKeyringSourceError(t *testing.T) {
+	keyring := NewKeyring(KeyringConfig{
+		Source: SecretSourceFunc(func() ([]Key, error) {
+			return nil, errors.New("unavailable")
+		}),
+	})
+
+	_, err := keyring.Current(time.Now())
+	assert.Error(t, err)
+	assert.Empty(t, keyring.Valid(time.Now()))
+}
@@ -1,7 +1,14 @@
 package oauth2
 
 import (
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
 	"strings"
 	"sync"
 	"time"
@@ -15,6 +22,248 @@ type ServerConfig struct {
 	AccessTokenLifespan       time.Duration
 	RefreshTokenLifespan      time.Duration
 	AuthorizationCodeLifespan time.Duration
+
+	// The scope that is requested if an authorization request or a password,
+	// client credentials or JWT bearer token request omits the scope (RFC
+	// 6749 section 3.3). It must be included in the allowed scope.
+	DefaultScope Scope
+
+	// If enabled, refresh tokens are only issued if the granted scope contains
+	// the offline access scope.
+	RequireOfflineAccess bool
+
+	// If set, token responses are signed with the key if the client accepts
+	// "application/jwt" responses.
+	ResponseSigningKey []byte
+
+	// If enabled, token responses are url encoded if the client accepts
+	// "application/x-www-form-urlencoded" but not "application/json"
+	// responses.
+	FormTokenResponses bool
+
+	// If enabled, authorization codes carry their own encrypted state and are
+	// not stored. Only the identifiers of used codes are retained until they
+	// expire to prevent replays.
+	EncryptedAuthorizationCodes bool
+
+	// The key used to encrypt authorization codes. If empty, a dedicated key
+	// is derived from the keys of the keyring or the secret.
+	CodeEncryptionKey []byte
+
+	// If set, tokens are generated and verified using the keys of the keyring
+	// instead of the secret.
+	Keyring *Keyring
+
+	// If enabled, requested scopes that contain characters not allowed by the
+	// OAuth2 spec are rejected instead of being processed leniently.
+	StrictScope bool
+
+	// If enabled, the token endpoint also accepts multipart/form-data bodies
+	// as sent by some legacy clients. By default, these requests are rejected
+	// as required by the OAuth2 spec.
+	AllowMultipartTokenRequests bool
+
+	// The maximum duration a request may wait for the server mutex. Requests
+	// that time out while waiting are answered with a temporarily unavailable
+	// error. Requests canceled by the client while waiting are answered the
+	// same way. Only the lock acquisition is bounded: once the mutex has been
+	// acquired, the request is processed to completion. The authorization
+	// policy and assertion verifier receive the request context and may
+	// observe client cancellation, event handlers are not bounded at all.
+	LockTimeout time.Duration
+
+	// If set, the guest grant is enabled and clients may obtain short-lived
+	// access tokens without resource owner credentials that are limited to
+	// this scope.
+	GuestScope Scope
+
+	// The lifespan of guest access tokens. Defaults to five minutes.
+	GuestTokenLifespan time.Duration
+
+	// The maximum number of guest tokens issued per client and minute. Zero
+	// means unlimited.
+	GuestRateLimit int
+
+	// If set, the JWT bearer grant is enabled and clients may exchange
+	// assertions of the trusted issuers for access tokens. The subject of the
+	// assertion becomes the resource owner of the issued token.
+	AssertionVerifier *AssertionVerifier
+
+	// If set, the introspection endpoint also accepts callers that
+	// authenticate with an access token that includes this scope. These
+	// callers may introspect tokens of all clients.
+	IntrospectionScope Scope
+
+	// If set, revocation requests of authenticated clients are accepted
+	// immediately and enqueued for asynchronous processing.
+	RevocationQueue RevocationQueue
+
+	// If set, the policy is evaluated after a request to a protected resource
+	// has been authorized using an access token.
+	AuthorizationPolicy AuthorizationPolicy
+
+	// If enabled, authorization requests must carry a state parameter to
+	// protect against CSRF. If PKCE is allowed to replace the state, requests
+	// that include a code challenge may omit the state as permitted by OAuth
+	// 2.1.
+	RequireState       bool
+	AllowStatelessPKCE bool
+
+	// If enabled, GET requests to the authorization endpoint that accept JSON
+	// responses are answered with a bearer challenge instead of the plain-text
+	// notice.
+	AuthorizationChallenge bool
+
+	// If set, the handler is called for issued and revoked tokens, failed
+	// client and resource owner authentications as well as disabled clients
+	// and changed client secrets.
+	EventHandler ServerEventHandler
+
+	// If set, the function is called to render the authorization page for
+	// validated GET requests to the authorization endpoint instead of showing
+	// a notice. The page should POST the request parameters together with the
+	// resource owner credentials back to the endpoint. It is called while the
+	// server is locked and must not call back into the server.
+	AuthorizationPage func(w http.ResponseWriter, r *http.Request, req *AuthorizationRequest)
+
+	// The redactor used to mask credentials in event reasons, recorded
+	// exchanges and the descriptions of errors written by the server.
+	// Defaults to DefaultRedactor.
+	Redactor *Redactor
+
+	// If enabled, used refresh tokens are retained until they expire. If a
+	// used refresh token is presented again, all tokens descending from the
+	// same original refresh token are revoked.
+	RefreshTokenReuseDetection bool
+
+	// If set together with reuse detection, a used refresh token remains
+	// usable for the specified period after its first use to tolerate racing
+	// refresh requests of the same client. Using it after the period revokes
+	// all tokens descending from the same original refresh token.
+	RefreshTokenGracePeriod time.Duration
+
+	// If positive, tokens descending from the same original authorization may
+	// only be refreshed the specified number of times. Refresh tokens of the
+	// last generation are rejected with an invalid_grant error and the
+	// resource owner must authorize the client again.
+	MaxRefreshGenerations int
+
+	// If enabled, token responses include the time the tokens were issued at
+	// and the "time" endpoint reports the current server time. This allows
+	// clients to detect clock skew.
+	ClockSkewSupport bool
+
+	// If set, the clock is used instead of the system time to check and set
+	// the expiry of credentials.
+	Clock Clock
+
+	// If set, tokens are signed using the Ed25519 or ECDSA P-256 private key
+	// instead of the secret or keyring. Resource servers may then verify
+	// tokens using ParseSignedToken and only the public key.
+	TokenSigner crypto.Signer
+
+	// The status used to redirect responses of the authorization endpoint.
+	// Either 302 Found or 303 See Other (default).
+	RedirectStatus int
+
+	// The key length of authorization codes. Defaults to the key length of
+	// tokens.
+	CodeKeyLength int
+
+	// The matcher used to check whether an access token grants the scope
+	// required by Authorize, Authenticate and privileged introspection
+	// requests. Defaults to exact matching.
+	ScopeMatcher ScopeMatcher
+
+	// If set, authorization codes are signed with an HMAC using this hash
+	// (e.g. crypto.SHA224) instead of using the token format. Together with
+	// the code key length, this allows issuing shorter codes.
+	CodeHash crypto.Hash
+
+	// If enabled, clients may bind refresh tokens to the key of a client
+	// instance by sending an instance proof with token requests. Refresh
+	// tokens that are bound to a key can only be used with a fresh proof
+	// signed by the same key.
+	InstanceProofs bool
+
+	// The client authentication methods accepted by the token, revocation and
+	// introspection endpoints. Defaults to all known methods.
+	ClientAuthMethods []ClientAuthMethod
+
+	// If enabled, access tokens issued to clients that authenticated using a
+	// TLS client certificate are bound to the certificate (RFC 8705). Bound
+	// tokens are only accepted with the same certificate and introspection
+	// responses include the confirmation.
+	CertificateBoundTokens bool
+
+	// If enabled, revoked access and refresh tokens are retained until they
+	// expire. Bearer and grant errors then describe them as revoked and
+	// introspection responses include the time of revocation. By default,
+	// revoked tokens are removed and indistinguishable from unknown tokens.
+	DistinguishRevokedTokens bool
+
+	// The authorization detail types (RFC 9396) that clients may request.
+	// Requests with other types are rejected. If empty, authorization details
+	// are not supported.
+	AuthorizationDetailTypes []string
+
+	// If enabled, resource owners authenticate and consent in separate
+	// requests. Authenticated authorization requests are answered with a flow
+	// ID that is also set as a cookie. A subsequent request with the flow ID
+	// and the approved scope (or "consent=deny") completes the authorization.
+	// Flows expire after the authorization code lifespan.
+	SeparateConsent bool
+
+	// If positive, authenticated resource owners receive a login session
+	// cookie that is valid for the specified duration. Subsequent
+	// authorization requests with the cookie and without credentials are
+	// authenticated as the same resource owner.
+	SessionLifespan time.Duration
+
+	// If enabled, the scope granted by resource owners to clients is
+	// remembered. Subsequent authorizations of the same client that do not
+	// exceed the granted scope skip the separate consent step.
+	RememberConsent bool
+
+	// If positive, remembered consents expire after the specified duration
+	// and must be given again. By default, consents are kept until revoked.
+	ConsentLifespan time.Duration
+
+	// If positive, the server records the specified number of most recent
+	// raw HTTP exchanges with masked credentials. They can be retrieved using
+	// Exchanges to diagnose failed integrations.
+	DebugExchanges int
+
+	// If enabled, clients must not use plaintext http redirect URIs unless
+	// they target a loopback IP. Such clients are reported by SelfCheck and
+	// their authorization requests are rejected.
+	RequireHTTPSRedirects bool
+
+	// If enabled, redirects to the client only carry the parameters defined
+	// for authorization responses (see RedirectParameters). Other parameters
+	// like the realm or scope of errors are dropped.
+	StrictRedirectParameters bool
+
+	// If enabled, the authorization and token endpoints reject requests that
+	// have not been received over TLS. Requests forwarded by a trusted proxy
+	// are accepted if the X-Forwarded-Proto or Forwarded header reports https.
+	RequireTLS bool
+
+	// The IP addresses or CIDR ranges of the proxies whose X-Forwarded-Proto
+	// and Forwarded headers are trusted. The headers of all other peers are
+	// ignored as they can be set by any client.
+	TrustedProxies []string
+
+	// If enabled, plaintext requests from loopback addresses are accepted even
+	// if TLS is required. This allows testing the server locally. Requests
+	// from trusted proxies or with forwarding headers are not considered to
+	// be loopback requests.
+	AllowInsecureLoopback bool
+
+	// If set, the "revoke_all" endpoint revokes all credentials of the client
+	// or user specified by the "client_id" or "username" form parameter. The
+	// endpoint must be called using the admin token as the bearer token.
+	AdminToken string
 }
 
 // DefaultServerConfig will return a default configuration.
@@ -29,6 +278,117 @@ func DefaultServerConfig(secret []byte, allowed Scope) ServerConfig {
 	}
 }
 
+// Validate will validate the configuration and return an error that lists all
+// detected problems.
+func (c ServerConfig) Validate() error {
+	// prepare problems
+	var problems []string
+
+	// check secret
+	if c.Keyring == nil && c.TokenSigner == nil && len(c.Secret) < 16 {
+		problems = append(problems, "secret must be at least 16 bytes long")
+	}
+
+	// check code encryption key
+	if c.EncryptedAuthorizationCodes && len(c.CodeEncryptionKey) < 16 && (len(c.CodeEncryptionKey) > 0 || c.Keyring == nil && len(c.Secret) < 16) {
+		problems = append(problems, "encrypted authorization codes require a key of at least 16 bytes")
+	}
+
+	// check key length
+	if c.KeyLength < 16 {
+		problems = append(problems, "key length must be at least 16")
+	}
+
+	// check lifespans
+	if c.AuthorizationCodeLifespan <= 0 || c.AccessTokenLifespan <= 0 || c.RefreshTokenLifespan <= 0 {
+		problems = append(problems, "lifespans must be positive")
+	} else if c.AuthorizationCodeLifespan >= c.AccessTokenLifespan {
+		problems = append(problems, "authorization code lifespan must be shorter than access token lifespan")
+	} else if c.AccessTokenLifespan >= c.RefreshTokenLifespan {
+		problems = append(problems, "access token lifespan must be shorter than refresh token lifespan")
+	}
+
+	// check keyring retention period
+	if c.Keyring != nil && c.Keyring.config.Source == nil && c.Keyring.config.RetentionPeriod < c.RefreshTokenLifespan {
+		problems = append(problems, "keyring retention period must not be shorter than refresh token lifespan")
+	}
+
+	// check allowed scope
+	if !c.AllowedScope.Empty() && c.RequireOfflineAccess && !c.AllowedScope.Contains(OfflineAccessScope) {
+		problems = append(problems, "allowed scope must contain offline access scope if required")
+	}
+
+	// check default scope
+	if !c.AllowedScope.Includes(c.DefaultScope) {
+		problems = append(problems, "default scope must be included in allowed scope")
+	}
+
+	// check response signing key
+	if c.ResponseSigningKey != nil && len(c.ResponseSigningKey) < 16 {
+		problems = append(problems, "response signing key must be at least 16 bytes long")
+	}
+
+	// check trusted proxies
+	for _, proxy := range c.TrustedProxies {
+		if parseProxy(proxy) == nil {
+			problems = append(problems, fmt.Sprintf("trusted proxy %q is not an IP address or CIDR range", proxy))
+		}
+	}
+
+	// check consent lifespan
+	if c.ConsentLifespan < 0 {
+		problems = append(problems, "consent lifespan must not be negative")
+	}
+
+	// check lock timeout
+	if c.LockTimeout < 0 {
+		problems = append(problems, "lock timeout must not be negative")
+	}
+
+	// check refresh token grace period
+	if c.RefreshTokenGracePeriod < 0 {
+		problems = append(problems, "refresh token grace period must not be negative")
+	} else if c.RefreshTokenGracePeriod > 0 && !c.RefreshTokenReuseDetection {
+		problems = append(problems, "refresh token grace period requires reuse detection")
+	}
+
+	// check debug exchanges
+	if c.DebugExchanges < 0 {
+		problems = append(problems, "debug exchanges must not be negative")
+	}
+
+	// check redirect status
+	if c.RedirectStatus != 0 && c.RedirectStatus != http.StatusFound && c.RedirectStatus != http.StatusSeeOther {
+		problems = append(problems, "redirect status must be 302 or 303")
+	}
+
+	// check code key length
+	if c.CodeKeyLength != 0 && c.CodeKeyLength < 8 {
+		problems = append(problems, "code key length must be at least 8")
+	}
+
+	// check code hash
+	if c.CodeHash != 0 && !c.CodeHash.Available() {
+		problems = append(problems, "code hash is not available")
+	} else if c.CodeHash != 0 && c.Keyring == nil && len(c.Secret) < 16 {
+		problems = append(problems, "code hash requires a secret or keyring")
+	}
+
+	// check client auth methods
+	for _, method := range c.ClientAuthMethods {
+		if !KnownClientAuthMethod(method) {
+			problems = append(problems, fmt.Sprintf("unknown client auth method %q", method))
+		}
+	}
+
+	// check problems
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid server config: %s", strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
 // MustGenerate will generate a new token.
 func (c ServerConfig) MustGenerate() *HS256Token {
 	return MustGenerateHS256Token(c.Secret, c.KeyLength)
@@ -39,6 +399,39 @@ type ServerEntity struct {
 	Secret       string
 	RedirectURI  string
 	Confidential bool
+	Disabled     bool
+
+	// The TLS client authentication settings of a client (see
+	// MatchClientCertificate). If the client presents a matching certificate,
+	// it authenticates without a secret.
+	TLSSubject      string
+	TLSCertificates []string
+
+	// The scope a resource owner must at least approve when authorizing the
+	// client.
+	RequiredScope Scope
+
+	// The optional policies of a client. If set, the client may only request
+	// the specified scope and use the specified grant and response types.
+	// Lifespans override the configured token lifespans.
+	AllowedScope         Scope
+	GrantTypes           []string
+	ResponseTypes        []string
+	AccessTokenLifespan  time.Duration
+	RefreshTokenLifespan time.Duration
+
+	// The descriptive metadata of a client that can be shown to resource
+	// owners when authorizing the client.
+	Name      string
+	Contacts  []string
+	LogoURI   string
+	PolicyURI string
+}
+
+// ServerAlias maps a previous client ID to the client that replaced it.
+type ServerAlias struct {
+	ClientID  string
+	ExpiresAt time.Time
 }
 
 // ServerCredential represents an access token, refresh token or authorization code.
@@ -49,7 +442,70 @@ type ServerCredential struct {
 	Scope       Scope
 	RedirectURI string
 	Code        string
+	Family      string
 	Used        bool
+	UsedAt      time.Time
+	Generation  int
+	InstanceKey string
+	RevokedAt   time.Time
+	TxCode      string
+
+	CertificateThumbprint string
+
+	Fingerprint string
+
+	AuthorizationDetails []AuthorizationDetail
+
+	CodeChallenge       string
+	CodeChallengeMethod string
+}
+
+func (c ServerCredential) clone() ServerCredential {
+	// copy scope
+	if c.Scope != nil {
+		c.Scope = append(Scope{}, c.Scope...)
+	}
+
+	// copy authorization details
+	if c.AuthorizationDetails != nil {
+		details := make([]AuthorizationDetail, len(c.AuthorizationDetails))
+		for i, detail := range c.AuthorizationDetails {
+			details[i] = detail.clone()
+		}
+		c.AuthorizationDetails = details
+	}
+
+	return c
+}
+
+func (c *ServerCredential) claims() Claims {
+	// prepare claims
+	claims := Claims{
+		"client_id": c.ClientID,
+	}
+
+	// add username
+	if c.Username != "" {
+		claims.Set("sub", c.Username)
+	}
+
+	// add scope and expiry
+	claims.SetScope(c.Scope)
+	claims.SetTime("exp", c.ExpiresAt)
+
+	// add authorization details
+	if len(c.AuthorizationDetails) > 0 {
+		claims.Set("authorization_details", c.AuthorizationDetails)
+	}
+
+	// add confirmation
+	if c.CertificateThumbprint != "" {
+		claims.Set("cnf", map[string]interface{}{
+			"x5t#S256": c.CertificateThumbprint,
+		})
+	}
+
+	return claims
 }
 
 // Server implements a basic in-memory OAuth2 authentication server intended for
@@ -61,7 +517,22 @@ type Server struct {
 	AccessTokens       map[string]*ServerCredential
 	RefreshTokens      map[string]*ServerCredential
 	AuthorizationCodes map[string]*ServerCredential
+	PreAuthorizedCodes map[string]*ServerCredential
+	UsedCodes          map[string]time.Time
+	ClientAliases      map[string]*ServerAlias
 	Mutex              sync.Mutex
+
+	gate          chan struct{}
+	gateOnce      sync.Once
+	guestIssuance map[string][]time.Time
+	proofChecker  ProofChecker
+	flows         map[string]*serverFlow
+	sessions      map[string]*serverSession
+	consents      map[serverConsentKey]serverConsent
+	timeOffset    time.Duration
+	stats         statsCollector
+	stateStats    StateStats
+	exchanges     exchangeRecorder
 }
 
 // NewServer creates and returns a new server.
@@ -73,58 +544,425 @@ func NewServer(config ServerConfig) *Server {
 		AccessTokens:       map[string]*ServerCredential{},
 		RefreshTokens:      map[string]*ServerCredential{},
 		AuthorizationCodes: map[string]*ServerCredential{},
+		PreAuthorizedCodes: map[string]*ServerCredential{},
+		UsedCodes:          map[string]time.Time{},
+		ClientAliases:      map[string]*ServerAlias{},
+	}
+}
+
+// SelfCheck will validate the configuration and verify that the server is
+// able to store and issue credentials. It returns an error that lists all
+// detected problems.
+func (s *Server) SelfCheck() error {
+	// acquire mutex
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+
+	// prepare problems
+	var problems []string
+
+	// validate config
+	err := s.Config.Validate()
+	if err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	// check storage
+	if s.Clients == nil || s.Users == nil || s.AccessTokens == nil || s.RefreshTokens == nil ||
+		s.AuthorizationCodes == nil || s.PreAuthorizedCodes == nil || s.UsedCodes == nil || s.ClientAliases == nil {
+		problems = append(problems, "storage is not initialized (use NewServer)")
+	}
+
+	// check clients
+	for id, client := range s.Clients {
+		if client == nil {
+			problems = append(problems, fmt.Sprintf("client %q is missing", id))
+		} else if client.RedirectURI != "" {
+			_, err = NormalizeRedirectURI(client.RedirectURI)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("client %q has an invalid redirect URI", id))
+			} else if s.Config.RequireHTTPSRedirects && plaintextRedirectURI(client.RedirectURI) {
+				problems = append(problems, fmt.Sprintf("client %q has a plaintext http redirect URI", id))
+			}
+		}
+
+		// check metadata
+		if client != nil && client.LogoURI != "" && !absoluteURI(client.LogoURI) {
+			problems = append(problems, fmt.Sprintf("client %q has an invalid logo URI", id))
+		}
+		if client != nil && client.PolicyURI != "" && !absoluteURI(client.PolicyURI) {
+			problems = append(problems, fmt.Sprintf("client %q has an invalid policy URI", id))
+		}
+
+		// check policies
+		if client != nil && (client.AccessTokenLifespan < 0 || client.RefreshTokenLifespan < 0) {
+			problems = append(problems, fmt.Sprintf("client %q has a negative lifespan", id))
+		}
+		if client != nil {
+			for _, grantType := range client.GrantTypes {
+				if !KnownGrantType(grantType) && !extensionGrantType(grantType) {
+					problems = append(problems, fmt.Sprintf("client %q has an unknown grant type %q", id, grantType))
+				}
+			}
+			for _, responseType := range client.ResponseTypes {
+				if !KnownResponseType(responseType) {
+					problems = append(problems, fmt.Sprintf("client %q has an unknown response type %q", id, responseType))
+				}
+			}
+		}
+	}
+
+	// check keyring
+	if s.Config.Keyring != nil {
+		_, err = s.Config.Keyring.Current(s.now())
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("keyring is not usable: %s", err.Error()))
+		}
+	}
+
+	// check token generation
+	if s.Config.TokenSigner != nil {
+		_, err = GenerateSignedToken(s.Config.TokenSigner, s.Config.KeyLength)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("unable to generate tokens: %s", err.Error()))
+		}
+	} else if s.Config.Keyring == nil {
+		_, err = GenerateHS256Token(s.Config.Secret, s.Config.KeyLength)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("unable to generate tokens: %s", err.Error()))
+		}
+	}
+
+	// check problems
+	if len(problems) > 0 {
+		return fmt.Errorf("self check failed: %s", strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+func absoluteURI(str string) bool {
+	u, err := url.Parse(str)
+	return err == nil && u.IsAbs() && u.Host != ""
+}
+
+func extensionGrantType(grantType string) bool {
+	return grantType == GuestGrantType || grantType == JWTBearerGrantType || grantType == PreAuthorizedCodeGrantType
+}
+
+func containsString(list []string, str string) bool {
+	for _, item := range list {
+		if item == str {
+			return true
+		}
+	}
+
+	return false
+}
+
+func copyStrings(list []string) []string {
+	// check list
+	if list == nil {
+		return nil
+	}
+
+	return append([]string{}, list...)
+}
+
+// DisableClient will disable the specified client. A disabled client cannot
+// obtain new authorization codes or tokens. If requested, all issued tokens and
+// authorization codes of the client are revoked as well.
+func (s *Server) DisableClient(id string, revokeTokens bool) error {
+	// acquire mutex
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+
+	// get client
+	client, ok := s.Clients[id]
+	if !ok {
+		return fmt.Errorf("unknown client %q", id)
+	}
+
+	// disable client
+	client.Disabled = true
+
+	// emit event
+	s.emit(ServerEvent{Type: ClientDisabledEvent, ClientID: id})
+
+	// revoke tokens if requested
+	if revokeTokens {
+		s.revokeAll(func(credential *ServerCredential) bool {
+			return credential.ClientID == id
+		})
+	}
+
+	return nil
+}
+
+// ChangeClientSecret will change the secret of the specified client.
+func (s *Server) ChangeClientSecret(id, secret string) error {
+	// acquire mutex
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+
+	// get client
+	client, ok := s.Clients[id]
+	if !ok {
+		return fmt.Errorf("unknown client %q", id)
+	}
+
+	// change secret
+	client.Secret = secret
+
+	// emit event
+	s.emit(ServerEvent{Type: CredentialChangedEvent, ClientID: id})
+
+	return nil
+}
+
+// EnableClient will enable the specified previously disabled client.
+func (s *Server) EnableClient(id string) error {
+	// acquire mutex
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+
+	// get client
+	client, ok := s.Clients[id]
+	if !ok {
+		return fmt.Errorf("unknown client %q", id)
+	}
+
+	// enable client
+	client.Disabled = false
+
+	return nil
+}
+
+// AliasClient will register the previous client ID as an alias of the
+// specified client. Refresh tokens issued to the previous client ID can then be
+// used by the client until the alias expires. A zero expiry never expires.
+func (s *Server) AliasClient(previousID, id string, expiresAt time.Time) error {
+	// acquire mutex
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+
+	// check client
+	if _, ok := s.Clients[id]; !ok {
+		return fmt.Errorf("unknown client %q", id)
+	}
+
+	// check alias
+	if previousID == "" || previousID == id {
+		return errors.New("invalid alias")
+	}
+
+	// store alias
+	s.ClientAliases[previousID] = &ServerAlias{
+		ClientID:  id,
+		ExpiresAt: expiresAt,
+	}
+
+	return nil
+}
+
+// ImportAccessToken will import an externally issued access token with the
+// specified details. The token is stored as a hash and accepted like tokens
+// issued by the server.
+func (s *Server) ImportAccessToken(token string, credential ServerCredential) error {
+	// acquire mutex
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+
+	return s.importToken(s.AccessTokens, token, credential)
+}
+
+// ImportRefreshToken will import an externally issued refresh token with the
+// specified details. The token is stored as a hash and can be used with the
+// refresh token grant like tokens issued by the server.
+func (s *Server) ImportRefreshToken(token string, credential ServerCredential) error {
+	// acquire mutex
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+
+	return s.importToken(s.RefreshTokens, token, credential)
+}
+
+// IssueTestTokens will issue the specified number of access tokens using the
+// template credential and return them. If the template has no expiry, the
+// configured access token lifespan is used. Unlike tokens issued by the
+// token endpoint, no events are emitted. This allows seeding load tests of
+// resource servers with many valid tokens quickly.
+func (s *Server) IssueTestTokens(n int, template ServerCredential) ([]string, error) {
+	// check count
+	if n < 0 {
+		return nil, errors.New("negative token count")
+	}
+
+	// acquire mutex
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+
+	// check client
+	if _, ok := s.Clients[template.ClientID]; !ok {
+		return nil, fmt.Errorf("unknown client %q", template.ClientID)
+	}
+
+	// set default expiry
+	if template.ExpiresAt.IsZero() {
+		template.ExpiresAt = s.now().Add(s.Config.AccessTokenLifespan)
+	}
+
+	// allocate tokens and credentials
+	tokens := make([]string, n)
+	credentials := make([]ServerCredential, n)
+
+	// issue tokens
+	for i := range tokens {
+		token, err := s.generateToken()
+		if err != nil {
+			return nil, err
+		}
+		tokens[i] = token.String()
+		credentials[i] = template.clone()
+		credentials[i].Fingerprint = Fingerprint(tokens[i])
+		s.AccessTokens[token.SignatureString()] = &credentials[i]
+	}
+
+	return tokens, nil
+}
+
+func (s *Server) importToken(list map[string]*ServerCredential, token string, credential ServerCredential) error {
+	// check token
+	if token == "" {
+		return errors.New("missing token")
+	}
+
+	// check client
+	if _, ok := s.Clients[credential.ClientID]; !ok {
+		return fmt.Errorf("unknown client %q", credential.ClientID)
 	}
+
+	// check expiry
+	if credential.ExpiresAt.IsZero() {
+		return errors.New("missing expiry")
+	}
+
+	// set fingerprint
+	credential.Fingerprint = Fingerprint(token)
+
+	// store token
+	list[importedTokenKey(token)] = &credential
+
+	return nil
+}
+
+func importedTokenKey(token string) string {
+	// hash token
+	sum := sha256.Sum256([]byte(token))
+
+	return "imported:" + b64.EncodeToString(sum[:])
 }
 
 // Authorize will authorize the request and require a valid access token. An
 // error has already be written to the client if false is returned.
 func (s *Server) Authorize(w http.ResponseWriter, r *http.Request, required Scope) bool {
+	_, ok := s.Authenticate(w, r, required)
+	return ok
+}
+
+// Authenticate will authorize the request like Authorize and return the claims
+// of the access token.
+func (s *Server) Authenticate(w http.ResponseWriter, r *http.Request, required Scope) (Claims, bool) {
 	// acquire mutex
-	s.Mutex.Lock()
-	defer s.Mutex.Unlock()
+	if !s.acquire(w, r) {
+		return nil, false
+	}
+	defer s.release()
 
 	// parse bearer token
 	tk, err := ParseBearerToken(r)
 	if err != nil {
-		_ = WriteBearerError(w, err)
-		return false
+		_ = s.writeBearerError(w, err)
+		return nil, false
 	}
 
 	// parse token
-	token, err := ParseHS256Token(s.Config.Secret, tk)
+	key, err := s.tokenKey(tk)
 	if err != nil {
-		_ = WriteBearerError(w, InvalidToken("malformed token"))
-		return false
+		_ = s.writeBearerError(w, InvalidToken("malformed token"))
+		return nil, false
 	}
 
 	// get token
-	accessToken, found := s.AccessTokens[token.SignatureString()]
+	accessToken, found := s.AccessTokens[key]
 	if !found {
-		_ = WriteBearerError(w, InvalidToken("unknown token"))
-		return false
+		_ = s.writeBearerError(w, InvalidToken("unknown token"))
+		return nil, false
+	}
+
+	// validate revocation
+	if !accessToken.RevokedAt.IsZero() {
+		_ = s.writeBearerError(w, InvalidToken("revoked token"))
+		return nil, false
 	}
 
 	// validate expiration
-	if accessToken.ExpiresAt.Before(time.Now()) {
-		_ = WriteBearerError(w, InvalidToken("expired token"))
-		return false
+	if accessToken.ExpiresAt.Before(s.now()) {
+		_ = s.writeBearerError(w, InvalidToken("expired token"))
+		return nil, false
+	}
+
+	// validate certificate binding
+	err = CheckCertificateBinding(r, accessToken.CertificateThumbprint)
+	if err != nil {
+		_ = s.writeBearerError(w, err)
+		return nil, false
 	}
 
 	// validate scope
-	if !accessToken.Scope.Includes(required) {
-		_ = WriteBearerError(w, InsufficientScope(required.String()))
-		return false
+	if !accessToken.Scope.IncludesWith(required, s.Config.ScopeMatcher) {
+		_ = s.writeBearerError(w, InsufficientScope(required.String()))
+		return nil, false
 	}
 
-	return true
+	// get claims
+	claims := accessToken.claims()
+
+	// evaluate policy if available
+	if s.Config.AuthorizationPolicy != nil {
+		ok, err := s.Config.AuthorizationPolicy.Evaluate(r.Context(), PolicyInput{
+			Claims: claims,
+			Method: r.Method,
+			Path:   r.URL.Path,
+			Scope:  required,
+		})
+		if err != nil {
+			_ = s.writeBearerError(w, ServerError("").SetCause(err))
+			return nil, false
+		} else if !ok {
+			_ = s.writeBearerError(w, AccessDenied("denied by policy"))
+			return nil, false
+		}
+	}
+
+	return claims, true
 }
 
 // ServeHTTP will handle the provided request based on the last path segment
 // of the request URL.
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// acquire mutex
-	s.Mutex.Lock()
-	defer s.Mutex.Unlock()
+	if !s.acquire(w, r) {
+		return
+	}
+	defer s.release()
+
+	// record exchange if enabled
+	if s.Config.DebugExchanges > 0 {
+		var done func()
+		w, done = s.exchanges.capture(w, r, s.now(), s.Config.DebugExchanges, s.redactor())
+		defer done()
+	}
 
 	// get path
 	path := r.URL.Path
@@ -138,496 +976,1716 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// check path
 	switch path {
 	case "authorize":
+		if s.Config.RedirectStatus == http.StatusFound {
+			w = &redirectStatusWriter{ResponseWriter: w, status: http.StatusFound}
+		}
 		s.authorizationEndpoint(w, r)
 	case "token":
-		s.tokenEndpoint(w, r)
+		s.measureTokenEndpoint(w, r)
 	case "introspect":
 		s.introspectionEndpoint(w, r)
 	case "revoke":
 		s.revocationEndpoint(w, r)
+	case "time":
+		s.timeEndpoint(w, r)
+	case "revoke_all":
+		s.revokeAllEndpoint(w, r)
 	default:
 		http.NotFound(w, r)
 	}
 }
 
-func (s *Server) authorizationEndpoint(w http.ResponseWriter, r *http.Request) {
-	// parse authorization request
-	req, err := ParseAuthorizationRequest(r)
-	if err != nil {
-		_ = WriteError(w, err)
-		return
+func (s *Server) acquire(w http.ResponseWriter, r *http.Request) bool {
+	// prepare gate
+	s.gateOnce.Do(func() {
+		s.gate = make(chan struct{}, 1)
+	})
+
+	// get context
+	ctx := r.Context()
+	if s.Config.LockTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Config.LockTimeout)
+		defer cancel()
 	}
 
-	// make sure the response type is known
-	if !KnownResponseType(req.ResponseType) {
-		_ = WriteError(w, InvalidRequest("unknown response type"))
-		return
+	// pass gate, waiting requests queue here and leave on timeout without
+	// leaving anything behind
+	select {
+	case s.gate <- struct{}{}:
+	case <-ctx.Done():
+		_ = s.writeError(w, TemporarilyUnavailable("request timed out or was canceled"))
+		return false
 	}
 
-	// get client
-	client, found := s.Clients[req.ClientID]
-	if !found {
-		_ = WriteError(w, InvalidClient("unknown client"))
-		return
+	// lock directly if no timeout is configured
+	if s.Config.LockTimeout <= 0 {
+		s.Mutex.Lock()
+
+		// check if the client went away in the meantime
+		if r.Context().Err() != nil {
+			s.release()
+			_ = s.writeError(w, TemporarilyUnavailable("request was canceled"))
+			return false
+		}
+
+		return true
 	}
 
-	// validate redirect uri
-	if client.RedirectURI != req.RedirectURI {
-		_ = WriteError(w, InvalidRequest("invalid redirect URI"))
-		return
+	// lock asynchronously as the mutex may be held by other callers, the
+	// goroutine releases the mutex and gate itself if the wait is abandoned
+	locked := make(chan struct{})
+	abandoned := make(chan struct{})
+	go func() {
+		s.Mutex.Lock()
+		select {
+		case locked <- struct{}{}:
+		case <-abandoned:
+			s.release()
+		}
+	}()
+
+	// await lock or cancellation
+	select {
+	case <-locked:
+	case <-ctx.Done():
+		close(abandoned)
+		_ = s.writeError(w, TemporarilyUnavailable("request timed out or was canceled"))
+		return false
 	}
 
-	// show notice for GET requests
-	if r.Method == "GET" {
-		_, _ = w.Write([]byte("This authentication server does not provide an authorization form.\n" +
-			"Please submit the resource owners username and password in a POST request."))
-		return
+	// check if the client went away in the meantime
+	if r.Context().Err() != nil {
+		s.release()
+		_ = s.writeError(w, TemporarilyUnavailable("request was canceled"))
+		return false
+	}
+
+	return true
+}
+
+func (s *Server) release() {
+	// release mutex and gate
+	s.Mutex.Unlock()
+	<-s.gate
+}
+
+func (s *Server) authorizationEndpoint(w http.ResponseWriter, r *http.Request) {
+	// check tls
+	if err := s.checkTLS(r); err != nil {
+		_ = s.writeError(w, err)
+		return
+	}
+
+	// resume flow if consent is a separate step
+	if s.Config.SeparateConsent && r.Method == "POST" {
+		if id := authorizationFlowID(r); id != "" {
+			s.resumeAuthorizationFlow(w, r, id)
+			return
+		}
+	}
+
+	// parse authorization request
+	req, err := ParseAuthorizationRequest(r)
+	if err != nil {
+		_ = s.writeError(w, err)
+		return
+	}
+
+	// make sure the response type is known
+	if !KnownResponseType(req.ResponseType) {
+		_ = s.writeError(w, InvalidRequest("unknown response type"))
+		return
+	}
+
+	// get client
+	client, found := s.Clients[req.ClientID]
+	if !found {
+		_ = s.writeError(w, InvalidClient("unknown client"))
+		return
+	}
+
+	// check if client is disabled
+	if client.Disabled {
+		_ = s.writeError(w, InvalidClient("disabled client"))
+		return
+	}
+
+	// validate redirect uri
+	if !MatchRedirectURI(client.RedirectURI, req.RedirectURI) {
+		_ = s.writeError(w, InvalidRequest("invalid redirect URI"))
+		return
+	}
+
+	// check redirect uri scheme
+	if s.Config.RequireHTTPSRedirects && plaintextRedirectURI(req.RedirectURI) {
+		_ = s.writeError(w, InvalidRequest("redirect URI must use https unless it targets a loopback IP"))
+		return
+	}
+
+	// check client response types
+	if len(client.ResponseTypes) > 0 && !containsString(client.ResponseTypes, req.ResponseType) {
+		_ = s.requestContext(req).WriteError(w, UnauthorizedClient("response type not allowed for client"))
+		return
+	}
+
+	// apply default scope
+	if req.Scope.Empty() {
+		req.Scope = s.Config.DefaultScope
+	}
+
+	// check client scope
+	if !client.AllowedScope.Empty() && !client.AllowedScope.Includes(req.Scope) {
+		_ = s.requestContext(req).WriteError(w, InvalidScope(""))
+		return
+	}
+
+	// check authorization details
+	if err := s.checkAuthorizationDetails(req.AuthorizationDetails); err != nil {
+		_ = s.requestContext(req).WriteError(w, err)
+		return
+	}
+
+	// validate scope strictly if enabled
+	if s.Config.StrictScope {
+		_, err = ParseStrictScope(r.Form.Get("scope"))
+		if err != nil {
+			_ = s.requestContext(req).WriteError(w, err)
+			return
+		}
+	}
+
+	// validate state if required
+	if s.Config.RequireState && req.State == "" && (!s.Config.AllowStatelessPKCE || req.CodeChallenge == "") {
+		_ = s.requestContext(req).WriteError(w, InvalidRequest("missing state"))
+		return
+	}
+
+	// challenge non-browser GET requests if enabled
+	if r.Method == "GET" && s.Config.AuthorizationChallenge && strings.Contains(r.Header.Get("Accept"), "application/json") {
+		_ = s.writeBearerError(w, ProtectedResource())
+		return
+	}
+
+	// clear stale login session cookie
+	s.clearSession(w, r)
+
+	// render authorization page for GET requests if available
+	if r.Method == "GET" && s.Config.AuthorizationPage != nil {
+		s.Config.AuthorizationPage(w, r, req)
+		return
+	}
+
+	// show notice for GET requests
+	if r.Method == "GET" {
+		_, _ = w.Write([]byte("This authentication server does not provide an authorization form.\n" +
+			"Please submit the resource owners username and password in a POST request."))
+		return
 	}
 
 	// read username and password
 	username := r.PostForm.Get("username")
 	password := r.PostForm.Get("password")
 
+	// use user of login session if available
+	if username == "" && password == "" {
+		username = s.sessionUser(r)
+	}
+
+	// preselect user using the login hint
+	if username == "" {
+		username = req.LoginHint
+	}
+
+	// start flow if consent is a separate step
+	if s.Config.SeparateConsent {
+		s.startAuthorizationFlow(w, r, req, username, password)
+		return
+	}
+
+	// narrow scope to the approved scope if present
+	if !s.approveScope(w, r, client, req) {
+		return
+	}
+
+	// validate scope
+	if !s.Config.AllowedScope.Includes(req.Scope) {
+		_ = s.requestContext(req).WriteError(w, InvalidScope(""))
+		return
+	}
+
+	// authenticate resource owner
+	if !s.authenticateOwner(w, r, username, password, req) {
+		return
+	}
+
+	// grant authorization
+	s.grantAuthorization(w, username, req)
+}
+
+func (s *Server) approveScope(w http.ResponseWriter, r *http.Request, client *ServerEntity, req *AuthorizationRequest) bool {
+	// check approved scope
+	approved, ok := r.PostForm["approved_scope"]
+	if !ok {
+		return true
+	}
+
+	// narrow scope
+	scope, err := ConsentDecision{
+		Approved: ParseScope(strings.Join(approved, " ")),
+		Required: client.RequiredScope,
+	}.Grant(req.Scope)
+	if err != nil {
+		_ = s.requestContext(req).WriteError(w, err)
+		return false
+	}
+
+	// set scope
+	req.Scope = scope
+
+	return true
+}
+
+func (s *Server) authenticateOwner(w http.ResponseWriter, r *http.Request, username, password string, req *AuthorizationRequest) bool {
+	// accept login session if available
+	if password == "" && username != "" && s.sessionUser(r) == username {
+		return true
+	}
+
+	// validate user credentials
+	owner, found := s.Users[username]
+	if !found || owner.Secret != password {
+		s.emit(ServerEvent{Type: AuthenticationFailedEvent, ClientID: req.ClientID, Username: username, Reason: "invalid resource owner credentials"})
+		_ = s.requestContext(req).WriteError(w, AccessDenied(""))
+		return false
+	}
+
+	// start login session if enabled
+	s.startSession(w, r, username)
+
+	return true
+}
+
+func (s *Server) grantAuthorization(w http.ResponseWriter, username string, req *AuthorizationRequest) {
+	// remember consent if enabled
+	s.recordConsent(username, req)
+
 	// triage based on response type
 	switch req.ResponseType {
 	case TokenResponseType:
-		s.handleImplicitGrant(w, username, password, req)
+		s.handleImplicitGrant(w, username, req)
 	case CodeResponseType:
-		s.handleAuthorizationCodeGrantAuthorization(w, username, password, req)
+		s.handleAuthorizationCodeGrantAuthorization(w, username, req)
 	}
 }
 
-func (s *Server) handleImplicitGrant(w http.ResponseWriter, username, password string, rq *AuthorizationRequest) {
-	// validate scope
-	if !s.Config.AllowedScope.Includes(rq.Scope) {
-		_ = WriteError(w, InvalidScope("").SetRedirect(rq.RedirectURI, rq.State, true))
+func (s *Server) handleImplicitGrant(w http.ResponseWriter, username string, rq *AuthorizationRequest) {
+	// issue tokens
+	r, err := s.issueTokens(false, rq.Scope, rq.ClientID, username, "")
+	if err != nil {
+		_ = s.requestContext(rq).WriteError(w, err)
 		return
 	}
 
-	// validate user credentials
-	owner, found := s.Users[username]
-	if !found || owner.Secret != password {
-		_ = WriteError(w, AccessDenied("").SetRedirect(rq.RedirectURI, rq.State, true))
+	// grant authorization details
+	s.grantAuthorizationDetails(r, rq.AuthorizationDetails)
+
+	// write response
+	_ = s.requestContext(rq).WriteToken(w, r.TokenResponse)
+}
+
+func (s *Server) handleAuthorizationCodeGrantAuthorization(w http.ResponseWriter, username string, rq *AuthorizationRequest) {
+	// prepare authorization code
+	credential := &ServerCredential{
+		ClientID:    rq.ClientID,
+		Username:    username,
+		ExpiresAt:   s.now().Add(s.Config.AuthorizationCodeLifespan),
+		Scope:       rq.Scope,
+		RedirectURI: rq.RedirectURI,
+
+		CodeChallenge:       rq.CodeChallenge,
+		CodeChallengeMethod: rq.CodeChallengeMethod,
+
+		AuthorizationDetails: rq.AuthorizationDetails,
+	}
+
+	// issue encrypted authorization code if enabled
+	if s.Config.EncryptedAuthorizationCodes {
+		// encrypt authorization code
+		code, err := s.encryptAuthorizationCode(credential)
+		if err != nil {
+			_ = s.requestContext(rq).WriteError(w, err)
+			return
+		}
+
+		// write response
+		_ = s.requestContext(rq).WriteCode(w, code)
+
 		return
 	}
 
-	// issue tokens
-	r := s.issueTokens(false, rq.Scope, rq.ClientID, username, "")
+	// generate new authorization code
+	authorizationCode, err := s.generateCode()
+	if err != nil {
+		_ = s.requestContext(rq).WriteError(w, err)
+		return
+	}
 
-	// redirect token
-	r.SetRedirect(rq.RedirectURI, rq.State)
+	// save authorization code
+	s.AuthorizationCodes[authorizationCode.SignatureString()] = credential
 
 	// write response
-	_ = WriteTokenResponse(w, r)
+	_ = s.requestContext(rq).WriteCode(w, authorizationCode.String())
 }
 
-func (s *Server) handleAuthorizationCodeGrantAuthorization(w http.ResponseWriter, username, password string, rq *AuthorizationRequest) {
-	// validate scope
+func (s *Server) requestContext(req *AuthorizationRequest) *RequestContext {
+	// get context
+	ctx := req.RequestContext()
+	ctx.Strict = s.Config.StrictRedirectParameters
+	ctx.Redactor = s.redactor()
+
+	return ctx
+}
+
+func (s *Server) writeError(w http.ResponseWriter, err error) error {
+	// ensure complex error
+	var anError *Error
+	if !errors.As(err, &anError) {
+		anError = ServerError("").SetCause(err)
+	}
+
+	return WriteError(w, anError.Redact(s.redactor()))
+}
+
+func (s *Server) writeBearerError(w http.ResponseWriter, err error) error {
+	// redact complex errors
+	var anError *Error
+	if errors.As(err, &anError) {
+		err = anError.Redact(s.redactor())
+	}
+
+	return WriteBearerError(w, err)
+}
+
+// Stats returns the statistics of the handled token requests by grant type.
+// Requests with a missing or unknown grant type are counted as "unknown".
+func (s *Server) Stats() map[string]GrantStats {
+	return s.stats.snapshot()
+}
+
+// Exchanges returns the recorded HTTP exchanges, oldest first. Exchanges are
+// only recorded if enabled using the DebugExchanges option.
+func (s *Server) Exchanges() []ServerExchange {
+	return s.exchanges.snapshot()
+}
+
+func (s *Server) measureTokenEndpoint(w http.ResponseWriter, r *http.Request) {
+	// get start
+	start := time.Now()
+
+	// handle request
+	rec := &statusRecorder{ResponseWriter: w}
+	s.tokenEndpoint(rec, r)
+
+	// get grant type
+	grantType := r.PostForm.Get("grant_type")
+	if !KnownGrantType(grantType) && !extensionGrantType(grantType) {
+		grantType = "unknown"
+	}
+
+	// record request
+	s.stats.record(grantType, time.Since(start), rec.status >= 400)
+}
+
+func (s *Server) tokenEndpoint(w http.ResponseWriter, r *http.Request) {
+	// check tls
+	if err := s.checkTLS(r); err != nil {
+		_ = s.writeError(w, err)
+		return
+	}
+
+	// check multipart requests
+	multipart := IsMultipartRequest(r)
+	if multipart && !s.Config.AllowMultipartTokenRequests {
+		_ = s.writeError(w, InvalidRequest("multipart form data is not supported"))
+		return
+	}
+
+	// parse token request
+	var req *TokenRequest
+	var err error
+	if multipart {
+		req, err = ParseMultipartTokenRequest(r, 1<<20)
+	} else {
+		req, err = ParseTokenRequest(r)
+	}
+	if err != nil {
+		_ = s.writeError(w, err)
+		return
+	}
+
+	// make sure the grant type is known
+	if !KnownGrantType(req.GrantType) && (req.GrantType != GuestGrantType || s.Config.GuestScope.Empty()) &&
+		(req.GrantType != JWTBearerGrantType || s.Config.AssertionVerifier == nil) &&
+		req.GrantType != PreAuthorizedCodeGrantType {
+		_ = s.writeError(w, InvalidRequest("unknown grant type"))
+		return
+	}
+
+	// check client certificate
+	req.AuthMethod = s.certificateAuthMethod(r, req.ClientID, req.AuthMethod)
+
+	// check auth method
+	if !s.acceptsAuthMethod(req.AuthMethod) {
+		_ = s.writeError(w, s.invalidClient(req.AuthMethod, "unsupported client authentication method"))
+		return
+	}
+
+	// find client
+	client, found := s.Clients[req.ClientID]
+	if !found {
+		_ = s.writeError(w, s.invalidClient(req.AuthMethod, "unknown client"))
+		return
+	}
+
+	// check if client is disabled
+	if client.Disabled {
+		_ = s.writeError(w, s.invalidClient(req.AuthMethod, "disabled client"))
+		return
+	}
+
+	// authenticate client
+	if client.Confidential && !IsTLSClientAuthMethod(req.AuthMethod) && client.Secret != req.ClientSecret {
+		s.emit(ServerEvent{Type: AuthenticationFailedEvent, ClientID: req.ClientID, Reason: "invalid client credentials"})
+		_ = s.writeError(w, s.invalidClient(req.AuthMethod, "unknown client"))
+		return
+	}
+
+	// check client grant types
+	if len(client.GrantTypes) > 0 && !containsString(client.GrantTypes, req.GrantType) {
+		_ = s.writeError(w, UnauthorizedClient("grant type not allowed for client"))
+		return
+	}
+
+	// apply default scope
+	if req.Scope.Empty() && (req.GrantType == PasswordGrantType || req.GrantType == ClientCredentialsGrantType || req.GrantType == JWTBearerGrantType) {
+		req.Scope = s.Config.DefaultScope
+	}
+
+	// check client scope
+	if !client.AllowedScope.Empty() && !client.AllowedScope.Includes(req.Scope) {
+		_ = s.writeError(w, InvalidScope(""))
+		return
+	}
+
+	// check authorization details
+	if err := s.checkAuthorizationDetails(req.AuthorizationDetails); err != nil {
+		_ = s.writeError(w, err)
+		return
+	}
+
+	// validate scope strictly if enabled
+	if s.Config.StrictScope {
+		_, err = ParseStrictScope(r.PostForm.Get("scope"))
+		if err != nil {
+			_ = s.writeError(w, err)
+			return
+		}
+	}
+
+	// verify instance proof if enabled and available
+	if s.Config.InstanceProofs && req.InstanceProof != "" {
+		thumbprint, err := s.verifyInstanceProof(r, req.InstanceProof)
+		if err != nil {
+			_ = s.writeError(w, InvalidRequest("invalid instance proof").SetCause(err))
+			return
+		}
+
+		// add instance key to context
+		r = r.WithContext(context.WithValue(r.Context(), instanceKey{}, thumbprint))
+	}
+
+	// add certificate thumbprint to context if enabled
+	if s.Config.CertificateBoundTokens && IsTLSClientAuthMethod(req.AuthMethod) {
+		r = r.WithContext(context.WithValue(r.Context(), certificateKey{}, CertificateThumbprint(ClientCertificate(r))))
+	}
+
+	// handle grant type
+	switch req.GrantType {
+	case PasswordGrantType:
+		s.handleResourceOwnerPasswordCredentialsGrant(w, r, req)
+	case ClientCredentialsGrantType:
+		s.handleClientCredentialsGrant(w, r, req)
+	case AuthorizationCodeGrantType:
+		s.handleAuthorizationCodeGrant(w, r, req)
+	case RefreshTokenGrantType:
+		s.handleRefreshTokenGrant(w, r, req)
+	case GuestGrantType:
+		s.handleGuestGrant(w, r, req)
+	case JWTBearerGrantType:
+		s.handleJWTBearerGrant(w, r, req)
+	case PreAuthorizedCodeGrantType:
+		s.handlePreAuthorizedCodeGrant(w, r, req)
+	}
+}
+
+func (s *Server) handleResourceOwnerPasswordCredentialsGrant(w http.ResponseWriter, r *http.Request, rq *TokenRequest) {
+	// authenticate resource owner
+	owner, found := s.Users[rq.Username]
+	if !found || owner.Secret != rq.Password {
+		s.emit(ServerEvent{Type: AuthenticationFailedEvent, ClientID: rq.ClientID, Username: rq.Username, Reason: "invalid resource owner credentials"})
+		_ = s.writeError(w, AccessDenied(""))
+		return
+	}
+
+	// check scope
 	if !s.Config.AllowedScope.Includes(rq.Scope) {
-		_ = WriteError(w, InvalidScope("").SetRedirect(rq.RedirectURI, rq.State, false))
+		_ = s.writeError(w, InvalidScope(""))
 		return
 	}
 
-	// validate user credentials
-	owner, found := s.Users[username]
-	if !found || owner.Secret != password {
-		_ = WriteError(w, AccessDenied("").SetRedirect(rq.RedirectURI, rq.State, false))
+	// issue tokens
+	res, err := s.issueTokens(true, rq.Scope, rq.ClientID, rq.Username, "")
+	if err != nil {
+		_ = s.writeError(w, err)
 		return
 	}
 
-	// generate new authorization code
-	authorizationCode := s.Config.MustGenerate()
+	// grant authorization details
+	s.grantAuthorizationDetails(res, rq.AuthorizationDetails)
 
-	// prepare response
-	r := NewCodeResponse(authorizationCode.String(), rq.RedirectURI, rq.State)
+	// write response
+	_ = s.writeTokenResponse(w, r, res)
+}
+
+func (s *Server) handleClientCredentialsGrant(w http.ResponseWriter, r *http.Request, rq *TokenRequest) {
+	// check client confidentiality
+	if !s.Clients[rq.ClientID].Confidential {
+		_ = s.writeError(w, s.invalidClient(rq.AuthMethod, "unknown client"))
+		return
+	}
+
+	// check scope
+	if !s.Config.AllowedScope.Includes(rq.Scope) {
+		_ = s.writeError(w, InvalidScope(""))
+		return
+	}
+
+	// save tokens
+	res, err := s.issueTokens(true, rq.Scope, rq.ClientID, "", "")
+	if err != nil {
+		_ = s.writeError(w, err)
+		return
+	}
+
+	// grant authorization details
+	s.grantAuthorizationDetails(res, rq.AuthorizationDetails)
+
+	// write response
+	_ = s.writeTokenResponse(w, r, res)
+}
+
+func (s *Server) handleJWTBearerGrant(w http.ResponseWriter, r *http.Request, rq *TokenRequest) {
+	// check assertion
+	if rq.Assertion == "" {
+		_ = s.writeError(w, InvalidRequest("missing assertion"))
+		return
+	}
+
+	// verify assertion
+	claims, err := s.Config.AssertionVerifier.Verify(r.Context(), rq.Assertion)
+	if err != nil {
+		s.emit(ServerEvent{Type: AuthenticationFailedEvent, ClientID: rq.ClientID, Reason: "invalid assertion: " + err.Error()})
+		_ = s.writeError(w, InvalidGrant("invalid assertion").SetCause(err))
+		return
+	}
+
+	// check scope
+	if !s.Config.AllowedScope.Includes(rq.Scope) {
+		_ = s.writeError(w, InvalidScope(""))
+		return
+	}
+
+	// issue tokens
+	res, err := s.issueTokens(false, rq.Scope, rq.ClientID, claims.GetString("sub"), "")
+	if err != nil {
+		_ = s.writeError(w, err)
+		return
+	}
+
+	// grant authorization details
+	s.grantAuthorizationDetails(res, rq.AuthorizationDetails)
+
+	// write response
+	_ = s.writeTokenResponse(w, r, res)
+}
+
+func (s *Server) handleGuestGrant(w http.ResponseWriter, r *http.Request, rq *TokenRequest) {
+	// default to guest scope
+	scope := rq.Scope
+	if scope.Empty() {
+		scope = s.Config.GuestScope
+	}
+
+	// check scope
+	if !s.Config.GuestScope.Includes(scope) {
+		_ = s.writeError(w, InvalidScope(""))
+		return
+	}
+
+	// get time
+	now := s.now()
+
+	// check rate limit
+	if s.Config.GuestRateLimit > 0 {
+		// prepare map
+		if s.guestIssuance == nil {
+			s.guestIssuance = map[string][]time.Time{}
+		}
+
+		// keep issuances of the last minute
+		var recent []time.Time
+		for _, t := range s.guestIssuance[rq.ClientID] {
+			if t.After(now.Add(-time.Minute)) {
+				recent = append(recent, t)
+			}
+		}
+
+		// check limit
+		if len(recent) >= s.Config.GuestRateLimit {
+			s.guestIssuance[rq.ClientID] = recent
+			_ = s.writeError(w, TemporarilyUnavailable("guest rate limit exceeded"))
+			return
+		}
+
+		// record issuance
+		s.guestIssuance[rq.ClientID] = append(recent, now)
+	}
+
+	// get lifespan
+	lifespan := s.Config.GuestTokenLifespan
+	if lifespan <= 0 {
+		lifespan = 5 * time.Minute
+	}
+
+	// generate access token
+	accessToken, err := s.generateToken()
+	if err != nil {
+		_ = s.writeError(w, err)
+		return
+	}
+
+	// prepare response
+	res := NewBearerTokenResponse(accessToken.String(), int(lifespan/time.Second))
+	res.Scope = scope
+
+	// set issued at if enabled
+	if s.Config.ClockSkewSupport {
+		res.IssuedAt = now.Unix()
+	}
+
+	// save access token
+	credential := &ServerCredential{
+		ClientID:    rq.ClientID,
+		ExpiresAt:   now.Add(lifespan),
+		Scope:       scope,
+		Fingerprint: Fingerprint(res.AccessToken),
+	}
+	s.AccessTokens[accessToken.SignatureString()] = credential
+
+	// emit event
+	s.emit(ServerEvent{Type: TokenIssuedEvent, ClientID: rq.ClientID, TokenType: AccessToken, Scope: scope, Fingerprint: Fingerprint(res.AccessToken)})
+
+	// write response
+	_ = s.writeTokenResponse(w, r, &issuedTokens{TokenResponse: res, access: credential})
+}
+
+func (s *Server) handleAuthorizationCodeGrant(w http.ResponseWriter, r *http.Request, rq *TokenRequest) {
+	// get stored authorization code
+	storedAuthorizationCode, codeID, err := s.findAuthorizationCode(rq.Code)
+	if err != nil {
+		_ = s.writeError(w, err)
+		return
+	}
+
+	// check if used
+	if storedAuthorizationCode.Used {
+		// revoke all access tokens
+		for key, token := range s.AccessTokens {
+			if token.Code == codeID {
+				delete(s.AccessTokens, key)
+			}
+		}
+
+		// revoke all refresh tokens
+		for key, token := range s.RefreshTokens {
+			if token.Code == codeID {
+				delete(s.RefreshTokens, key)
+			}
+		}
+
+		_ = s.writeError(w, InvalidGrant("unknown authorization code"))
+		return
+	}
+
+	// validate expiration
+	if storedAuthorizationCode.ExpiresAt.Before(s.now()) {
+		_ = s.writeError(w, InvalidGrant("expired authorization code"))
+		return
+	}
+
+	// validate ownership
+	if storedAuthorizationCode.ClientID != rq.ClientID {
+		_ = s.writeError(w, InvalidGrant("invalid authorization code ownership"))
+		return
+	}
+
+	// validate redirect uri
+	if storedAuthorizationCode.RedirectURI != rq.RedirectURI {
+		_ = s.writeError(w, InvalidGrant("changed redirect uri"))
+		return
+	}
+
+	// validate code verifier
+	if storedAuthorizationCode.CodeChallenge != "" {
+		if !VerifyCodeChallenge(storedAuthorizationCode.CodeChallenge, storedAuthorizationCode.CodeChallengeMethod, rq.CodeVerifier) {
+			_ = s.writeError(w, InvalidGrant("invalid code verifier"))
+			return
+		}
+	} else if rq.CodeVerifier != "" {
+		_ = s.writeError(w, InvalidGrant("unexpected code verifier"))
+		return
+	}
+
+	// issue tokens
+	res, err := s.issueTokens(true, storedAuthorizationCode.Scope, rq.ClientID, storedAuthorizationCode.Username, codeID)
+	if err != nil {
+		_ = s.writeError(w, err)
+		return
+	}
+
+	// grant authorization details
+	s.grantAuthorizationDetails(res, storedAuthorizationCode.AuthorizationDetails)
+
+	// mark authorization code
+	storedAuthorizationCode.Used = true
+
+	// remember encrypted authorization code
+	if s.Config.EncryptedAuthorizationCodes {
+		s.UsedCodes[codeID] = storedAuthorizationCode.ExpiresAt
+	}
+
+	// write response
+	_ = s.writeTokenResponse(w, r, res)
+}
+
+func (s *Server) findAuthorizationCode(code string) (*ServerCredential, string, error) {
+	// handle encrypted authorization codes
+	if s.Config.EncryptedAuthorizationCodes {
+		return s.decryptAuthorizationCode(code)
+	}
+
+	// parse authorization code
+	authorizationCode, err := s.parseCode(code)
+	if err != nil {
+		return nil, "", InvalidRequest(err.Error())
+	}
+
+	// get stored authorization code by signature
+	storedAuthorizationCode, found := s.AuthorizationCodes[authorizationCode.SignatureString()]
+	if !found {
+		return nil, "", InvalidGrant("unknown authorization code")
+	}
+
+	return storedAuthorizationCode, authorizationCode.SignatureString(), nil
+}
+
+func (s *Server) encryptAuthorizationCode(credential *ServerCredential) (string, error) {
+	// encode credential
+	data, err := json.Marshal(credential)
+	if err != nil {
+		return "", err
+	}
+
+	// get keys
+	keys, err := s.codeEncryptionKeys()
+	if err != nil {
+		return "", err
+	}
+
+	// encrypt credential using the current key
+	data, err = seal(keys[0], data)
+	if err != nil {
+		return "", err
+	}
+
+	return b64.EncodeToString(data), nil
+}
+
+func (s *Server) decryptAuthorizationCode(code string) (*ServerCredential, string, error) {
+	// decode authorization code
+	data, err := b64.DecodeString(code)
+	if err != nil {
+		return nil, "", InvalidRequest("authorization code is not base64 encoded").SetCause(err)
+	}
+
+	// get keys
+	keys, err := s.codeEncryptionKeys()
+	if err != nil {
+		return nil, "", ServerError("").SetCause(err)
+	}
+
+	// decrypt authorization code using any valid key
+	var nonce, plaintext []byte
+	for _, key := range keys {
+		nonce, plaintext, err = open(key, data)
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, "", InvalidGrant("unknown authorization code").SetCause(err)
+	}
+
+	// decode credential
+	var credential ServerCredential
+	err = json.Unmarshal(plaintext, &credential)
+	if err != nil {
+		return nil, "", InvalidGrant("unknown authorization code").SetCause(err)
+	}
+
+	// get id
+	id := b64.EncodeToString(nonce)
+
+	// forget expired authorization codes
+	now := s.now()
+	for key, expiresAt := range s.UsedCodes {
+		if expiresAt.Before(now) {
+			delete(s.UsedCodes, key)
+		}
+	}
+
+	// check if used
+	_, credential.Used = s.UsedCodes[id]
+
+	return &credential, id, nil
+}
+
+// the label of keys derived for authorization code encryption
+const codeEncryptionLabel = "oauth2:authorization-code-encryption"
+
+func (s *Server) codeEncryptionKeys() ([][]byte, error) {
+	// use configured key
+	if len(s.Config.CodeEncryptionKey) > 0 {
+		return [][]byte{s.Config.CodeEncryptionKey}, nil
+	}
+
+	// derive keys from keyring, starting with the current key
+	if s.Config.Keyring != nil {
+		now := s.now()
+		current, err := s.Config.Keyring.Current(now)
+		if err != nil {
+			return nil, err
+		}
+		keys := [][]byte{deriveKey(current.Secret, codeEncryptionLabel)}
+		for _, key := range s.Config.Keyring.Valid(now) {
+			if key.ID != current.ID {
+				keys = append(keys, deriveKey(key.Secret, codeEncryptionLabel))
+			}
+		}
+		return keys, nil
+	}
+
+	// check secret
+	if len(s.Config.Secret) == 0 {
+		return nil, errors.New("missing code encryption key")
+	}
+
+	return [][]byte{deriveKey(s.Config.Secret, codeEncryptionLabel)}, nil
+}
+
+func (s *Server) handleRefreshTokenGrant(w http.ResponseWriter, r *http.Request, rq *TokenRequest) {
+	// parse refresh token
+	key, err := s.tokenKey(rq.RefreshToken)
+	if err != nil {
+		_ = s.writeError(w, InvalidRequest(err.Error()))
+		return
+	}
+
+	// get stored refresh token by signature
+	storedRefreshToken, found := s.RefreshTokens[key]
+	if !found {
+		_ = s.writeError(w, InvalidGrant("unknown refresh token"))
+		return
+	}
+
+	// validate revocation
+	if !storedRefreshToken.RevokedAt.IsZero() {
+		_ = s.writeError(w, InvalidGrant("revoked refresh token"))
+		return
+	}
+
+	// validate expiration
+	if storedRefreshToken.ExpiresAt.Before(s.now()) {
+		_ = s.writeError(w, InvalidGrant("expired refresh token"))
+		return
+	}
+
+	// validate ownership
+	if storedRefreshToken.ClientID != rq.ClientID && !s.isAlias(storedRefreshToken.ClientID, rq.ClientID) {
+		_ = s.writeError(w, InvalidGrant("invalid refresh token ownership"))
+		return
+	}
+
+	// validate instance key
+	if storedRefreshToken.InstanceKey != "" && storedRefreshToken.InstanceKey != s.instanceKey(r) {
+		_ = s.writeError(w, InvalidGrant("invalid instance proof"))
+		return
+	}
+
+	// check if used outside of grace period
+	if storedRefreshToken.Used && !s.now().Before(storedRefreshToken.UsedAt.Add(s.Config.RefreshTokenGracePeriod)) {
+		// revoke token family
+		for _, list := range []map[string]*ServerCredential{s.AccessTokens, s.RefreshTokens} {
+			for key, token := range list {
+				if token.Family == storedRefreshToken.Family {
+					delete(list, key)
+				}
+			}
+		}
+
+		_ = s.writeError(w, InvalidGrant("unknown refresh token"))
+		return
+	}
+
+	// check generation limit
+	if s.Config.MaxRefreshGenerations > 0 && storedRefreshToken.Generation >= s.Config.MaxRefreshGenerations {
+		_ = s.writeError(w, InvalidGrant("refresh token generation limit reached"))
+		return
+	}
+
+	// inherit scope from stored refresh token
+	if rq.Scope.Empty() {
+		rq.Scope = storedRefreshToken.Scope
+	}
+
+	// validate scope - a missing scope is always included
+	if !storedRefreshToken.Scope.Includes(rq.Scope) {
+		_ = s.writeError(w, InvalidScope("scope exceeds the originally granted scope"))
+		return
+	}
+
+	// inherit authorization details from stored refresh token
+	if len(rq.AuthorizationDetails) == 0 {
+		rq.AuthorizationDetails = storedRefreshToken.AuthorizationDetails
+	}
+
+	// validate authorization details
+	if !IncludesAuthorizationDetails(storedRefreshToken.AuthorizationDetails, rq.AuthorizationDetails) {
+		_ = s.writeError(w, InvalidAuthorizationDetails("authorization details exceed the originally granted authorization details"))
+		return
+	}
+
+	// issue tokens
+	res, err := s.issueTokens(true, rq.Scope, rq.ClientID, storedRefreshToken.Username, "")
+	if err != nil {
+		_ = s.writeError(w, err)
+		return
+	}
+
+	// grant authorization details
+	s.grantAuthorizationDetails(res, rq.AuthorizationDetails)
+
+	// set generation of issued tokens
+	s.setGeneration(res, storedRefreshToken.Generation+1)
+
+	// retain or delete used refresh token
+	if s.Config.RefreshTokenReuseDetection {
+		s.retainRefreshToken(key, storedRefreshToken, res)
+	} else {
+		delete(s.RefreshTokens, key)
+	}
+
+	// write response
+	_ = s.writeTokenResponse(w, r, res)
+}
+
+func (s *Server) retainRefreshToken(key string, storedRefreshToken *ServerCredential, res *issuedTokens) {
+	// determine family
+	family := storedRefreshToken.Family
+	if family == "" {
+		family = key
+	}
+
+	// set family of issued tokens
+	res.access.Family = family
+	if res.refresh != nil {
+		res.refresh.Family = family
+	}
+
+	// mark used refresh token
+	storedRefreshToken.Family = family
+	if !storedRefreshToken.Used {
+		storedRefreshToken.Used = true
+		storedRefreshToken.UsedAt = s.now()
+	}
+}
+
+func (s *Server) setGeneration(res *issuedTokens, generation int) {
+	// set generation of issued tokens
+	res.access.Generation = generation
+	if res.refresh != nil {
+		res.refresh.Generation = generation
+	}
+}
+
+func (s *Server) isAlias(previousID, id string) bool {
+	// get alias
+	alias, ok := s.ClientAliases[previousID]
+	if !ok || alias.ClientID != id {
+		return false
+	}
+
+	// check expiry
+	if !alias.ExpiresAt.IsZero() && alias.ExpiresAt.Before(s.now()) {
+		return false
+	}
+
+	return true
+}
+
+func (s *Server) revocationEndpoint(w http.ResponseWriter, r *http.Request) {
+	// parse authorization request
+	req, err := ParseRevocationRequest(r)
+	if err != nil {
+		_ = s.writeError(w, err)
+		return
+	}
+
+	// check token type hint
+	_, err = ParseTokenTypeHint(req.TokenTypeHint)
+	if err != nil {
+		_ = s.writeError(w, err)
+		return
+	}
+
+	// check client certificate
+	req.AuthMethod = s.certificateAuthMethod(r, req.ClientID, req.AuthMethod)
+
+	// check auth method
+	if !s.acceptsAuthMethod(req.AuthMethod) {
+		_ = s.writeError(w, s.invalidClient(req.AuthMethod, "unsupported client authentication method"))
+		return
+	}
+
+	// get client
+	client, found := s.Clients[req.ClientID]
+	if !found {
+		_ = s.writeError(w, s.invalidClient(req.AuthMethod, "unknown client"))
+		return
+	}
+
+	// authenticate client
+	if client.Confidential && !IsTLSClientAuthMethod(req.AuthMethod) && client.Secret != req.ClientSecret {
+		s.emit(ServerEvent{Type: AuthenticationFailedEvent, ClientID: req.ClientID, Reason: "invalid client credentials"})
+		_ = s.writeError(w, s.invalidClient(req.AuthMethod, "unknown client"))
+		return
+	}
+
+	// prepare task
+	task := RevocationTask{
+		ClientID:      req.ClientID,
+		Token:         req.Token,
+		TokenTypeHint: req.TokenTypeHint,
+	}
+
+	// enqueue task if a queue is configured
+	if s.Config.RevocationQueue != nil {
+		err = s.Config.RevocationQueue.Enqueue(task)
+		if err != nil {
+			_ = s.writeError(w, TemporarilyUnavailable(err.Error()))
+			return
+		}
+
+		// write response
+		_ = WriteRevocationResponse(w)
+
+		return
+	}
+
+	// revoke token
+	err = s.revoke(task)
+	if err != nil {
+		_ = s.writeError(w, err)
+		return
+	}
+
+	// write response
+	_ = WriteRevocationResponse(w)
+}
+
+type instanceKey struct{}
+
+type certificateKey struct{}
+
+func (s *Server) instanceKey(r *http.Request) string {
+	key, _ := r.Context().Value(instanceKey{}).(string)
+	return key
+}
+
+func (s *Server) verifyInstanceProof(r *http.Request, str string) (string, error) {
+	// parse proof
+	proof, err := ParseInstanceProof(str)
+	if err != nil {
+		return "", err
+	}
+
+	// check proof
+	err = s.proofChecker.Check(proof, r.Method, ProofURL(r, s.secureRequest(r)), s.now(), 0)
+	if err != nil {
+		return "", err
+	}
+
+	return proof.Thumbprint, nil
+}
+
+func (s *Server) certificateAuthMethod(r *http.Request, clientID string, method ClientAuthMethod) ClientAuthMethod {
+	// check method
+	if method != NoClientAuth {
+		return method
+	}
+
+	// get client
+	client, found := s.Clients[clientID]
+	if !found {
+		return method
+	}
 
-	// save authorization code
-	s.AuthorizationCodes[authorizationCode.SignatureString()] = &ServerCredential{
-		ClientID:    rq.ClientID,
-		Username:    username,
-		ExpiresAt:   time.Now().Add(s.Config.AuthorizationCodeLifespan),
-		Scope:       rq.Scope,
-		RedirectURI: rq.RedirectURI,
+	// match certificate
+	tlsMethod, ok := MatchClientCertificate(ClientCertificate(r), VerifiedClientCertificate(r), client.TLSSubject, client.TLSCertificates)
+	if !ok {
+		return method
 	}
 
-	// write response
-	_ = WriteCodeResponse(w, r)
+	return tlsMethod
 }
 
-func (s *Server) tokenEndpoint(w http.ResponseWriter, r *http.Request) {
-	// parse token request
-	req, err := ParseTokenRequest(r)
-	if err != nil {
-		_ = WriteError(w, err)
-		return
+func (s *Server) acceptsAuthMethod(method ClientAuthMethod) bool {
+	// check default
+	if len(s.Config.ClientAuthMethods) == 0 {
+		return true
 	}
 
-	// make sure the grant type is known
-	if !KnownGrantType(req.GrantType) {
-		_ = WriteError(w, InvalidRequest("unknown grant type"))
-		return
+	// check methods
+	for _, m := range s.Config.ClientAuthMethods {
+		if m == method {
+			return true
+		}
 	}
 
-	// find client
-	client, found := s.Clients[req.ClientID]
-	if !found {
-		_ = WriteError(w, InvalidClient("unknown client"))
-		return
-	}
+	return false
+}
 
-	// authenticate client
-	if client.Confidential && client.Secret != req.ClientSecret {
-		_ = WriteError(w, InvalidClient("unknown client"))
-		return
-	}
+func (s *Server) invalidClient(method ClientAuthMethod, description string) *Error {
+	// prepare error
+	err := InvalidClient(description)
 
-	// handle grant type
-	switch req.GrantType {
-	case PasswordGrantType:
-		s.handleResourceOwnerPasswordCredentialsGrant(w, req)
-	case ClientCredentialsGrantType:
-		s.handleClientCredentialsGrant(w, req)
-	case AuthorizationCodeGrantType:
-		s.handleAuthorizationCodeGrant(w, req)
-	case RefreshTokenGrantType:
-		s.handleRefreshTokenGrant(w, req)
+	// only challenge clients that used or may use basic authentication
+	if method == ClientSecretPost || IsTLSClientAuthMethod(method) || (method == NoClientAuth && !s.acceptsAuthMethod(ClientSecretBasic)) {
+		err.Status = http.StatusBadRequest
+		err.Headers = nil
 	}
+
+	return err
 }
 
-func (s *Server) handleResourceOwnerPasswordCredentialsGrant(w http.ResponseWriter, rq *TokenRequest) {
-	// authenticate resource owner
-	owner, found := s.Users[rq.Username]
-	if !found || owner.Secret != rq.Password {
-		_ = WriteError(w, AccessDenied(""))
-		return
+func (s *Server) revoke(task RevocationTask) error {
+	// parse token
+	key, err := s.tokenKey(task.Token)
+	if err != nil {
+		return InvalidRequest(err.Error())
 	}
 
-	// check scope
-	if !s.Config.AllowedScope.Includes(rq.Scope) {
-		_ = WriteError(w, InvalidScope(""))
-		return
+	// find token
+	credential, typ := s.findToken(key, TokenTypeHint(task.TokenTypeHint))
+	if credential == nil || !credential.RevokedAt.IsZero() {
+		return nil
 	}
 
-	// issue tokens
-	r := s.issueTokens(true, rq.Scope, rq.ClientID, rq.Username, "")
+	// check owner
+	if credential.ClientID != task.ClientID {
+		return InvalidClient("wrong client")
+	}
 
-	// write response
-	_ = WriteTokenResponse(w, r)
+	// revoke token
+	s.revokeToken(task.ClientID, s.tokenList(typ), key, s.Config.DistinguishRevokedTokens)
+
+	// emit event
+	s.emit(ServerEvent{Type: TokenRevokedEvent, ClientID: credential.ClientID, Username: credential.Username, TokenType: string(typ), Scope: credential.Scope, Fingerprint: Fingerprint(task.Token)})
+
+	return nil
 }
 
-func (s *Server) handleClientCredentialsGrant(w http.ResponseWriter, rq *TokenRequest) {
-	// check client confidentiality
-	if !s.Clients[rq.ClientID].Confidential {
-		_ = WriteError(w, InvalidClient("unknown client"))
+func (s *Server) introspectionEndpoint(w http.ResponseWriter, r *http.Request) {
+	// parse authorization request
+	req, err := ParseIntrospectionRequest(r)
+	if err != nil {
+		_ = s.writeError(w, err)
 		return
 	}
 
-	// check scope
-	if !s.Config.AllowedScope.Includes(rq.Scope) {
-		_ = WriteError(w, InvalidScope(""))
+	// check token type hint
+	hint, err := ParseTokenTypeHint(req.TokenTypeHint)
+	if err != nil {
+		_ = s.writeError(w, err)
 		return
 	}
 
-	// save tokens
-	r := s.issueTokens(true, rq.Scope, rq.ClientID, "", "")
+	// authenticate caller
+	privileged := req.BearerToken != ""
+	if privileged {
+		if !s.authenticateIntrospection(w, req.BearerToken) {
+			return
+		}
+	} else {
+		// check client certificate
+		req.AuthMethod = s.certificateAuthMethod(r, req.ClientID, req.AuthMethod)
 
-	// write response
-	_ = WriteTokenResponse(w, r)
-}
+		// check auth method
+		if !s.acceptsAuthMethod(req.AuthMethod) {
+			_ = s.writeError(w, s.invalidClient(req.AuthMethod, "unsupported client authentication method"))
+			return
+		}
 
-func (s *Server) handleAuthorizationCodeGrant(w http.ResponseWriter, rq *TokenRequest) {
-	// parse authorization code
-	authorizationCode, err := ParseHS256Token(s.Config.Secret, rq.Code)
-	if err != nil {
-		_ = WriteError(w, InvalidRequest(err.Error()))
-		return
+		// get client
+		client, found := s.Clients[req.ClientID]
+		if !found {
+			_ = s.writeError(w, s.invalidClient(req.AuthMethod, "unknown client"))
+			return
+		}
+
+		// authenticate client
+		if client.Confidential && !IsTLSClientAuthMethod(req.AuthMethod) && client.Secret != req.ClientSecret {
+			s.emit(ServerEvent{Type: AuthenticationFailedEvent, ClientID: req.ClientID, Reason: "invalid client credentials"})
+			_ = s.writeError(w, s.invalidClient(req.AuthMethod, "unknown client"))
+			return
+		}
 	}
 
-	// get stored authorization code by signature
-	storedAuthorizationCode, found := s.AuthorizationCodes[authorizationCode.SignatureString()]
-	if !found {
-		_ = WriteError(w, InvalidGrant("unknown authorization code"))
+	// parse token
+	key, err := s.tokenKey(req.Token)
+	if err != nil {
+		_ = s.writeError(w, InvalidRequest(err.Error()))
 		return
 	}
 
-	// check if used
-	if storedAuthorizationCode.Used {
-		// revoke all access tokens
-		for key, token := range s.AccessTokens {
-			if token.Code == authorizationCode.SignatureString() {
-				delete(s.AccessTokens, key)
-			}
+	// prepare response
+	res := &IntrospectionResponse{}
+
+	// find token
+	if credential, typ := s.findToken(key, hint); credential != nil && !credential.RevokedAt.IsZero() {
+		// check owner
+		if !privileged && credential.ClientID != req.ClientID {
+			_ = s.writeError(w, InvalidClient("wrong client"))
+			return
 		}
 
-		// revoke all refresh tokens
-		for key, token := range s.RefreshTokens {
-			if token.Code == authorizationCode.SignatureString() {
-				delete(s.RefreshTokens, key)
+		// set revocation time
+		res.RevokedAt = credential.RevokedAt.Unix()
+	} else if credential != nil && !credential.Used {
+		// check owner
+		if !privileged && credential.ClientID != req.ClientID {
+			_ = s.writeError(w, InvalidClient("wrong client"))
+			return
+		}
+
+		// set response
+		res.Active = true
+		res.Scope = credential.Scope.String()
+		res.ClientID = credential.ClientID
+		res.Username = credential.Username
+		res.TokenType = string(typ)
+		res.ExpiresAt = credential.ExpiresAt.Unix()
+		res.AuthorizationDetails = credential.AuthorizationDetails
+
+		// set confirmation
+		if credential.CertificateThumbprint != "" {
+			res.Confirmation = &Confirmation{
+				CertificateThumbprint: credential.CertificateThumbprint,
 			}
 		}
+	}
 
-		_ = WriteError(w, InvalidGrant("unknown authorization code"))
-		return
+	// write response
+	_ = WriteIntrospectionResponse(w, res)
+}
+
+// findToken will look up the token in the list indicated by the hint first and
+// fall back to the other list.
+func (s *Server) findToken(key string, hint TokenTypeHint) (*ServerCredential, TokenTypeHint) {
+	// determine order
+	order := []TokenTypeHint{AccessTokenHint, RefreshTokenHint}
+	if hint == RefreshTokenHint {
+		order = []TokenTypeHint{RefreshTokenHint, AccessTokenHint}
 	}
 
-	// validate expiration
-	if storedAuthorizationCode.ExpiresAt.Before(time.Now()) {
-		_ = WriteError(w, InvalidGrant("expired authorization code"))
-		return
+	// find token
+	for _, typ := range order {
+		if credential, found := s.tokenList(typ)[key]; found {
+			return credential, typ
+		}
 	}
 
-	// validate ownership
-	if storedAuthorizationCode.ClientID != rq.ClientID {
-		_ = WriteError(w, InvalidGrant("invalid authorization code ownership"))
-		return
+	return nil, ""
+}
+
+func (s *Server) tokenList(typ TokenTypeHint) map[string]*ServerCredential {
+	if typ == RefreshTokenHint {
+		return s.RefreshTokens
 	}
 
-	// validate redirect uri
-	if storedAuthorizationCode.RedirectURI != rq.RedirectURI {
-		_ = WriteError(w, InvalidGrant("changed redirect uri"))
+	return s.AccessTokens
+}
+
+func (s *Server) timeEndpoint(w http.ResponseWriter, r *http.Request) {
+	// check if enabled
+	if !s.Config.ClockSkewSupport {
+		http.NotFound(w, r)
 		return
 	}
 
-	// issue tokens
-	r := s.issueTokens(true, storedAuthorizationCode.Scope, rq.ClientID, storedAuthorizationCode.Username, authorizationCode.SignatureString())
-
-	// mark authorization code
-	storedAuthorizationCode.Used = true
+	// get time
+	now := s.now()
 
 	// write response
-	_ = WriteTokenResponse(w, r)
+	_ = Write(w, map[string]interface{}{
+		"time":    now.Unix(),
+		"rfc3339": now.UTC().Format(time.RFC3339Nano),
+	}, http.StatusOK)
 }
 
-func (s *Server) handleRefreshTokenGrant(w http.ResponseWriter, rq *TokenRequest) {
-	// parse refresh token
-	refreshToken, err := ParseHS256Token(s.Config.Secret, rq.RefreshToken)
-	if err != nil {
-		_ = WriteError(w, InvalidRequest(err.Error()))
-		return
+func (s *Server) authenticateIntrospection(w http.ResponseWriter, bearerToken string) bool {
+	// check if enabled
+	if s.Config.IntrospectionScope.Empty() {
+		_ = s.writeBearerError(w, InvalidToken("bearer authentication not supported"))
+		return false
 	}
 
-	// get stored refresh token by signature
-	storedRefreshToken, found := s.RefreshTokens[refreshToken.SignatureString()]
-	if !found {
-		_ = WriteError(w, InvalidGrant("unknown refresh token"))
-		return
+	// parse token
+	key, err := s.tokenKey(bearerToken)
+	if err != nil {
+		_ = s.writeBearerError(w, InvalidToken("malformed token"))
+		return false
 	}
 
-	// validate expiration
-	if storedRefreshToken.ExpiresAt.Before(time.Now()) {
-		_ = WriteError(w, InvalidGrant("expired refresh token"))
-		return
+	// get token
+	accessToken, found := s.AccessTokens[key]
+	if !found {
+		_ = s.writeBearerError(w, InvalidToken("unknown token"))
+		return false
 	}
 
-	// validate ownership
-	if storedRefreshToken.ClientID != rq.ClientID {
-		_ = WriteError(w, InvalidGrant("invalid refresh token ownership"))
-		return
+	// validate revocation
+	if !accessToken.RevokedAt.IsZero() {
+		_ = s.writeBearerError(w, InvalidToken("revoked token"))
+		return false
 	}
 
-	// inherit scope from stored refresh token
-	if rq.Scope.Empty() {
-		rq.Scope = storedRefreshToken.Scope
+	// validate expiration
+	if accessToken.ExpiresAt.Before(s.now()) {
+		_ = s.writeBearerError(w, InvalidToken("expired token"))
+		return false
 	}
 
-	// validate scope - a missing scope is always included
-	if !storedRefreshToken.Scope.Includes(rq.Scope) {
-		_ = WriteError(w, InvalidScope("scope exceeds the originally granted scope"))
-		return
+	// validate scope
+	if !accessToken.Scope.IncludesWith(s.Config.IntrospectionScope, s.Config.ScopeMatcher) {
+		_ = s.writeBearerError(w, InsufficientScope(s.Config.IntrospectionScope.String()))
+		return false
 	}
 
-	// issue tokens
-	r := s.issueTokens(true, rq.Scope, rq.ClientID, storedRefreshToken.Username, "")
+	return true
+}
 
-	// delete used refresh token
-	delete(s.RefreshTokens, refreshToken.SignatureString())
+type serverToken interface {
+	String() string
+	SignatureString() string
+}
 
-	// write response
-	_ = WriteTokenResponse(w, r)
+func (s *Server) generateToken() (serverToken, error) {
+	return s.generateTokenWithLength(s.Config.KeyLength)
 }
 
-func (s *Server) revocationEndpoint(w http.ResponseWriter, r *http.Request) {
-	// parse authorization request
-	req, err := ParseRevocationRequest(r)
+func (s *Server) generateTokenWithLength(length int) (serverToken, error) {
+	// use signer if configured
+	if s.Config.TokenSigner != nil {
+		return GenerateSignedToken(s.Config.TokenSigner, length)
+	}
+
+	// use secret if no keyring is configured
+	if s.Config.Keyring == nil {
+		return GenerateHS256Token(s.Config.Secret, length)
+	}
+
+	// get current key
+	key, err := s.Config.Keyring.Current(s.now())
 	if err != nil {
-		_ = WriteError(w, err)
-		return
+		return nil, err
 	}
 
-	// check token type hint
-	if req.TokenTypeHint != "" && !KnownTokenType(req.TokenTypeHint) {
-		_ = WriteError(w, UnsupportedTokenType(""))
-		return
+	return GenerateHS256Token(key.Secret, length)
+}
+
+func (s *Server) generateCode() (serverToken, error) {
+	// get length
+	length := s.Config.CodeKeyLength
+	if length == 0 {
+		length = s.Config.KeyLength
 	}
 
-	// get client
-	client, found := s.Clients[req.ClientID]
-	if !found {
-		_ = WriteError(w, InvalidClient("unknown client"))
-		return
+	// use token format if no hash is configured
+	if s.Config.CodeHash == 0 {
+		return s.generateTokenWithLength(length)
 	}
 
-	// authenticate client
-	if client.Confidential && client.Secret != req.ClientSecret {
-		_ = WriteError(w, InvalidClient("unknown client"))
-		return
+	// use secret if no keyring is configured
+	if s.Config.Keyring == nil {
+		return GenerateHMACToken(s.Config.CodeHash, s.Config.Secret, length)
 	}
 
-	// parse token
-	token, err := ParseHS256Token(s.Config.Secret, req.Token)
+	// get current key
+	key, err := s.Config.Keyring.Current(s.now())
 	if err != nil {
-		_ = WriteError(w, InvalidRequest(err.Error()))
-		return
+		return nil, err
 	}
 
-	// check access token
-	if accessToken, found := s.AccessTokens[token.SignatureString()]; found {
-		// check owner
-		if accessToken.ClientID != req.ClientID {
-			_ = WriteError(w, InvalidClient("wrong client"))
-			return
-		}
+	return GenerateHMACToken(s.Config.CodeHash, key.Secret, length)
+}
 
-		// revoke token
-		s.revokeToken(req.ClientID, s.AccessTokens, token.SignatureString())
+func (s *Server) parseCode(str string) (serverToken, error) {
+	// use token format if no hash is configured
+	if s.Config.CodeHash == 0 {
+		return s.parseToken(str)
 	}
 
-	// check refresh token
-	if refreshToken, found := s.RefreshTokens[token.SignatureString()]; found {
-		// check owner
-		if refreshToken.ClientID != req.ClientID {
-			_ = WriteError(w, InvalidClient("wrong client"))
-			return
+	// use secret if no keyring is configured
+	if s.Config.Keyring == nil {
+		return ParseHMACToken(s.Config.CodeHash, s.Config.Secret, str)
+	}
+
+	// try all valid keys
+	var err error
+	for _, key := range s.Config.Keyring.Valid(s.now()) {
+		var token *HMACToken
+		token, err = ParseHMACToken(s.Config.CodeHash, key.Secret, str)
+		if err == nil {
+			return token, nil
 		}
+	}
 
-		// revoke token
-		s.revokeToken(req.ClientID, s.RefreshTokens, token.SignatureString())
+	// ensure error
+	if err == nil {
+		err = errors.New("no valid key")
 	}
 
-	// write header
-	w.WriteHeader(http.StatusOK)
+	return nil, err
 }
 
-func (s *Server) introspectionEndpoint(w http.ResponseWriter, r *http.Request) {
-	// parse authorization request
-	req, err := ParseIntrospectionRequest(r)
-	if err != nil {
-		_ = WriteError(w, err)
-		return
+func (s *Server) parseToken(str string) (serverToken, error) {
+	// use signer if configured
+	if s.Config.TokenSigner != nil {
+		return ParseSignedToken(s.Config.TokenSigner.Public(), str)
 	}
 
-	// check token type hint
-	if req.TokenTypeHint != "" && !KnownTokenType(req.TokenTypeHint) {
-		_ = WriteError(w, UnsupportedTokenType(""))
-		return
+	// use secret if no keyring is configured
+	if s.Config.Keyring == nil {
+		return ParseHS256Token(s.Config.Secret, str)
 	}
 
-	// get client
-	client, found := s.Clients[req.ClientID]
-	if !found {
-		_ = WriteError(w, InvalidClient("unknown client"))
-		return
+	// try all valid keys
+	var err error
+	for _, key := range s.Config.Keyring.Valid(s.now()) {
+		var token *HS256Token
+		token, err = ParseHS256Token(key.Secret, str)
+		if err == nil {
+			return token, nil
+		}
 	}
 
-	// authenticate client
-	if client.Confidential && client.Secret != req.ClientSecret {
-		_ = WriteError(w, InvalidClient("unknown client"))
-		return
+	// ensure error
+	if err == nil {
+		err = errors.New("no valid key")
 	}
 
+	return nil, err
+}
+
+func (s *Server) tokenKey(str string) (string, error) {
 	// parse token
-	token, err := ParseHS256Token(s.Config.Secret, req.Token)
-	if err != nil {
-		_ = WriteError(w, InvalidRequest(err.Error()))
-		return
+	token, err := s.parseToken(str)
+	if err == nil {
+		return token.SignatureString(), nil
 	}
 
-	// prepare response
-	res := &IntrospectionResponse{}
+	// check imported tokens
+	key := importedTokenKey(str)
+	if _, ok := s.AccessTokens[key]; ok {
+		return key, nil
+	} else if _, ok := s.RefreshTokens[key]; ok {
+		return key, nil
+	}
 
-	// check access token
-	if accessToken, found := s.AccessTokens[token.SignatureString()]; found {
-		// check owner
-		if accessToken.ClientID != req.ClientID {
-			_ = WriteError(w, InvalidClient("wrong client"))
-			return
-		}
+	return "", err
+}
 
-		// set response
-		res.Active = true
-		res.Scope = accessToken.Scope.String()
-		res.ClientID = accessToken.ClientID
-		res.Username = accessToken.Username
-		res.TokenType = AccessToken
-		res.ExpiresAt = accessToken.ExpiresAt.Unix()
+func (s *Server) writeTokenResponse(w http.ResponseWriter, r *http.Request, res *issuedTokens) error {
+	// bind refresh token to instance key if available
+	if key := s.instanceKey(r); key != "" && res.refresh != nil {
+		res.refresh.InstanceKey = key
 	}
 
-	// check refresh token
-	if refreshToken, found := s.RefreshTokens[token.SignatureString()]; found {
-		// check owner
-		if refreshToken.ClientID != req.ClientID {
-			_ = WriteError(w, InvalidClient("wrong client"))
-			return
-		}
+	// bind access token to certificate if available
+	if thumbprint, _ := r.Context().Value(certificateKey{}).(string); thumbprint != "" {
+		res.access.CertificateThumbprint = thumbprint
+	}
 
-		// set response
-		res.Active = true
-		res.Scope = refreshToken.Scope.String()
-		res.ClientID = refreshToken.ClientID
-		res.Username = refreshToken.Username
-		res.TokenType = RefreshToken
-		res.ExpiresAt = refreshToken.ExpiresAt.Unix()
+	// write signed response if accepted
+	if s.Config.ResponseSigningKey != nil && strings.Contains(r.Header.Get("Accept"), JWTContentType) {
+		return WriteSignedTokenResponse(w, res.TokenResponse, s.Config.ResponseSigningKey)
 	}
 
-	// write response
-	_ = WriteIntrospectionResponse(w, res)
+	// write form response if accepted
+	if accept := r.Header.Get("Accept"); s.Config.FormTokenResponses && strings.Contains(accept, FormContentType) && !strings.Contains(accept, "application/json") {
+		return WriteFormTokenResponse(w, res.TokenResponse)
+	}
+
+	return WriteTokenResponse(w, res.TokenResponse)
 }
 
-func (s *Server) issueTokens(issueRefreshToken bool, scope Scope, clientID, username, code string) *TokenResponse {
+// issuedTokens is a token response together with the stored credentials of the
+// issued tokens.
+type issuedTokens struct {
+	*TokenResponse
+	access  *ServerCredential
+	refresh *ServerCredential
+}
+
+func (s *Server) issueTokens(issueRefreshToken bool, scope Scope, clientID, username, code string) (*issuedTokens, error) {
+	// check offline access
+	if s.Config.RequireOfflineAccess && !scope.Contains(OfflineAccessScope) {
+		issueRefreshToken = false
+	}
+
+	// get lifespans
+	accessTokenLifespan := s.Config.AccessTokenLifespan
+	refreshTokenLifespan := s.Config.RefreshTokenLifespan
+	if client := s.Clients[clientID]; client != nil {
+		if client.AccessTokenLifespan > 0 {
+			accessTokenLifespan = client.AccessTokenLifespan
+		}
+		if client.RefreshTokenLifespan > 0 {
+			refreshTokenLifespan = client.RefreshTokenLifespan
+		}
+	}
+
 	// generate access token
-	accessToken := s.Config.MustGenerate()
+	accessToken, err := s.generateToken()
+	if err != nil {
+		return nil, err
+	}
 
 	// generate refresh token if requested
-	var refreshToken *HS256Token
+	var refreshToken serverToken
 	if issueRefreshToken {
-		refreshToken = s.Config.MustGenerate()
+		refreshToken, err = s.generateToken()
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	// prepare response
-	r := NewBearerTokenResponse(accessToken.String(), int(s.Config.AccessTokenLifespan/time.Second))
+	r := NewBearerTokenResponse(accessToken.String(), int(accessTokenLifespan/time.Second))
 
 	// set granted scope
 	r.Scope = scope
 
-	// set refresh token if available
+	// set issued at if enabled
+	if s.Config.ClockSkewSupport {
+		r.IssuedAt = s.now().Unix()
+	}
+
+	// set refresh token and family if available
+	var family string
 	if refreshToken != nil {
 		r.RefreshToken = refreshToken.String()
+		family = refreshToken.SignatureString()
 	}
 
+	// prepare result
+	issued := &issuedTokens{TokenResponse: r}
+
 	// save access token
-	s.AccessTokens[accessToken.SignatureString()] = &ServerCredential{
-		ClientID:  clientID,
-		Username:  username,
-		ExpiresAt: time.Now().Add(s.Config.AccessTokenLifespan),
-		Scope:     scope,
-		Code:      code,
+	issued.access = &ServerCredential{
+		ClientID:    clientID,
+		Username:    username,
+		ExpiresAt:   s.now().Add(accessTokenLifespan),
+		Scope:       scope,
+		Code:        code,
+		Family:      family,
+		Fingerprint: Fingerprint(r.AccessToken),
 	}
+	s.AccessTokens[accessToken.SignatureString()] = issued.access
 
 	// save refresh token if available
 	if refreshToken != nil {
-		s.RefreshTokens[refreshToken.SignatureString()] = &ServerCredential{
-			ClientID:  clientID,
-			Username:  username,
-			ExpiresAt: time.Now().Add(s.Config.RefreshTokenLifespan),
-			Scope:     scope,
-			Code:      code,
+		issued.refresh = &ServerCredential{
+			ClientID:    clientID,
+			Username:    username,
+			ExpiresAt:   s.now().Add(refreshTokenLifespan),
+			Scope:       scope,
+			Code:        code,
+			Family:      family,
+			Fingerprint: Fingerprint(r.RefreshToken),
+		}
+		s.RefreshTokens[refreshToken.SignatureString()] = issued.refresh
+	}
+
+	// emit events
+	s.emit(ServerEvent{Type: TokenIssuedEvent, ClientID: clientID, Username: username, TokenType: AccessToken, Scope: scope, Fingerprint: Fingerprint(r.AccessToken)})
+	if refreshToken != nil {
+		s.emit(ServerEvent{Type: TokenIssuedEvent, ClientID: clientID, Username: username, TokenType: RefreshToken, Scope: scope, Fingerprint: Fingerprint(r.RefreshToken)})
+	}
+
+	return issued, nil
+}
+
+func (s *Server) checkAuthorizationDetails(details []AuthorizationDetail) *Error {
+	// check types
+	for _, detail := range details {
+		if !containsString(s.Config.AuthorizationDetailTypes, detail.Type) {
+			return InvalidAuthorizationDetails("unsupported authorization detail type")
 		}
 	}
 
-	return r
+	return nil
+}
+
+func (s *Server) grantAuthorizationDetails(res *issuedTokens, details []AuthorizationDetail) {
+	// check details
+	if len(details) == 0 {
+		return
+	}
+
+	// set response details
+	res.AuthorizationDetails = details
+
+	// set token details
+	res.access.AuthorizationDetails = details
+	if res.refresh != nil {
+		res.refresh.AuthorizationDetails = details
+	}
 }
 
-func (s *Server) revokeToken(clientID string, list map[string]*ServerCredential, signature string) {
+func (s *Server) revokeToken(clientID string, list map[string]*ServerCredential, signature string, retain bool) {
 	// get token
 	token, ok := list[signature]
 	if !ok {
@@ -639,6 +2697,28 @@ func (s *Server) revokeToken(clientID string, list map[string]*ServerCredential,
 		return
 	}
 
+	// mark token as revoked if it should be retained
+	if retain {
+		if token.RevokedAt.IsZero() {
+			token.RevokedAt = s.now()
+		}
+		return
+	}
+
 	// remove token
 	delete(list, signature)
 }
+
+type redirectStatusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *redirectStatusWriter) WriteHeader(status int) {
+	// replace redirect status
+	if status == http.StatusSeeOther {
+		status = w.status
+	}
+
+	w.ResponseWriter.WriteHeader(status)
+}
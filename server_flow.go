@@ -0,0 +1,145 @@
+package oauth2
+
+import (
+	"net/http"
+	"time"
+)
+
+// AuthorizationFlowCookie is the cookie that carries the flow ID if consent is
+// a separate step of the authorization.
+const AuthorizationFlowCookie = "oauth2_flow"
+
+type serverFlow struct {
+	request   *AuthorizationRequest
+	username  string
+	expiresAt time.Time
+}
+
+func authorizationFlowID(r *http.Request) string {
+	// parse form
+	_ = r.ParseForm()
+
+	// get flow parameter
+	if id := r.PostForm.Get("flow"); id != "" {
+		return id
+	}
+
+	// get flow cookie if the request does not start a new flow
+	if r.PostForm.Get("response_type") == "" {
+		if cookie, err := r.Cookie(AuthorizationFlowCookie); err == nil {
+			return cookie.Value
+		}
+	}
+
+	return ""
+}
+
+func (s *Server) startAuthorizationFlow(w http.ResponseWriter, r *http.Request, req *AuthorizationRequest, username, password string) {
+	// validate scope
+	if !s.Config.AllowedScope.Includes(req.Scope) {
+		_ = s.requestContext(req).WriteError(w, InvalidScope(""))
+		return
+	}
+
+	// authenticate resource owner
+	if !s.authenticateOwner(w, r, username, password, req) {
+		return
+	}
+
+	// grant authorization directly if consent has been given before
+	if s.hasConsent(username, req) {
+		s.grantAuthorization(w, username, req)
+		return
+	}
+
+	// get time
+	now := s.now()
+
+	// prepare map
+	if s.flows == nil {
+		s.flows = map[string]*serverFlow{}
+	}
+
+	// forget expired flows
+	for id, flow := range s.flows {
+		if flow.expiresAt.Before(now) {
+			delete(s.flows, id)
+		}
+	}
+
+	// generate id
+	key, err := generateKey(16)
+	if err != nil {
+		_ = s.writeError(w, ServerError("").SetCause(err))
+		return
+	}
+	id := b64.EncodeToString(key)
+
+	// store flow
+	s.flows[id] = &serverFlow{
+		request:   req,
+		username:  username,
+		expiresAt: now.Add(s.Config.AuthorizationCodeLifespan),
+	}
+
+	// set cookie
+	http.SetCookie(w, &http.Cookie{
+		Name:     AuthorizationFlowCookie,
+		Value:    id,
+		Expires:  now.Add(s.Config.AuthorizationCodeLifespan),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	// write response
+	_ = Write(w, map[string]string{
+		"flow":           id,
+		"client_id":      req.ClientID,
+		"scope":          req.Scope.String(),
+		"required_scope": s.Clients[req.ClientID].RequiredScope.String(),
+	}, http.StatusOK)
+}
+
+func (s *Server) resumeAuthorizationFlow(w http.ResponseWriter, r *http.Request, id string) {
+	// get flow
+	flow, ok := s.flows[id]
+	if !ok || flow.expiresAt.Before(s.now()) {
+		_ = s.writeError(w, InvalidRequest("unknown flow"))
+		return
+	}
+
+	// remove flow
+	delete(s.flows, id)
+
+	// clear cookie
+	http.SetCookie(w, &http.Cookie{
+		Name:     AuthorizationFlowCookie,
+		MaxAge:   -1,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	// get request
+	req := flow.request
+
+	// get client
+	client, found := s.Clients[req.ClientID]
+	if !found || client.Disabled {
+		_ = s.writeError(w, InvalidClient("unknown client"))
+		return
+	}
+
+	// check denial
+	if r.PostForm.Get("consent") == "deny" {
+		_ = s.requestContext(req).WriteError(w, AccessDenied(""))
+		return
+	}
+
+	// narrow scope to the approved scope if present
+	if !s.approveScope(w, r, client, req) {
+		return
+	}
+
+	// grant authorization
+	s.grantAuthorization(w, flow.username, req)
+}
@@ -0,0 +1,97 @@
+package oauth2
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/256dpi/oauth2/v2/oauth2test"
+)
+
+func TestServerSeparateConsent(t *testing.T) {
+	server := newTestServer()
+	server.Config.SeparateConsent = true
+
+	start := func(password string) *oauth2test.Response {
+		return oauth2test.Do(server, &oauth2test.Request{
+			Method: "POST",
+			Path:   "/oauth2/authorize",
+			Form: map[string]string{
+				"response_type": CodeResponseType,
+				"client_id":     "client1",
+				"redirect_uri":  "http://example.com/callback1",
+				"scope":         "foo bar",
+				"state":         "xyz",
+				"username":      "user1",
+				"password":      password,
+			},
+		})
+	}
+
+	res := start("bar")
+	assert.Equal(t, http.StatusSeeOther, res.Status)
+	assert.Equal(t, "access_denied", res.Query["error"])
+
+	res = start("foo")
+	assert.Equal(t, http.StatusOK, res.Status)
+	assert.NotEmpty(t, res.String("flow"))
+	assert.Equal(t, "client1", res.String("client_id"))
+	assert.Equal(t, "foo bar", res.String("scope"))
+	assert.Contains(t, res.Header.Get("Set-Cookie"), AuthorizationFlowCookie+"="+res.String("flow"))
+
+	flow := res.String("flow")
+
+	res = oauth2test.Do(server, &oauth2test.Request{
+		Method: "POST",
+		Path:   "/oauth2/authorize",
+		Form: map[string]string{
+			"flow":           flow,
+			"approved_scope": "foo",
+		},
+	})
+	assert.Equal(t, http.StatusSeeOther, res.Status)
+	assert.NotEmpty(t, res.Query["code"])
+	assert.Equal(t, "xyz", res.Query["state"])
+
+	res = oauth2test.Do(server, &oauth2test.Request{
+		Method:   "POST",
+		Path:     "/oauth2/token",
+		Username: "client1",
+		Password: "foo",
+		Form: map[string]string{
+			"grant_type":   AuthorizationCodeGrantType,
+			"code":         res.Query["code"],
+			"redirect_uri": "http://example.com/callback1",
+		},
+	})
+	assert.Equal(t, http.StatusOK, res.Status)
+	assert.Equal(t, "foo", res.String("scope"))
+
+	res = oauth2test.Do(server, &oauth2test.Request{
+		Method: "POST",
+		Path:   "/oauth2/authorize",
+		Form: map[string]string{
+			"flow": flow,
+		},
+	})
+	assert.Equal(t, http.StatusBadRequest, res.Status)
+	assert.Equal(t, "unknown flow", res.String("error_description"))
+
+	res = start("foo")
+	assert.Equal(t, http.StatusOK, res.Status)
+
+	res = oauth2test.Do(server, &oauth2test.Request{
+		Method: "POST",
+		Path:   "/oauth2/authorize",
+		Header: map[string]string{
+			"Cookie": AuthorizationFlowCookie + "=" + res.String("flow"),
+		},
+		Form: map[string]string{
+			"consent": "deny",
+		},
+	})
+	assert.Equal(t, http.StatusSeeOther, res.Status)
+	assert.Equal(t, "access_denied", res.Query["error"])
+	assert.Equal(t, "xyz", res.Query["state"])
+}
@@ -0,0 +1,119 @@
+package oauth2
+
+import (
+	"sort"
+	"time"
+)
+
+// ServerGrant describes the access a user has granted to a client. It is the
+// basis of an "authorized apps" page where users review and revoke the access
+// of clients.
+type ServerGrant struct {
+	// The client the access has been granted to.
+	ClientID string
+
+	// The remembered scope the user has consented to. If no consent has been
+	// remembered, the scope of the active tokens is listed instead.
+	Scope Scope
+
+	// The time of the most recent consent and the time at which the first of
+	// the consents expires. Both are zero if no consent has been remembered,
+	// the expiry is also zero if consents do not expire.
+	GrantedAt time.Time
+	ExpiresAt time.Time
+
+	// The number of active access and refresh tokens issued to the client on
+	// behalf of the user.
+	AccessTokens  int
+	RefreshTokens int
+}
+
+// ListGrants will list the grants of the specified user sorted by client ID.
+// A grant is listed for every client the user has given a remembered consent
+// to or that holds active tokens issued on behalf of the user.
+func (s *Server) ListGrants(username string) []ServerGrant {
+	// acquire mutex
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+
+	// get time
+	now := s.now()
+
+	// prepare grants
+	grants := map[string]*ServerGrant{}
+	grant := func(clientID string) *ServerGrant {
+		if grants[clientID] == nil {
+			grants[clientID] = &ServerGrant{ClientID: clientID}
+		}
+		return grants[clientID]
+	}
+
+	// collect consents
+	for key, consent := range s.consents {
+		if key.username != username || !consent.valid(now) {
+			continue
+		}
+		g := grant(key.clientID)
+		g.Scope = append(g.Scope, key.scope)
+		if consent.grantedAt.After(g.GrantedAt) {
+			g.GrantedAt = consent.grantedAt
+		}
+		if !consent.expiresAt.IsZero() && (g.ExpiresAt.IsZero() || consent.expiresAt.Before(g.ExpiresAt)) {
+			g.ExpiresAt = consent.expiresAt
+		}
+	}
+
+	// count tokens
+	for _, typ := range []TokenTypeHint{AccessTokenHint, RefreshTokenHint} {
+		for _, credential := range s.tokenList(typ) {
+			if credential.Username != username || !credential.RevokedAt.IsZero() || !credential.ExpiresAt.After(now) {
+				continue
+			}
+			g := grant(credential.ClientID)
+			if typ == AccessTokenHint {
+				g.AccessTokens++
+			} else {
+				g.RefreshTokens++
+			}
+			if g.GrantedAt.IsZero() {
+				for _, scope := range credential.Scope {
+					if !g.Scope.Contains(scope) {
+						g.Scope = append(g.Scope, scope)
+					}
+				}
+			}
+		}
+	}
+
+	// sort grants
+	list := make([]ServerGrant, 0, len(grants))
+	for _, g := range grants {
+		sort.Strings(g.Scope)
+		list = append(list, *g)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ClientID < list[j].ClientID
+	})
+
+	return list
+}
+
+// RevokeGrant will forget the consents the specified user has given to the
+// specified client and revoke all tokens and authorization codes issued to the
+// client on behalf of the user. It returns the number of revoked credentials.
+func (s *Server) RevokeGrant(username, clientID string) int {
+	// acquire mutex
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+
+	// remove consents
+	for key := range s.consents {
+		if key.username == username && key.clientID == clientID {
+			delete(s.consents, key)
+		}
+	}
+
+	return s.revokeAll(func(credential *ServerCredential) bool {
+		return credential.Username == username && credential.ClientID == clientID
+	})
+}
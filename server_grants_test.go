@@ -0,0 +1,80 @@
+package oauth2
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/256dpi/oauth2/v2/oauth2test"
+)
+
+func TestServerGrants(t *testing.T) {
+	server := newTestServer()
+	server.Config.SeparateConsent = true
+	server.Config.RememberConsent = true
+	server.Config.ConsentLifespan = 24 * time.Hour
+
+	assert.Empty(t, server.ListGrants("user1"))
+
+	res := oauth2test.Do(server, &oauth2test.Request{
+		Method: "POST",
+		Path:   "/oauth2/authorize",
+		Form: map[string]string{
+			"response_type": CodeResponseType,
+			"client_id":     "client1",
+			"redirect_uri":  "http://example.com/callback1",
+			"scope":         "foo",
+			"username":      "user1",
+			"password":      "foo",
+		},
+	})
+	assert.Equal(t, http.StatusOK, res.Status)
+
+	res = oauth2test.Do(server, &oauth2test.Request{
+		Method: "POST",
+		Path:   "/oauth2/authorize",
+		Form: map[string]string{
+			"flow": res.String("flow"),
+		},
+	})
+	assert.Equal(t, http.StatusSeeOther, res.Status)
+
+	res = oauth2test.Do(server, &oauth2test.Request{
+		Method:   "POST",
+		Path:     "/oauth2/token",
+		Username: "client1",
+		Password: "foo",
+		Form: map[string]string{
+			"grant_type": PasswordGrantType,
+			"username":   "user1",
+			"password":   "foo",
+			"scope":      "foo bar",
+		},
+	})
+	assert.Equal(t, http.StatusOK, res.Status)
+
+	grants := server.ListGrants("user1")
+	assert.Len(t, grants, 1)
+	assert.Equal(t, "client1", grants[0].ClientID)
+	assert.Equal(t, Scope{"foo"}, grants[0].Scope)
+	assert.False(t, grants[0].GrantedAt.IsZero())
+	assert.Equal(t, grants[0].GrantedAt.Add(24*time.Hour), grants[0].ExpiresAt)
+	assert.Equal(t, 1, grants[0].AccessTokens)
+	assert.Equal(t, 1, grants[0].RefreshTokens)
+	assert.Empty(t, server.ListGrants("user2"))
+
+	server.AdvanceTime(25 * time.Hour)
+
+	assert.Equal(t, []ServerGrant{
+		{
+			ClientID:      "client1",
+			Scope:         Scope{"bar", "foo"},
+			RefreshTokens: 1,
+		},
+	}, server.ListGrants("user1"))
+
+	assert.Equal(t, 3, server.RevokeGrant("user1", "client1"))
+	assert.Empty(t, server.ListGrants("user1"))
+}
@@ -0,0 +1,135 @@
+package oauth2
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// IssuePreAuthorizedCode will issue a code for the pre-authorized code grant
+// using the provided credential. The client, resource owner and scope of the
+// credential are used for the issued tokens. If the credential has no expiry,
+// the configured authorization code lifespan is used. If a transaction code
+// is set, it must be presented together with the code. A code is invalidated
+// if a wrong transaction code is presented.
+func (s *Server) IssuePreAuthorizedCode(credential ServerCredential) (string, error) {
+	// acquire mutex
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+
+	// check client
+	if _, ok := s.Clients[credential.ClientID]; !ok {
+		return "", fmt.Errorf("unknown client %q", credential.ClientID)
+	}
+
+	// check scope
+	if !s.Config.AllowedScope.Includes(credential.Scope) {
+		return "", fmt.Errorf("scope %q is not allowed", credential.Scope.String())
+	}
+
+	// check authorization details
+	if err := s.checkAuthorizationDetails(credential.AuthorizationDetails); err != nil {
+		return "", err
+	}
+
+	// set default expiry
+	if credential.ExpiresAt.IsZero() {
+		credential.ExpiresAt = s.now().Add(s.Config.AuthorizationCodeLifespan)
+	}
+
+	// generate code
+	code, err := s.generateCode()
+	if err != nil {
+		return "", err
+	}
+
+	// store code
+	s.PreAuthorizedCodes[code.SignatureString()] = &credential
+
+	return code.String(), nil
+}
+
+func (s *Server) handlePreAuthorizedCodeGrant(w http.ResponseWriter, r *http.Request, rq *TokenRequest) {
+	// check code
+	if rq.PreAuthorizedCode == "" {
+		_ = s.writeError(w, InvalidRequest("missing pre-authorized code"))
+		return
+	}
+
+	// parse code
+	code, err := s.parseCode(rq.PreAuthorizedCode)
+	if err != nil {
+		_ = s.writeError(w, InvalidRequest(err.Error()))
+		return
+	}
+
+	// get stored code
+	key := code.SignatureString()
+	storedCode, found := s.PreAuthorizedCodes[key]
+	if !found {
+		_ = s.writeError(w, InvalidGrant("unknown pre-authorized code"))
+		return
+	}
+
+	// validate expiration
+	if storedCode.ExpiresAt.Before(s.now()) {
+		_ = s.writeError(w, InvalidGrant("expired pre-authorized code"))
+		return
+	}
+
+	// validate ownership
+	if storedCode.ClientID != rq.ClientID {
+		_ = s.writeError(w, InvalidGrant("invalid pre-authorized code ownership"))
+		return
+	}
+
+	// validate transaction code
+	if storedCode.TxCode != "" {
+		if rq.TxCode == "" {
+			_ = s.writeError(w, InvalidRequest("missing transaction code"))
+			return
+		} else if storedCode.TxCode != rq.TxCode {
+			delete(s.PreAuthorizedCodes, key)
+			s.emit(ServerEvent{Type: AuthenticationFailedEvent, ClientID: rq.ClientID, Username: storedCode.Username, Reason: "invalid transaction code"})
+			_ = s.writeError(w, InvalidGrant("invalid transaction code"))
+			return
+		}
+	}
+
+	// inherit scope from stored code
+	if rq.Scope.Empty() {
+		rq.Scope = storedCode.Scope
+	}
+
+	// validate scope - a missing scope is always included
+	if !storedCode.Scope.Includes(rq.Scope) {
+		_ = s.writeError(w, InvalidScope("scope exceeds the pre-authorized scope"))
+		return
+	}
+
+	// inherit authorization details from stored code
+	if len(rq.AuthorizationDetails) == 0 {
+		rq.AuthorizationDetails = storedCode.AuthorizationDetails
+	}
+
+	// validate authorization details
+	if !IncludesAuthorizationDetails(storedCode.AuthorizationDetails, rq.AuthorizationDetails) {
+		_ = s.writeError(w, InvalidAuthorizationDetails("authorization details exceed the pre-authorized authorization details"))
+		return
+	}
+
+	// issue tokens
+	res, err := s.issueTokens(true, rq.Scope, rq.ClientID, storedCode.Username, "")
+	if err != nil {
+		_ = s.writeError(w, err)
+		return
+	}
+
+	// grant authorization details
+	s.grantAuthorizationDetails(res, rq.AuthorizationDetails)
+
+	// delete used code
+	delete(s.PreAuthorizedCodes, key)
+
+	// write response
+	_ = s.writeTokenResponse(w, r, res)
+}
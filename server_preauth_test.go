@@ -0,0 +1,93 @@
+package oauth2
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/256dpi/oauth2/v2/oauth2test"
+)
+
+func TestServerPreAuthorizedCodeGrant(t *testing.T) {
+	server := newTestServer()
+
+	exchange := func(code, txCode string) *oauth2test.Response {
+		return oauth2test.Do(server, &oauth2test.Request{
+			Method: "POST",
+			Path:   "/oauth2/token",
+			Form: map[string]string{
+				"grant_type":          PreAuthorizedCodeGrantType,
+				"client_id":           "client2",
+				"pre-authorized_code": code,
+				"tx_code":             txCode,
+			},
+		})
+	}
+
+	code, err := server.IssuePreAuthorizedCode(ServerCredential{
+		ClientID: "client2",
+		Username: "user1",
+		Scope:    Scope{"foo"},
+		TxCode:   "1234",
+	})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, code)
+
+	res := exchange(code, "")
+	assert.Equal(t, http.StatusBadRequest, res.Status)
+	assert.Equal(t, "invalid_request", res.String("error"))
+	assert.Equal(t, "missing transaction code", res.String("error_description"))
+
+	res = exchange(code, "1234")
+	assert.Equal(t, http.StatusOK, res.Status)
+	assert.NotEmpty(t, res.String("access_token"))
+	assert.NotEmpty(t, res.String("refresh_token"))
+	assert.Equal(t, "foo", res.String("scope"))
+
+	res = exchange(code, "1234")
+	assert.Equal(t, http.StatusBadRequest, res.Status)
+	assert.Equal(t, "invalid_grant", res.String("error"))
+	assert.Equal(t, "unknown pre-authorized code", res.String("error_description"))
+
+	code, err = server.IssuePreAuthorizedCode(ServerCredential{
+		ClientID: "client2",
+		Scope:    Scope{"foo"},
+		TxCode:   "1234",
+	})
+	assert.NoError(t, err)
+
+	res = exchange(code, "4321")
+	assert.Equal(t, http.StatusBadRequest, res.Status)
+	assert.Equal(t, "invalid transaction code", res.String("error_description"))
+
+	res = exchange(code, "1234")
+	assert.Equal(t, http.StatusBadRequest, res.Status)
+	assert.Equal(t, "unknown pre-authorized code", res.String("error_description"))
+
+	code, err = server.IssuePreAuthorizedCode(ServerCredential{
+		ClientID:  "client2",
+		ExpiresAt: time.Now().Add(-time.Minute),
+	})
+	assert.NoError(t, err)
+
+	res = exchange(code, "")
+	assert.Equal(t, http.StatusBadRequest, res.Status)
+	assert.Equal(t, "expired pre-authorized code", res.String("error_description"))
+
+	res = exchange("", "")
+	assert.Equal(t, http.StatusBadRequest, res.Status)
+	assert.Equal(t, "missing pre-authorized code", res.String("error_description"))
+
+	_, err = server.IssuePreAuthorizedCode(ServerCredential{
+		ClientID: "foo",
+	})
+	assert.EqualError(t, err, `unknown client "foo"`)
+
+	_, err = server.IssuePreAuthorizedCode(ServerCredential{
+		ClientID: "client2",
+		Scope:    Scope{"baz"},
+	})
+	assert.EqualError(t, err, `scope "baz" is not allowed`)
+}
@@ -0,0 +1,196 @@
+package oauth2
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ServerQuery is used to list and paginate stored credentials. All set
+// fields must match for a credential to be included.
+type ServerQuery struct {
+	// The client and user the credentials have been issued to.
+	ClientID string
+	Username string
+
+	// The scope the credentials must include.
+	Scope Scope
+
+	// The fingerprint of the token (see Fingerprint).
+	Fingerprint string
+
+	// The window in which the credentials must expire.
+	ExpiresAfter  time.Time
+	ExpiresBefore time.Time
+
+	// If enabled, the credentials are sorted by descending expiry.
+	Descending bool
+
+	// The cursor returned by a previous listing to continue from and the
+	// maximum number of credentials to return (defaults to 100).
+	Cursor string
+	Limit  int
+}
+
+// ServerListItem is a single credential returned by a listing.
+type ServerListItem struct {
+	Signature  string
+	Credential ServerCredential
+}
+
+// ListAccessTokens will list the stored access tokens that match the query.
+// If more credentials are available, a cursor is returned that can be used to
+// continue the listing.
+func (s *Server) ListAccessTokens(query ServerQuery) ([]ServerListItem, string, error) {
+	// acquire mutex
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+
+	return listCredentials(s.AccessTokens, query)
+}
+
+// ListRefreshTokens will list the stored refresh tokens that match the query.
+// If more credentials are available, a cursor is returned that can be used to
+// continue the listing.
+func (s *Server) ListRefreshTokens(query ServerQuery) ([]ServerListItem, string, error) {
+	// acquire mutex
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+
+	return listCredentials(s.RefreshTokens, query)
+}
+
+// ListAuthorizationCodes will list the stored authorization codes that match
+// the query. If more credentials are available, a cursor is returned that can
+// be used to continue the listing.
+func (s *Server) ListAuthorizationCodes(query ServerQuery) ([]ServerListItem, string, error) {
+	// acquire mutex
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+
+	return listCredentials(s.AuthorizationCodes, query)
+}
+
+func listCredentials(list map[string]*ServerCredential, query ServerQuery) ([]ServerListItem, string, error) {
+	// set default limit
+	if query.Limit <= 0 {
+		query.Limit = 100
+	}
+
+	// parse cursor
+	var cursorTime int64
+	var cursorSignature string
+	if query.Cursor != "" {
+		var err error
+		cursorTime, cursorSignature, err = parseCursor(query.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	// collect matching credentials
+	var items []ServerListItem
+	for signature, credential := range list {
+		if query.matches(credential) {
+			items = append(items, ServerListItem{
+				Signature:  signature,
+				Credential: *credential,
+			})
+		}
+	}
+
+	// prepare ordering
+	less := func(t1 int64, s1 string, t2 int64, s2 string) bool {
+		if t1 != t2 {
+			return (t1 < t2) != query.Descending
+		}
+
+		return (s1 < s2) != query.Descending
+	}
+
+	// sort credentials
+	sort.Slice(items, func(i, j int) bool {
+		return less(items[i].Credential.ExpiresAt.UnixNano(), items[i].Signature,
+			items[j].Credential.ExpiresAt.UnixNano(), items[j].Signature)
+	})
+
+	// skip credentials up to and including the cursor
+	if query.Cursor != "" {
+		idx := sort.Search(len(items), func(i int) bool {
+			return less(cursorTime, cursorSignature, items[i].Credential.ExpiresAt.UnixNano(), items[i].Signature)
+		})
+		items = items[idx:]
+	}
+
+	// return all remaining credentials
+	if len(items) <= query.Limit {
+		return items, "", nil
+	}
+
+	// limit credentials
+	items = items[:query.Limit]
+
+	// get last item
+	last := items[len(items)-1]
+
+	// prepare cursor
+	cursor := b64.EncodeToString([]byte(strconv.FormatInt(last.Credential.ExpiresAt.UnixNano(), 10) + ":" + last.Signature))
+
+	return items, cursor, nil
+}
+
+func parseCursor(cursor string) (int64, string, error) {
+	// decode cursor
+	data, err := b64.DecodeString(cursor)
+	if err != nil {
+		return 0, "", errors.New("invalid cursor")
+	}
+
+	// split cursor
+	s := strings.SplitN(string(data), ":", 2)
+	if len(s) != 2 {
+		return 0, "", errors.New("invalid cursor")
+	}
+
+	// parse time
+	t, err := strconv.ParseInt(s[0], 10, 64)
+	if err != nil {
+		return 0, "", errors.New("invalid cursor")
+	}
+
+	return t, s[1], nil
+}
+
+func (q ServerQuery) matches(credential *ServerCredential) bool {
+	// check client id
+	if q.ClientID != "" && credential.ClientID != q.ClientID {
+		return false
+	}
+
+	// check username
+	if q.Username != "" && credential.Username != q.Username {
+		return false
+	}
+
+	// check fingerprint
+	if q.Fingerprint != "" && credential.Fingerprint != q.Fingerprint {
+		return false
+	}
+
+	// check scope
+	if !credential.Scope.Includes(q.Scope) {
+		return false
+	}
+
+	// check expiry window
+	if !q.ExpiresAfter.IsZero() && !credential.ExpiresAt.After(q.ExpiresAfter) {
+		return false
+	}
+	if !q.ExpiresBefore.IsZero() && !credential.ExpiresAt.Before(q.ExpiresBefore) {
+		return false
+	}
+
+	return true
+}
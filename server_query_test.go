@@ -0,0 +1,97 @@
+package oauth2
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServerListAccessTokens(t *testing.T) {
+	server := newTestServer()
+
+	now := time.Now()
+
+	for i := 0; i < 10; i++ {
+		server.AccessTokens["t"+strconv.Itoa(i)] = &ServerCredential{
+			ClientID:  []string{"client1", "client2"}[i%2],
+			Username:  "user1",
+			Scope:     []Scope{{"foo"}, {"foo", "bar"}}[i%2],
+			ExpiresAt: now.Add(time.Duration(i) * time.Minute),
+		}
+	}
+
+	items, cursor, err := server.ListAccessTokens(ServerQuery{})
+	assert.NoError(t, err)
+	assert.Len(t, items, 10)
+	assert.Empty(t, cursor)
+	assert.Equal(t, "t0", items[0].Signature)
+	assert.Equal(t, "t9", items[9].Signature)
+
+	var signatures []string
+	cursor = ""
+	for {
+		items, cursor, err = server.ListAccessTokens(ServerQuery{
+			ClientID: "client2",
+			Cursor:   cursor,
+			Limit:    2,
+		})
+		assert.NoError(t, err)
+		for _, item := range items {
+			signatures = append(signatures, item.Signature)
+		}
+		if cursor == "" {
+			break
+		}
+	}
+	assert.Equal(t, []string{"t1", "t3", "t5", "t7", "t9"}, signatures)
+
+	items, _, err = server.ListAccessTokens(ServerQuery{
+		Scope:         Scope{"bar"},
+		ExpiresAfter:  now.Add(2 * time.Minute),
+		ExpiresBefore: now.Add(8 * time.Minute),
+		Descending:    true,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, items, 3)
+	assert.Equal(t, "t7", items[0].Signature)
+	assert.Equal(t, "t3", items[2].Signature)
+
+	items, cursor, err = server.ListAccessTokens(ServerQuery{
+		Username:   "user1",
+		Descending: true,
+		Limit:      3,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, items, 3)
+	assert.Equal(t, "t9", items[0].Signature)
+
+	delete(server.AccessTokens, "t7")
+
+	items, _, err = server.ListAccessTokens(ServerQuery{
+		Descending: true,
+		Cursor:     cursor,
+		Limit:      1,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, items, 1)
+	assert.Equal(t, "t6", items[0].Signature)
+
+	items, _, err = server.ListRefreshTokens(ServerQuery{})
+	assert.NoError(t, err)
+	assert.Empty(t, items)
+
+	items, _, err = server.ListAuthorizationCodes(ServerQuery{})
+	assert.NoError(t, err)
+	assert.Empty(t, items)
+
+	for _, cursor := range []string{"%", "Zm9v", "Zm9vOmJhcg"} {
+		items, cursor, err = server.ListAccessTokens(ServerQuery{
+			Cursor: cursor,
+		})
+		assert.Error(t, err)
+		assert.Nil(t, items)
+		assert.Empty(t, cursor)
+	}
+}
@@ -0,0 +1,232 @@
+package oauth2
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RevocationTask describes a revocation request that has been accepted for
+// asynchronous processing.
+type RevocationTask struct {
+	ClientID      string
+	Token         string
+	TokenTypeHint string
+}
+
+// RevocationQueue is used by the server to process revocation requests
+// asynchronously. Enqueue must not block and should return an error if the
+// task cannot be accepted.
+type RevocationQueue interface {
+	Enqueue(task RevocationTask) error
+}
+
+// Revoke will process the specified revocation task. It is called by
+// revocation queues to eventually revoke the token.
+func (s *Server) Revoke(task RevocationTask) error {
+	// acquire mutex
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+
+	return s.revoke(task)
+}
+
+// RevokeClientTokens will revoke all access tokens, refresh tokens and
+// authorization codes issued to the specified client, e.g. after the client
+// has been compromised. It returns the number of revoked credentials.
+func (s *Server) RevokeClientTokens(clientID string) int {
+	// acquire mutex
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+
+	return s.revokeAll(func(credential *ServerCredential) bool {
+		return credential.ClientID == clientID
+	})
+}
+
+// RevokeUserTokens will revoke all access tokens, refresh tokens and
+// authorization codes issued on behalf of the specified user, e.g. after the
+// user changed their password. It returns the number of revoked credentials.
+func (s *Server) RevokeUserTokens(username string) int {
+	// acquire mutex
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+
+	return s.revokeAll(func(credential *ServerCredential) bool {
+		return credential.Username == username
+	})
+}
+
+func (s *Server) revokeAll(match func(*ServerCredential) bool) int {
+	// prepare counter
+	var revoked int
+
+	// revoke tokens
+	for _, typ := range []TokenTypeHint{AccessTokenHint, RefreshTokenHint} {
+		list := s.tokenList(typ)
+		for signature, credential := range list {
+			if !match(credential) || !credential.RevokedAt.IsZero() {
+				continue
+			}
+			s.revokeToken(credential.ClientID, list, signature, s.Config.DistinguishRevokedTokens)
+			s.emit(ServerEvent{Type: TokenRevokedEvent, ClientID: credential.ClientID, Username: credential.Username, TokenType: string(typ), Scope: credential.Scope, Fingerprint: credential.Fingerprint})
+			revoked++
+		}
+	}
+
+	// revoke codes
+	for _, list := range []map[string]*ServerCredential{s.AuthorizationCodes, s.PreAuthorizedCodes} {
+		for signature, credential := range list {
+			if match(credential) {
+				delete(list, signature)
+				revoked++
+			}
+		}
+	}
+
+	return revoked
+}
+
+func (s *Server) revokeAllEndpoint(w http.ResponseWriter, r *http.Request) {
+	// check if enabled
+	if s.Config.AdminToken == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	// check method
+	if r.Method != "POST" {
+		_ = s.writeError(w, InvalidRequest("invalid HTTP method"))
+		return
+	}
+
+	// check admin token
+	token, err := ParseBearerToken(r)
+	if err != nil {
+		_ = s.writeBearerError(w, err)
+		return
+	} else if subtle.ConstantTimeCompare([]byte(token), []byte(s.Config.AdminToken)) != 1 {
+		_ = s.writeBearerError(w, InvalidToken("invalid admin token"))
+		return
+	}
+
+	// parse form
+	err = r.ParseForm()
+	if err != nil {
+		_ = s.writeError(w, InvalidRequest("malformed query parameters or body form"))
+		return
+	}
+
+	// get client id and username
+	clientID := r.PostForm.Get("client_id")
+	username := r.PostForm.Get("username")
+	if (clientID == "") == (username == "") {
+		_ = s.writeError(w, InvalidRequest("either client ID or username required"))
+		return
+	}
+
+	// revoke credentials
+	revoked := s.revokeAll(func(credential *ServerCredential) bool {
+		if clientID != "" {
+			return credential.ClientID == clientID
+		}
+		return credential.Username == username
+	})
+
+	// write response
+	_ = Write(w, map[string]int{"revoked": revoked}, http.StatusOK)
+}
+
+// MemoryRevocationQueue is a basic in-memory revocation queue that processes
+// tasks sequentially in the background.
+type MemoryRevocationQueue struct {
+	server     *Server
+	delay      time.Duration
+	onComplete func(RevocationTask, error)
+	tasks      chan RevocationTask
+	mutex      sync.RWMutex
+	closed     bool
+	done       chan struct{}
+}
+
+// NewMemoryRevocationQueue creates and returns a new queue that buffers up to
+// the specified amount of tasks and processes them using the specified server.
+// Each task is delayed by the specified duration before processing. The
+// optional callback is called once a task has been processed.
+func NewMemoryRevocationQueue(server *Server, size int, delay time.Duration, onComplete func(RevocationTask, error)) *MemoryRevocationQueue {
+	// prepare queue
+	q := &MemoryRevocationQueue{
+		server:     server,
+		delay:      delay,
+		onComplete: onComplete,
+		tasks:      make(chan RevocationTask, size),
+		done:       make(chan struct{}),
+	}
+
+	// run worker
+	go q.worker()
+
+	return q
+}
+
+// Enqueue implements the RevocationQueue interface.
+func (q *MemoryRevocationQueue) Enqueue(task RevocationTask) error {
+	// acquire mutex
+	q.mutex.RLock()
+	defer q.mutex.RUnlock()
+
+	// check if closed
+	if q.closed {
+		return errors.New("revocation queue closed")
+	}
+
+	// queue task
+	select {
+	case q.tasks <- task:
+		return nil
+	default:
+		return errors.New("revocation queue full")
+	}
+}
+
+// Close will stop accepting tasks and wait until all queued tasks have been
+// processed.
+func (q *MemoryRevocationQueue) Close() {
+	// acquire mutex
+	q.mutex.Lock()
+
+	// close queue
+	if !q.closed {
+		q.closed = true
+		close(q.tasks)
+	}
+
+	// release mutex
+	q.mutex.Unlock()
+
+	// await worker
+	<-q.done
+}
+
+func (q *MemoryRevocationQueue) worker() {
+	// signal exit
+	defer close(q.done)
+
+	// process tasks
+	for task := range q.tasks {
+		// delay processing
+		if q.delay > 0 {
+			time.Sleep(q.delay)
+		}
+
+		// revoke token
+		err := q.server.Revoke(task)
+
+		// call callback
+		if q.onComplete != nil {
+			q.onComplete(task, err)
+		}
+	}
+}
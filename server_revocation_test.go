@@ -0,0 +1,171 @@
+package oauth2
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/256dpi/oauth2/v2/oauth2test"
+)
+
+func TestServerRevocationQueue(t *testing.T) {
+	server := newTestServer()
+
+	token1 := server.Config.MustGenerate()
+	server.AccessTokens[token1.SignatureString()] = &ServerCredential{
+		ClientID:  "client1",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	token2 := server.Config.MustGenerate()
+	server.RefreshTokens[token2.SignatureString()] = &ServerCredential{
+		ClientID:  "client2",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	completed := make(chan error, 2)
+	queue := NewMemoryRevocationQueue(server, 1, 10*time.Millisecond, func(task RevocationTask, err error) {
+		completed <- err
+	})
+	server.Config.RevocationQueue = queue
+
+	res := oauth2test.Do(server, &oauth2test.Request{
+		Method:   "POST",
+		Path:     "/oauth2/revoke",
+		Username: "client1",
+		Password: "foo",
+		Form: map[string]string{
+			"token": token1.String(),
+		},
+	})
+	assert.Equal(t, http.StatusOK, res.Status)
+
+	server.Mutex.Lock()
+	assert.Len(t, server.AccessTokens, 1)
+	server.Mutex.Unlock()
+
+	assert.NoError(t, <-completed)
+
+	server.Mutex.Lock()
+	assert.Empty(t, server.AccessTokens)
+	server.Mutex.Unlock()
+
+	res = oauth2test.Do(server, &oauth2test.Request{
+		Method:   "POST",
+		Path:     "/oauth2/revoke",
+		Username: "client1",
+		Password: "foo",
+		Form: map[string]string{
+			"token": token2.String(),
+		},
+	})
+	assert.Equal(t, http.StatusOK, res.Status)
+	assert.Equal(t, "invalid_client: wrong client", (<-completed).Error())
+
+	queue.Close()
+
+	err := queue.Enqueue(RevocationTask{})
+	assert.Error(t, err)
+
+	res = oauth2test.Do(server, &oauth2test.Request{
+		Method:   "POST",
+		Path:     "/oauth2/revoke",
+		Username: "client1",
+		Password: "foo",
+		Form: map[string]string{
+			"token": token2.String(),
+		},
+	})
+	assert.Equal(t, http.StatusServiceUnavailable, res.Status)
+	assert.Equal(t, "temporarily_unavailable", res.String("error"))
+}
+
+func TestMemoryRevocationQueueFull(t *testing.T) {
+	server := newTestServer()
+
+	queue := NewMemoryRevocationQueue(server, 1, 0, nil)
+
+	server.Mutex.Lock()
+
+	var err error
+	for i := 0; i < 3; i++ {
+		err = queue.Enqueue(RevocationTask{})
+	}
+	assert.Error(t, err)
+
+	server.Mutex.Unlock()
+
+	queue.Close()
+}
+
+func TestServerRevokeAll(t *testing.T) {
+	server := newTestServer()
+
+	server.issueTokens(true, Scope{"foo"}, "client1", "user1", "")
+	server.issueTokens(true, Scope{"foo"}, "client1", "user2", "")
+	server.issueTokens(true, Scope{"foo"}, "client2", "user1", "")
+	assert.Len(t, server.AccessTokens, 3)
+
+	assert.Equal(t, 4, server.RevokeUserTokens("user1"))
+	assert.Len(t, server.AccessTokens, 1)
+	assert.Len(t, server.RefreshTokens, 1)
+	assert.Equal(t, 0, server.RevokeUserTokens("user1"))
+
+	server.Config.DistinguishRevokedTokens = true
+
+	assert.Equal(t, 2, server.RevokeClientTokens("client1"))
+	assert.Len(t, server.AccessTokens, 1)
+	assert.False(t, server.AccessTokens[firstKey(server.AccessTokens)].RevokedAt.IsZero())
+	assert.Equal(t, 0, server.RevokeClientTokens("client1"))
+}
+
+func TestServerRevokeAllEndpoint(t *testing.T) {
+	server := newTestServer()
+
+	server.issueTokens(true, Scope{"foo"}, "client1", "user1", "")
+	server.issueTokens(true, Scope{"foo"}, "client2", "user1", "")
+
+	revokeAll := func(token string, form map[string]string) *oauth2test.Response {
+		return oauth2test.Do(server, &oauth2test.Request{
+			Method: "POST",
+			Path:   "/oauth2/revoke_all",
+			Header: map[string]string{
+				"Authorization": "Bearer " + token,
+			},
+			Form: form,
+		})
+	}
+
+	res := revokeAll("admin", map[string]string{"client_id": "client1"})
+	assert.Equal(t, http.StatusNotFound, res.Status)
+
+	server.Config.AdminToken = "admin"
+
+	res = revokeAll("foo", map[string]string{"client_id": "client1"})
+	assert.Equal(t, http.StatusUnauthorized, res.Status)
+	assert.Len(t, server.AccessTokens, 2)
+
+	res = revokeAll("admin", map[string]string{})
+	assert.Equal(t, http.StatusBadRequest, res.Status)
+	assert.Equal(t, "invalid_request", res.String("error"))
+
+	res = revokeAll("admin", map[string]string{"client_id": "client1"})
+	assert.Equal(t, http.StatusOK, res.Status)
+	assert.Equal(t, float64(2), res.Float("revoked"))
+	assert.Len(t, server.AccessTokens, 1)
+
+	res = revokeAll("admin", map[string]string{"username": "user1"})
+	assert.Equal(t, http.StatusOK, res.Status)
+	assert.Equal(t, float64(2), res.Float("revoked"))
+	assert.Len(t, server.AccessTokens, 0)
+	assert.Len(t, server.RefreshTokens, 0)
+}
+
+func firstKey(list map[string]*ServerCredential) string {
+	for key := range list {
+		return key
+	}
+	return ""
+}
@@ -0,0 +1,219 @@
+package oauth2
+
+import (
+	"net/http"
+	"time"
+)
+
+// LoginSessionCookie is the cookie that carries the login session ID if login
+// sessions are enabled.
+const LoginSessionCookie = "oauth2_session"
+
+type serverSession struct {
+	username  string
+	expiresAt time.Time
+}
+
+type serverConsentKey struct {
+	username string
+	clientID string
+	scope    string
+}
+
+type serverConsent struct {
+	grantedAt time.Time
+	expiresAt time.Time
+}
+
+func (c serverConsent) valid(now time.Time) bool {
+	return c.expiresAt.IsZero() || c.expiresAt.After(now)
+}
+
+// RevokeConsent will forget the consent the specified user has given to the
+// specified client for the specified scope. If no scope is specified, the
+// consent for all scopes is forgotten. Subsequent authorizations that request
+// a revoked scope require consent again.
+func (s *Server) RevokeConsent(username, clientID string, scope ...string) {
+	// acquire mutex
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+
+	// remove consents
+	for key := range s.consents {
+		if key.username == username && key.clientID == clientID && (len(scope) == 0 || containsString(scope, key.scope)) {
+			delete(s.consents, key)
+		}
+	}
+}
+
+// EndSessions will end all login sessions of the specified user. The session
+// cookie is cleared when the user agent presents it on the next request to
+// the authorization endpoint.
+func (s *Server) EndSessions(username string) {
+	// acquire mutex
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+
+	// remove sessions
+	for id, session := range s.sessions {
+		if session.username == username {
+			delete(s.sessions, id)
+		}
+	}
+}
+
+func (s *Server) sessionUser(r *http.Request) string {
+	// check config
+	if s.Config.SessionLifespan <= 0 {
+		return ""
+	}
+
+	// get cookie
+	cookie, err := r.Cookie(LoginSessionCookie)
+	if err != nil {
+		return ""
+	}
+
+	// get session
+	session, ok := s.sessions[cookie.Value]
+	if !ok || session.expiresAt.Before(s.now()) {
+		return ""
+	}
+
+	// check user
+	if _, ok := s.Users[session.username]; !ok {
+		return ""
+	}
+
+	return session.username
+}
+
+func (s *Server) startSession(w http.ResponseWriter, r *http.Request, username string) {
+	// check config
+	if s.Config.SessionLifespan <= 0 {
+		return
+	}
+
+	// get time
+	now := s.now()
+
+	// prepare map
+	if s.sessions == nil {
+		s.sessions = map[string]*serverSession{}
+	}
+
+	// forget expired sessions
+	for id, session := range s.sessions {
+		if session.expiresAt.Before(now) {
+			delete(s.sessions, id)
+		}
+	}
+
+	// generate id
+	key, err := generateKey(16)
+	if err != nil {
+		return
+	}
+	id := b64.EncodeToString(key)
+
+	// store session
+	s.sessions[id] = &serverSession{
+		username:  username,
+		expiresAt: now.Add(s.Config.SessionLifespan),
+	}
+
+	// set cookie
+	http.SetCookie(w, &http.Cookie{
+		Name:     LoginSessionCookie,
+		Value:    id,
+		Path:     r.URL.Path,
+		Expires:  now.Add(s.Config.SessionLifespan),
+		Secure:   s.secureRequest(r),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (s *Server) clearSession(w http.ResponseWriter, r *http.Request) {
+	// check config
+	if s.Config.SessionLifespan <= 0 {
+		return
+	}
+
+	// check cookie
+	if _, err := r.Cookie(LoginSessionCookie); err != nil {
+		return
+	}
+
+	// check session
+	if s.sessionUser(r) != "" {
+		return
+	}
+
+	// clear cookie
+	http.SetCookie(w, &http.Cookie{
+		Name:     LoginSessionCookie,
+		Path:     r.URL.Path,
+		MaxAge:   -1,
+		Secure:   s.secureRequest(r),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (s *Server) hasConsent(username string, req *AuthorizationRequest) bool {
+	// check config
+	if !s.Config.RememberConsent {
+		return false
+	}
+
+	// check scope
+	if req.Scope.Empty() {
+		return false
+	}
+
+	// get time
+	now := s.now()
+
+	// check consents
+	for _, scope := range req.Scope {
+		consent, ok := s.consents[serverConsentKey{username: username, clientID: req.ClientID, scope: scope}]
+		if !ok || !consent.valid(now) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (s *Server) recordConsent(username string, req *AuthorizationRequest) {
+	// check config
+	if !s.Config.RememberConsent {
+		return
+	}
+
+	// prepare map
+	if s.consents == nil {
+		s.consents = map[serverConsentKey]serverConsent{}
+	}
+
+	// prepare consent
+	consent := serverConsent{
+		grantedAt: s.now(),
+	}
+	if s.Config.ConsentLifespan > 0 {
+		consent.expiresAt = consent.grantedAt.Add(s.Config.ConsentLifespan)
+	}
+
+	// forget expired consents
+	for key, existing := range s.consents {
+		if !existing.valid(consent.grantedAt) {
+			delete(s.consents, key)
+		}
+	}
+
+	// store consents
+	for _, scope := range req.Scope {
+		s.consents[serverConsentKey{username: username, clientID: req.ClientID, scope: scope}] = consent
+	}
+}
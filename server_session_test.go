@@ -0,0 +1,169 @@
+package oauth2
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/256dpi/oauth2/v2/oauth2test"
+)
+
+func TestServerLoginSession(t *testing.T) {
+	server := newTestServer()
+	server.Config.SessionLifespan = time.Hour
+
+	authorize := func(cookie, password string) *oauth2test.Response {
+		form := map[string]string{
+			"response_type": CodeResponseType,
+			"client_id":     "client1",
+			"redirect_uri":  "http://example.com/callback1",
+			"scope":         "foo",
+		}
+		if password != "" {
+			form["username"] = "user1"
+			form["password"] = password
+		}
+		return oauth2test.Do(server, &oauth2test.Request{
+			Method: "POST",
+			Path:   "/oauth2/authorize",
+			Header: map[string]string{
+				"Cookie": cookie,
+			},
+			Form: form,
+		})
+	}
+
+	res := authorize("", "")
+	assert.Equal(t, http.StatusSeeOther, res.Status)
+	assert.Equal(t, "access_denied", res.Query["error"])
+
+	res = authorize("", "foo")
+	assert.Equal(t, http.StatusSeeOther, res.Status)
+	assert.NotEmpty(t, res.Query["code"])
+
+	cookie := (&http.Response{Header: res.Header}).Cookies()[0]
+	assert.Equal(t, LoginSessionCookie, cookie.Name)
+	assert.Equal(t, "/oauth2/authorize", cookie.Path)
+	assert.True(t, cookie.HttpOnly)
+	assert.False(t, cookie.Secure)
+
+	res = authorize(cookie.Name+"="+cookie.Value, "")
+	assert.Equal(t, http.StatusSeeOther, res.Status)
+	assert.NotEmpty(t, res.Query["code"])
+
+	res = authorize(cookie.Name+"=foo", "")
+	assert.Equal(t, http.StatusSeeOther, res.Status)
+	assert.Equal(t, "access_denied", res.Query["error"])
+
+	server.AdvanceTime(2 * time.Hour)
+
+	res = authorize(cookie.Name+"="+cookie.Value, "")
+	assert.Equal(t, http.StatusSeeOther, res.Status)
+	assert.Equal(t, "access_denied", res.Query["error"])
+
+	res = authorize("", "foo")
+	cookie = (&http.Response{Header: res.Header}).Cookies()[0]
+
+	server.EndSessions("user1")
+
+	res = authorize(cookie.Name+"="+cookie.Value, "")
+	assert.Equal(t, http.StatusSeeOther, res.Status)
+	assert.Equal(t, "access_denied", res.Query["error"])
+
+	cleared := (&http.Response{Header: res.Header}).Cookies()[0]
+	assert.Equal(t, LoginSessionCookie, cleared.Name)
+	assert.Equal(t, "/oauth2/authorize", cleared.Path)
+	assert.True(t, cleared.MaxAge < 0)
+
+	server.Config.TrustedProxies = []string{"192.0.2.1"}
+
+	req := httptest.NewRequest("POST", "/oauth2/authorize", strings.NewReader(url.Values{
+		"response_type": []string{CodeResponseType},
+		"client_id":     []string{"client1"},
+		"redirect_uri":  []string{"http://example.com/callback1"},
+		"scope":         []string{"foo"},
+		"username":      []string{"user1"},
+		"password":      []string{"foo"},
+	}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("X-Forwarded-Proto", "https")
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusSeeOther, rec.Code)
+
+	cookie = rec.Result().Cookies()[0]
+	assert.True(t, cookie.Secure)
+}
+
+func TestServerRememberConsent(t *testing.T) {
+	server := newTestServer()
+	server.Config.SeparateConsent = true
+	server.Config.RememberConsent = true
+
+	start := func(scope string) *oauth2test.Response {
+		return oauth2test.Do(server, &oauth2test.Request{
+			Method: "POST",
+			Path:   "/oauth2/authorize",
+			Form: map[string]string{
+				"response_type": CodeResponseType,
+				"client_id":     "client1",
+				"redirect_uri":  "http://example.com/callback1",
+				"scope":         scope,
+				"username":      "user1",
+				"password":      "foo",
+			},
+		})
+	}
+
+	res := start("foo")
+	assert.Equal(t, http.StatusOK, res.Status)
+	assert.NotEmpty(t, res.String("flow"))
+
+	res = oauth2test.Do(server, &oauth2test.Request{
+		Method: "POST",
+		Path:   "/oauth2/authorize",
+		Form: map[string]string{
+			"flow": res.String("flow"),
+		},
+	})
+	assert.Equal(t, http.StatusSeeOther, res.Status)
+	assert.NotEmpty(t, res.Query["code"])
+
+	res = start("foo")
+	assert.Equal(t, http.StatusSeeOther, res.Status)
+	assert.NotEmpty(t, res.Query["code"])
+
+	res = start("foo bar")
+	assert.Equal(t, http.StatusOK, res.Status)
+	assert.NotEmpty(t, res.String("flow"))
+
+	res = oauth2test.Do(server, &oauth2test.Request{
+		Method: "POST",
+		Path:   "/oauth2/authorize",
+		Form: map[string]string{
+			"flow": res.String("flow"),
+		},
+	})
+	assert.Equal(t, http.StatusSeeOther, res.Status)
+
+	server.RevokeConsent("user1", "client1", "bar")
+
+	res = start("foo")
+	assert.Equal(t, http.StatusSeeOther, res.Status)
+	assert.NotEmpty(t, res.Query["code"])
+
+	res = start("foo bar")
+	assert.Equal(t, http.StatusOK, res.Status)
+	assert.NotEmpty(t, res.String("flow"))
+
+	server.RevokeConsent("user1", "client1")
+
+	res = start("foo")
+	assert.Equal(t, http.StatusOK, res.Status)
+	assert.NotEmpty(t, res.String("flow"))
+}
@@ -0,0 +1,247 @@
+package oauth2
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// StateStats contains the counters of the records processed by EncryptState
+// and DecryptState. Expired credentials, used codes and aliases are dropped
+// when the state is saved or loaded and counted as skipped.
+type StateStats struct {
+	Saves   int
+	Loads   int
+	Saved   int
+	Loaded  int
+	Skipped int
+}
+
+type serverState struct {
+	Clients            map[string]*ServerEntity     `json:"clients"`
+	Users              map[string]*ServerEntity     `json:"users"`
+	AccessTokens       map[string]*ServerCredential `json:"access_tokens"`
+	RefreshTokens      map[string]*ServerCredential `json:"refresh_tokens"`
+	AuthorizationCodes map[string]*ServerCredential `json:"authorization_codes"`
+	PreAuthorizedCodes map[string]*ServerCredential `json:"pre_authorized_codes"`
+	UsedCodes          map[string]time.Time         `json:"used_codes"`
+	ClientAliases      map[string]*ServerAlias      `json:"client_aliases"`
+}
+
+type serverSnapshot struct {
+	State    json.RawMessage `json:"state"`
+	Checksum string          `json:"checksum"`
+}
+
+// EncryptState will export the clients, users and issued credentials of the
+// server and encrypt them using the specified key. The state can be loaded by
+// another server using DecryptState. Tokens are only accepted by the other
+// server if it uses the same secret or keyring. Expired entries are not
+// exported.
+func (s *Server) EncryptState(key []byte) ([]byte, error) {
+	// check key
+	if len(key) < 16 {
+		return nil, errors.New("key must be at least 16 bytes long")
+	}
+
+	// acquire mutex
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+
+	// compact state
+	state := serverState{
+		Clients:            s.Clients,
+		Users:              s.Users,
+		AccessTokens:       s.AccessTokens,
+		RefreshTokens:      s.RefreshTokens,
+		AuthorizationCodes: s.AuthorizationCodes,
+		PreAuthorizedCodes: s.PreAuthorizedCodes,
+		UsedCodes:          s.UsedCodes,
+		ClientAliases:      s.ClientAliases,
+	}
+	skipped := state.compact(s.now())
+
+	// encode state
+	data, err := json.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+
+	// compute checksum
+	sum := sha256.Sum256(data)
+
+	// encode snapshot
+	data, err = json.Marshal(serverSnapshot{
+		State:    data,
+		Checksum: hex.EncodeToString(sum[:]),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// seal snapshot
+	data, err = seal(key, data)
+	if err != nil {
+		return nil, err
+	}
+
+	// update stats
+	s.stateStats.Saves++
+	s.stateStats.Saved += state.count()
+	s.stateStats.Skipped += skipped
+
+	return data, nil
+}
+
+// DecryptState will decrypt the state previously exported using EncryptState
+// with the specified key and replace the clients, users and issued credentials
+// of the server. Entries that expired since the state was exported are dropped.
+func (s *Server) DecryptState(key, data []byte) error {
+	// check key
+	if len(key) < 16 {
+		return errors.New("key must be at least 16 bytes long")
+	}
+
+	// decrypt data
+	_, plaintext, err := open(key, data)
+	if err != nil {
+		return errors.New("invalid state")
+	}
+
+	// decode snapshot
+	var snapshot serverSnapshot
+	err = json.Unmarshal(plaintext, &snapshot)
+	if err != nil {
+		return err
+	}
+
+	// verify checksum if available (states exported by older versions are
+	// not wrapped in a snapshot)
+	if snapshot.State != nil {
+		sum := sha256.Sum256(snapshot.State)
+		if subtle.ConstantTimeCompare([]byte(hex.EncodeToString(sum[:])), []byte(snapshot.Checksum)) != 1 {
+			return errors.New("state checksum mismatch")
+		}
+		plaintext = snapshot.State
+	}
+
+	// decode state
+	var state serverState
+	err = json.Unmarshal(plaintext, &state)
+	if err != nil {
+		return err
+	}
+
+	// ensure storage
+	if state.Clients == nil {
+		state.Clients = map[string]*ServerEntity{}
+	}
+	if state.Users == nil {
+		state.Users = map[string]*ServerEntity{}
+	}
+	if state.AccessTokens == nil {
+		state.AccessTokens = map[string]*ServerCredential{}
+	}
+	if state.RefreshTokens == nil {
+		state.RefreshTokens = map[string]*ServerCredential{}
+	}
+	if state.AuthorizationCodes == nil {
+		state.AuthorizationCodes = map[string]*ServerCredential{}
+	}
+	if state.PreAuthorizedCodes == nil {
+		state.PreAuthorizedCodes = map[string]*ServerCredential{}
+	}
+	if state.UsedCodes == nil {
+		state.UsedCodes = map[string]time.Time{}
+	}
+	if state.ClientAliases == nil {
+		state.ClientAliases = map[string]*ServerAlias{}
+	}
+
+	// acquire mutex
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+
+	// compact state
+	skipped := state.compact(s.now())
+
+	// replace state
+	s.Clients = state.Clients
+	s.Users = state.Users
+	s.AccessTokens = state.AccessTokens
+	s.RefreshTokens = state.RefreshTokens
+	s.AuthorizationCodes = state.AuthorizationCodes
+	s.PreAuthorizedCodes = state.PreAuthorizedCodes
+	s.UsedCodes = state.UsedCodes
+	s.ClientAliases = state.ClientAliases
+
+	// update stats
+	s.stateStats.Loads++
+	s.stateStats.Loaded += state.count()
+	s.stateStats.Skipped += skipped
+
+	return nil
+}
+
+// StateStats returns the counters of the states saved and loaded by the server.
+func (s *Server) StateStats() StateStats {
+	// acquire mutex
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+
+	return s.stateStats
+}
+
+func (s *serverState) compact(now time.Time) int {
+	// prepare counter
+	var skipped int
+
+	// compact credentials
+	compact := func(list map[string]*ServerCredential) map[string]*ServerCredential {
+		out := make(map[string]*ServerCredential, len(list))
+		for key, credential := range list {
+			if !credential.ExpiresAt.IsZero() && credential.ExpiresAt.Before(now) {
+				skipped++
+				continue
+			}
+			out[key] = credential
+		}
+		return out
+	}
+	s.AccessTokens = compact(s.AccessTokens)
+	s.RefreshTokens = compact(s.RefreshTokens)
+	s.AuthorizationCodes = compact(s.AuthorizationCodes)
+	s.PreAuthorizedCodes = compact(s.PreAuthorizedCodes)
+
+	// compact used codes
+	usedCodes := make(map[string]time.Time, len(s.UsedCodes))
+	for id, expiresAt := range s.UsedCodes {
+		if expiresAt.Before(now) {
+			skipped++
+			continue
+		}
+		usedCodes[id] = expiresAt
+	}
+	s.UsedCodes = usedCodes
+
+	// compact aliases
+	aliases := make(map[string]*ServerAlias, len(s.ClientAliases))
+	for alias, entry := range s.ClientAliases {
+		if !entry.ExpiresAt.IsZero() && entry.ExpiresAt.Before(now) {
+			skipped++
+			continue
+		}
+		aliases[alias] = entry
+	}
+	s.ClientAliases = aliases
+
+	return skipped
+}
+
+func (s *serverState) count() int {
+	return len(s.Clients) + len(s.Users) + len(s.AccessTokens) + len(s.RefreshTokens) +
+		len(s.AuthorizationCodes) + len(s.PreAuthorizedCodes) + len(s.UsedCodes) + len(s.ClientAliases)
+}
@@ -0,0 +1,129 @@
+package oauth2
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServerEncryptState(t *testing.T) {
+	key := []byte("0123456789abcdef")
+
+	server1 := newTestServer()
+	res, err := server1.issueTokens(true, Scope{"foo"}, "client1", "user1", "")
+	assert.NoError(t, err)
+
+	_, err = server1.IssuePreAuthorizedCode(ServerCredential{
+		ClientID: "client1",
+		Username: "user1",
+		Scope:    Scope{"foo"},
+		TxCode:   "1234",
+	})
+	assert.NoError(t, err)
+
+	data, err := server1.EncryptState(key)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, data)
+	assert.NotContains(t, string(data), "client1")
+
+	server2 := NewServer(server1.Config)
+	err = server2.DecryptState(key, data)
+	assert.NoError(t, err)
+	assert.Len(t, server2.Clients, 2)
+	assert.Len(t, server2.Users, 1)
+	assert.Len(t, server2.AccessTokens, 1)
+	assert.Len(t, server2.RefreshTokens, 1)
+	assert.NotNil(t, server2.AuthorizationCodes)
+	assert.Len(t, server2.PreAuthorizedCodes, 1)
+	assert.NotNil(t, server2.UsedCodes)
+
+	for _, code := range server2.PreAuthorizedCodes {
+		assert.Equal(t, "1234", code.TxCode)
+	}
+
+	req := httptest.NewRequest("GET", "/api", nil)
+	req.Header.Set("Authorization", "Bearer "+res.AccessToken)
+	rec := httptest.NewRecorder()
+	assert.True(t, server2.Authorize(rec, req, Scope{"foo"}))
+
+	err = server2.DecryptState([]byte("fedcba9876543210"), data)
+	assert.EqualError(t, err, "invalid state")
+
+	err = server2.DecryptState([]byte("short"), data)
+	assert.EqualError(t, err, "key must be at least 16 bytes long")
+
+	_, err = server1.EncryptState([]byte("short"))
+	assert.EqualError(t, err, "key must be at least 16 bytes long")
+
+	rec = httptest.NewRecorder()
+	assert.True(t, server2.Authorize(rec, req, Scope{"foo"}))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestServerEncryptStateCompaction(t *testing.T) {
+	key := []byte("0123456789abcdef")
+
+	server1 := newTestServer()
+	server1.issueTokens(true, Scope{"foo"}, "client1", "user1", "")
+	server1.UsedCodes["old"] = time.Now().Add(-time.Minute)
+	server1.UsedCodes["new"] = time.Now().Add(time.Minute)
+
+	server1.AccessTokens["expired"] = &ServerCredential{
+		ClientID:  "client1",
+		ExpiresAt: time.Now().Add(-time.Minute),
+	}
+
+	data, err := server1.EncryptState(key)
+	assert.NoError(t, err)
+	assert.Len(t, server1.AccessTokens, 2)
+	assert.Equal(t, StateStats{
+		Saves:   1,
+		Saved:   6,
+		Skipped: 2,
+	}, server1.StateStats())
+
+	server2 := NewServer(server1.Config)
+	err = server2.DecryptState(key, data)
+	assert.NoError(t, err)
+	assert.Len(t, server2.AccessTokens, 1)
+	assert.Len(t, server2.UsedCodes, 1)
+	assert.Equal(t, StateStats{
+		Loads:  1,
+		Loaded: 6,
+	}, server2.StateStats())
+
+	server2.AdvanceTime(24 * time.Hour)
+	err = server2.DecryptState(key, data)
+	assert.NoError(t, err)
+	assert.Len(t, server2.AccessTokens, 0)
+	assert.Len(t, server2.RefreshTokens, 1)
+	assert.Len(t, server2.UsedCodes, 0)
+	assert.Equal(t, StateStats{
+		Loads:   2,
+		Loaded:  10,
+		Skipped: 2,
+	}, server2.StateStats())
+}
+
+func TestServerDecryptStateChecksum(t *testing.T) {
+	key := []byte("0123456789abcdef")
+
+	server := newTestServer()
+
+	data, err := seal(key, []byte(`{"state":{"clients":{}},"checksum":"00"}`))
+	assert.NoError(t, err)
+
+	err = server.DecryptState(key, data)
+	assert.EqualError(t, err, "state checksum mismatch")
+	assert.Len(t, server.Clients, 2)
+
+	data, err = seal(key, []byte(`{"clients":{}}`))
+	assert.NoError(t, err)
+
+	err = server.DecryptState(key, data)
+	assert.NoError(t, err)
+	assert.Len(t, server.Clients, 0)
+}
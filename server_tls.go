@@ -0,0 +1,129 @@
+package oauth2
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+func (s *Server) checkTLS(r *http.Request) error {
+	// check config
+	if !s.Config.RequireTLS {
+		return nil
+	}
+
+	// check request
+	if s.secureRequest(r) {
+		return nil
+	}
+
+	// allow direct loopback requests if enabled
+	if s.Config.AllowInsecureLoopback && !s.trustedProxy(r) && !forwardedRequest(r) && loopbackRequest(r) {
+		return nil
+	}
+
+	return InvalidRequest("TLS is required")
+}
+
+func (s *Server) secureRequest(r *http.Request) bool {
+	// check connection
+	if r.TLS != nil {
+		return true
+	}
+
+	// check headers of trusted proxies
+	return s.trustedProxy(r) && forwardedSecure(r)
+}
+
+func (s *Server) trustedProxy(r *http.Request) bool {
+	// get ip
+	ip := remoteIP(r)
+	if ip == nil {
+		return false
+	}
+
+	// check proxies
+	for _, proxy := range s.Config.TrustedProxies {
+		if network := parseProxy(proxy); network != nil && network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func parseProxy(proxy string) *net.IPNet {
+	// parse range
+	if strings.Contains(proxy, "/") {
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil
+		}
+		return network
+	}
+
+	// parse address
+	ip := net.ParseIP(proxy)
+	if ip == nil {
+		return nil
+	}
+
+	// get mask
+	bits := 8 * net.IPv6len
+	if ip.To4() != nil {
+		ip = ip.To4()
+		bits = 8 * net.IPv4len
+	}
+
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}
+}
+
+func forwardedSecure(r *http.Request) bool {
+	// check forwarded proto (closest proxy)
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		list := strings.Split(proto, ",")
+		proto = strings.TrimSpace(list[len(list)-1])
+		return strings.EqualFold(proto, "https")
+	}
+
+	// check forwarded header (RFC 7239, closest proxy)
+	if forwarded := r.Header.Get("Forwarded"); forwarded != "" {
+		list := strings.Split(forwarded, ",")
+		for _, pair := range strings.Split(list[len(list)-1], ";") {
+			pair = strings.TrimSpace(pair)
+			if len(pair) > 6 && strings.EqualFold(pair[:6], "proto=") {
+				return strings.EqualFold(strings.Trim(pair[6:], `"`), "https")
+			}
+		}
+	}
+
+	return false
+}
+
+func forwardedRequest(r *http.Request) bool {
+	// check headers
+	for _, name := range []string{"Forwarded", "X-Forwarded-For", "X-Forwarded-Proto", "X-Real-Ip"} {
+		if r.Header.Get(name) != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func loopbackRequest(r *http.Request) bool {
+	// check ip
+	ip := remoteIP(r)
+
+	return ip != nil && ip.IsLoopback()
+}
+
+func remoteIP(r *http.Request) net.IP {
+	// get host
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	return net.ParseIP(host)
+}
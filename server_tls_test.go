@@ -0,0 +1,121 @@
+package oauth2
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/256dpi/oauth2/v2/oauth2test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServerRequireTLS(t *testing.T) {
+	server := newTestServer()
+	server.Config.RequireTLS = true
+
+	token := func(header map[string]string) *oauth2test.Response {
+		return oauth2test.Do(server, &oauth2test.Request{
+			Method:   "POST",
+			Path:     "/oauth2/token",
+			Header:   header,
+			Username: "client1",
+			Password: "foo",
+			Form: map[string]string{
+				"grant_type": ClientCredentialsGrantType,
+				"scope":      "foo",
+			},
+		})
+	}
+
+	res := token(nil)
+	assert.Equal(t, http.StatusBadRequest, res.Status)
+	assert.Equal(t, "invalid_request", res.String("error"))
+	assert.Equal(t, "TLS is required", res.String("error_description"))
+
+	res = token(map[string]string{"X-Forwarded-Proto": "http"})
+	assert.Equal(t, http.StatusBadRequest, res.Status)
+
+	res = token(map[string]string{"X-Forwarded-Proto": "https"})
+	assert.Equal(t, http.StatusBadRequest, res.Status)
+
+	res = token(map[string]string{"Forwarded": `for=192.0.2.60;proto="https";by=203.0.113.43`})
+	assert.Equal(t, http.StatusBadRequest, res.Status)
+
+	res = oauth2test.Do(server, &oauth2test.Request{
+		Method: "POST",
+		Path:   "/oauth2/authorize",
+		Form: map[string]string{
+			"response_type": CodeResponseType,
+			"client_id":     "client1",
+			"redirect_uri":  "http://example.com/callback1",
+			"scope":         "foo",
+			"username":      "user1",
+			"password":      "foo",
+		},
+	})
+	assert.Equal(t, http.StatusBadRequest, res.Status)
+	assert.Equal(t, "invalid_request", res.String("error"))
+
+	request := func(remoteAddr string, secure bool, header ...string) int {
+		form := url.Values{
+			"grant_type": []string{ClientCredentialsGrantType},
+			"scope":      []string{"foo"},
+		}
+		req := httptest.NewRequest("POST", "/oauth2/token", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth("client1", "foo")
+		req.RemoteAddr = remoteAddr
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		if secure {
+			req.TLS = &tls.ConnectionState{}
+		}
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, request("192.0.2.1:1234", true))
+	assert.Equal(t, http.StatusBadRequest, request("127.0.0.1:1234", false))
+	assert.Equal(t, http.StatusBadRequest, request("192.0.2.1:1234", false, "X-Forwarded-Proto", "https"))
+
+	server.Config.TrustedProxies = []string{"192.0.2.0/24"}
+
+	assert.Equal(t, http.StatusOK, request("192.0.2.1:1234", false, "X-Forwarded-Proto", "https"))
+	assert.Equal(t, http.StatusBadRequest, request("192.0.2.1:1234", false, "X-Forwarded-Proto", "https, http"))
+	assert.Equal(t, http.StatusOK, request("192.0.2.1:1234", false, "Forwarded", `for=192.0.2.60;proto="https";by=203.0.113.43`))
+	assert.Equal(t, http.StatusBadRequest, request("198.51.100.1:1234", false, "X-Forwarded-Proto", "https"))
+
+	server.Config.TrustedProxies = []string{"192.0.2.2"}
+
+	assert.Equal(t, http.StatusBadRequest, request("192.0.2.1:1234", false, "X-Forwarded-Proto", "https"))
+	assert.Equal(t, http.StatusOK, request("192.0.2.2:1234", false, "X-Forwarded-Proto", "https"))
+
+	server.Config.TrustedProxies = nil
+
+	server.Config.AllowInsecureLoopback = true
+
+	assert.Equal(t, http.StatusOK, request("127.0.0.1:1234", false))
+	assert.Equal(t, http.StatusOK, request("[::1]:1234", false))
+	assert.Equal(t, http.StatusBadRequest, request("192.0.2.1:1234", false))
+	assert.Equal(t, http.StatusBadRequest, request("127.0.0.1:1234", false, "X-Forwarded-For", "192.0.2.1"))
+
+	server.Config.TrustedProxies = []string{"127.0.0.1"}
+
+	assert.Equal(t, http.StatusBadRequest, request("127.0.0.1:1234", false))
+	assert.Equal(t, http.StatusOK, request("127.0.0.1:1234", false, "X-Forwarded-Proto", "https"))
+}
+
+func TestServerTrustedProxiesValidate(t *testing.T) {
+	config := DefaultServerConfig([]byte("0123456789abcdef"), Scope{"foo"})
+	config.TrustedProxies = []string{"10.0.0.1", "10.0.0.0/8", "::1"}
+	assert.NoError(t, config.Validate())
+
+	config.TrustedProxies = []string{"foo", "10.0.0.0/33"}
+	assert.Equal(t, `invalid server config: trusted proxy "foo" is not an IP address or CIDR range; `+
+		`trusted proxy "10.0.0.0/33" is not an IP address or CIDR range`, config.Validate().Error())
+}
@@ -0,0 +1,248 @@
+package oauth2
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SecurityEventContentType is the content type of security event tokens.
+const SecurityEventContentType = "application/secevent+jwt"
+
+// The event types of the emitted security event tokens.
+const (
+	TokensRevokedEventURI    = "https://schemas.openid.net/secevent/risc/event-type/tokens-revoked"
+	AccountDisabledEventURI  = "https://schemas.openid.net/secevent/risc/event-type/account-disabled"
+	CredentialChangeEventURI = "https://schemas.openid.net/secevent/caep/event-type/credential-change"
+)
+
+// SecurityEventReceiver is a registered receiver of security event tokens.
+type SecurityEventReceiver struct {
+	// The audience of the tokens, e.g. the URL of the receiver.
+	Audience string
+
+	// The function that is called with every signed token. It is called by
+	// the background worker of the emitter and may block, e.g. to push the
+	// token (see PushSecurityEvent). Receivers are called sequentially.
+	Deliver func(set string)
+}
+
+// SecurityEventEmitter is a server event handler that emits security event
+// tokens (RFC 8417) for revoked tokens, disabled clients and changed client
+// secrets to the registered receivers. Other events are ignored. Events are
+// queued and delivered asynchronously so that the server is not blocked by
+// slow receivers.
+type SecurityEventEmitter struct {
+	// The issuer of the tokens.
+	Issuer string
+
+	// The key used to sign the tokens (HS256).
+	Key []byte
+
+	// The registered receivers.
+	Receivers []SecurityEventReceiver
+
+	// The number of events that are buffered for delivery. Defaults to 1024.
+	QueueSize int
+
+	// The callback that is called with events that are dropped because the
+	// queue is full or the emitter has been closed.
+	OnDrop func(event ServerEvent)
+
+	events chan ServerEvent
+	once   sync.Once
+	mutex  sync.RWMutex
+	closed bool
+	done   chan struct{}
+}
+
+// HandleEvent implements the ServerEventHandler interface. It does not block
+// and drops the event if the queue is full.
+func (e *SecurityEventEmitter) HandleEvent(event ServerEvent) {
+	// check event type
+	if securityEventURI(event.Type) == "" {
+		return
+	}
+
+	// start worker
+	e.once.Do(e.start)
+
+	// acquire mutex
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	// queue event
+	if !e.closed {
+		select {
+		case e.events <- event:
+			return
+		default:
+		}
+	}
+
+	// call callback
+	if e.OnDrop != nil {
+		e.OnDrop(event)
+	}
+}
+
+// Close will stop accepting events and wait until all queued events have been
+// delivered.
+func (e *SecurityEventEmitter) Close() {
+	// start worker
+	e.once.Do(e.start)
+
+	// acquire mutex
+	e.mutex.Lock()
+
+	// close queue
+	if !e.closed {
+		e.closed = true
+		close(e.events)
+	}
+
+	// release mutex
+	e.mutex.Unlock()
+
+	// await worker
+	<-e.done
+}
+
+func (e *SecurityEventEmitter) start() {
+	// get queue size
+	size := e.QueueSize
+	if size <= 0 {
+		size = 1024
+	}
+
+	// prepare queue
+	e.events = make(chan ServerEvent, size)
+	e.done = make(chan struct{})
+
+	// run worker
+	go e.worker()
+}
+
+func (e *SecurityEventEmitter) worker() {
+	// signal exit
+	defer close(e.done)
+
+	// deliver events
+	for event := range e.events {
+		e.deliver(event)
+	}
+}
+
+func securityEventURI(typ ServerEventType) string {
+	switch typ {
+	case TokenRevokedEvent:
+		return TokensRevokedEventURI
+	case ClientDisabledEvent:
+		return AccountDisabledEventURI
+	case CredentialChangedEvent:
+		return CredentialChangeEventURI
+	default:
+		return ""
+	}
+}
+
+func (e *SecurityEventEmitter) deliver(event ServerEvent) {
+	// get event uri
+	uri := securityEventURI(event.Type)
+
+	// prepare subject
+	subject := map[string]string{
+		"format": "opaque",
+		"id":     event.ClientID,
+	}
+	if event.Username != "" {
+		subject["id"] = event.Username
+	}
+
+	// prepare payload
+	payload := map[string]string{}
+	if event.ClientID != "" {
+		payload["client_id"] = event.ClientID
+	}
+	if event.TokenType != "" {
+		payload["token_type"] = event.TokenType
+	}
+
+	// add required CAEP members, credential changes are client secret updates
+	if uri == CredentialChangeEventURI {
+		payload["credential_type"] = "password"
+		payload["change_type"] = "update"
+	}
+
+	// deliver to all receivers
+	for _, receiver := range e.Receivers {
+		// generate id
+		id, err := generateKey(16)
+		if err != nil {
+			continue
+		}
+
+		// prepare claims
+		claims := Claims{
+			"iss":    e.Issuer,
+			"aud":    Audience{receiver.Audience},
+			"jti":    b64.EncodeToString(id),
+			"sub_id": subject,
+			"events": map[string]interface{}{
+				uri: payload,
+			},
+		}
+		claims.SetTime("iat", event.Time)
+
+		// sign token
+		set, err := signJWS(e.Key, "secevent+jwt", claims)
+		if err != nil {
+			continue
+		}
+
+		// deliver token
+		receiver.Deliver(set)
+	}
+}
+
+// PushSecurityEvent will deliver the specified security event token to the
+// specified endpoint using push-based delivery as defined by RFC 8935.
+func PushSecurityEvent(ctx context.Context, client *http.Client, uri, set string) error {
+	return pushSecurityEvent(ctx, client, uri, set, nil)
+}
+
+func pushSecurityEvent(ctx context.Context, client *http.Client, uri, set string, secret []byte) error {
+	// create request
+	req, err := http.NewRequest("POST", uri, strings.NewReader(set))
+	if err != nil {
+		return err
+	}
+
+	// set headers
+	req.Header.Set("Content-Type", SecurityEventContentType)
+	req.Header.Set("Accept", "application/json")
+
+	// sign request if requested
+	if secret != nil {
+		req.Header.Set(WebhookSignatureHeader, SignWebhook(secret, []byte(set), time.Now()))
+	}
+
+	// perform request
+	res, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+
+	// ensure body is closed
+	defer res.Body.Close()
+
+	// check status
+	if res.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("unexpected status %d from %s", res.StatusCode, uri)
+	}
+
+	return nil
+}
@@ -0,0 +1,144 @@
+package oauth2
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecurityEventEmitter(t *testing.T) {
+	var sets []string
+	emitter := &SecurityEventEmitter{
+		Issuer: "http://auth.server",
+		Key:    testSecret,
+		Receivers: []SecurityEventReceiver{
+			{
+				Audience: "http://receiver",
+				Deliver: func(set string) {
+					sets = append(sets, set)
+				},
+			},
+		},
+	}
+
+	server := newTestServer()
+	server.Config.EventHandler = emitter
+
+	res, err := server.issueTokens(false, Scope{"foo"}, "client1", "user1", "")
+	assert.NoError(t, err)
+	assert.Empty(t, sets)
+
+	assert.NoError(t, server.revoke(RevocationTask{ClientID: "client1", Token: res.AccessToken}))
+	assert.NoError(t, server.ChangeClientSecret("client1", "bar"))
+	assert.NoError(t, server.DisableClient("client1", false))
+
+	emitter.Close()
+	assert.Len(t, sets, 3)
+	assert.Equal(t, "bar", server.Clients["client1"].Secret)
+
+	var claims Claims
+	assert.NoError(t, VerifyJWS(testSecret, sets[0], &claims))
+	assert.Equal(t, "http://auth.server", claims.GetString("iss"))
+	assert.Equal(t, "http://receiver", claims.GetString("aud"))
+	assert.NotEmpty(t, claims.GetString("jti"))
+	assert.NotZero(t, claims.GetInt64("iat"))
+	assert.Equal(t, map[string]interface{}{
+		"format": "opaque",
+		"id":     "user1",
+	}, claims["sub_id"])
+	assert.Equal(t, map[string]interface{}{
+		TokensRevokedEventURI: map[string]interface{}{
+			"client_id":  "client1",
+			"token_type": "access_token",
+		},
+	}, claims["events"])
+
+	claims = nil
+	assert.NoError(t, VerifyJWS(testSecret, sets[1], &claims))
+	assert.Equal(t, map[string]interface{}{
+		CredentialChangeEventURI: map[string]interface{}{
+			"client_id":       "client1",
+			"credential_type": "password",
+			"change_type":     "update",
+		},
+	}, claims["events"])
+
+	claims = nil
+	assert.NoError(t, VerifyJWS(testSecret, sets[2], &claims))
+	assert.Equal(t, map[string]interface{}{
+		"format": "opaque",
+		"id":     "client1",
+	}, claims["sub_id"])
+	assert.Equal(t, map[string]interface{}{
+		AccountDisabledEventURI: map[string]interface{}{
+			"client_id": "client1",
+		},
+	}, claims["events"])
+
+	assert.Error(t, server.ChangeClientSecret("foo", "bar"))
+}
+
+func TestSecurityEventEmitterQueue(t *testing.T) {
+	release := make(chan struct{})
+	var sets []string
+	var dropped []ServerEvent
+	emitter := &SecurityEventEmitter{
+		Key: testSecret,
+		Receivers: []SecurityEventReceiver{
+			{
+				Deliver: func(set string) {
+					<-release
+					sets = append(sets, set)
+				},
+			},
+		},
+		QueueSize: 1,
+		OnDrop: func(event ServerEvent) {
+			dropped = append(dropped, event)
+		},
+	}
+
+	emitter.HandleEvent(ServerEvent{Type: TokenIssuedEvent})
+	assert.Empty(t, dropped)
+
+	for i := 0; i < 3; i++ {
+		emitter.HandleEvent(ServerEvent{Type: TokenRevokedEvent, ClientID: "client1"})
+	}
+	assert.NotEmpty(t, dropped)
+
+	close(release)
+	emitter.Close()
+	assert.Equal(t, 3, len(sets)+len(dropped))
+
+	emitter.HandleEvent(ServerEvent{Type: TokenRevokedEvent, ClientID: "client1"})
+	assert.Equal(t, 4, len(sets)+len(dropped))
+}
+
+func TestPushSecurityEvent(t *testing.T) {
+	var body, contentType string
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		body = string(data)
+		contentType = r.Header.Get("Content-Type")
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer receiver.Close()
+
+	err := PushSecurityEvent(context.Background(), http.DefaultClient, receiver.URL, "foo.bar.baz")
+	assert.NoError(t, err)
+	assert.Equal(t, "foo.bar.baz", body)
+	assert.Equal(t, SecurityEventContentType, contentType)
+
+	err = PushSecurityEvent(context.Background(), http.DefaultClient, receiver.URL+"/%", "foo")
+	assert.Error(t, err)
+
+	failing := httptest.NewServer(http.NotFoundHandler())
+	defer failing.Close()
+
+	err = PushSecurityEvent(context.Background(), http.DefaultClient, failing.URL, "foo")
+	assert.EqualError(t, err, "unexpected status 404 from "+failing.URL)
+}
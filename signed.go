@@ -0,0 +1,133 @@
+package oauth2
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+)
+
+// SignedToken implements tokens whose random key is signed with an asymmetric
+// Ed25519 or ECDSA P-256 key. In contrast to HMAC tokens, signed tokens can be
+// verified using only the public key.
+type SignedToken struct {
+	Key       []byte
+	Signature []byte
+}
+
+// GenerateSignedToken will return a new token that is constructed using a
+// random key of the specified length and signed using the specified Ed25519
+// or ECDSA P-256 private key.
+func GenerateSignedToken(signer crypto.Signer, length int) (*SignedToken, error) {
+	// generate key
+	key, err := generateKey(length)
+	if err != nil {
+		return nil, err
+	}
+
+	// sign key
+	var signature []byte
+	switch privateKey := signer.(type) {
+	case ed25519.PrivateKey:
+		signature = ed25519.Sign(privateKey, key)
+	case *ecdsa.PrivateKey:
+		// check curve
+		if privateKey.Curve != elliptic.P256() {
+			return nil, errors.New("unsupported curve")
+		}
+
+		// sign digest
+		digest := sha256.Sum256(key)
+		r, s, err := ecdsa.Sign(randSource, privateKey, digest[:])
+		if err != nil {
+			return nil, err
+		}
+
+		// encode signature
+		signature = make([]byte, 64)
+		rb, sb := r.Bytes(), s.Bytes()
+		copy(signature[32-len(rb):32], rb)
+		copy(signature[64-len(sb):], sb)
+	default:
+		return nil, errors.New("unsupported private key")
+	}
+
+	return &SignedToken{
+		Key:       key,
+		Signature: signature,
+	}, nil
+}
+
+// MustGenerateSignedToken will generate a token using GenerateSignedToken and
+// panic instead of returning an error.
+func MustGenerateSignedToken(signer crypto.Signer, length int) *SignedToken {
+	token, err := GenerateSignedToken(signer, length)
+	if err != nil {
+		panic(err)
+	}
+
+	return token
+}
+
+// ParseSignedToken will parse a token that is in its string representation
+// and verify it using the specified Ed25519 or ECDSA P-256 public key.
+func ParseSignedToken(publicKey crypto.PublicKey, str string) (*SignedToken, error) {
+	// split token
+	key, signature, err := splitToken(str)
+	if err != nil {
+		return nil, err
+	}
+
+	// construct token
+	token := &SignedToken{
+		Key:       key,
+		Signature: signature,
+	}
+
+	// verify signature
+	if !token.Valid(publicKey) {
+		return nil, errors.New("invalid token supplied")
+	}
+
+	return token, nil
+}
+
+// Valid returns true when the token's signature is a valid signature of its
+// key created by the private key of the specified public key.
+func (t *SignedToken) Valid(publicKey crypto.PublicKey) bool {
+	switch publicKey := publicKey.(type) {
+	case ed25519.PublicKey:
+		return len(publicKey) == ed25519.PublicKeySize && ed25519.Verify(publicKey, t.Key, t.Signature)
+	case *ecdsa.PublicKey:
+		// check signature
+		if publicKey.Curve != elliptic.P256() || len(t.Signature) != 64 {
+			return false
+		}
+
+		// verify signature
+		digest := sha256.Sum256(t.Key)
+		r := new(big.Int).SetBytes(t.Signature[:32])
+		s := new(big.Int).SetBytes(t.Signature[32:])
+		return ecdsa.Verify(publicKey, digest[:], r, s)
+	}
+
+	return false
+}
+
+// KeyString returns a string (base64) representation of the key.
+func (t *SignedToken) KeyString() string {
+	return b64.EncodeToString(t.Key)
+}
+
+// SignatureString returns a string (base64) representation of the signature.
+func (t *SignedToken) SignatureString() string {
+	return b64.EncodeToString(t.Signature)
+}
+
+// String returns a string representation of the whole token.
+func (t *SignedToken) String() string {
+	return t.KeyString() + "." + t.SignatureString()
+}
@@ -0,0 +1,71 @@
+package oauth2
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignedToken(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	for _, signer := range []crypto.Signer{edKey, ecKey} {
+		token1, err := GenerateSignedToken(signer, 16)
+		assert.NoError(t, err)
+		assert.Len(t, token1.Key, 16)
+		assert.Len(t, token1.Signature, 64)
+		assert.NotEmpty(t, token1.String())
+
+		token2, err := ParseSignedToken(signer.Public(), token1.String())
+		assert.NoError(t, err)
+		assert.Equal(t, token1.Key, token2.Key)
+		assert.Equal(t, token1.Signature, token2.Signature)
+
+		token2, err = ParseSignedToken(signer.Public(), token1.String()+"foo")
+		assert.Error(t, err)
+		assert.Nil(t, token2)
+
+		token2, err = ParseSignedToken(signer.Public(), MustGenerateHS256Token(testSecret, 16).String())
+		assert.Error(t, err)
+		assert.Nil(t, token2)
+	}
+
+	token := MustGenerateSignedToken(edKey, 16)
+	assert.False(t, token.Valid(ecKey.Public()))
+	assert.False(t, token.Valid("foo"))
+}
+
+func TestGenerateSignedTokenError(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	assert.NoError(t, err)
+
+	token, err := GenerateSignedToken(ecKey, 16)
+	assert.EqualError(t, err, "unsupported curve")
+	assert.Nil(t, token)
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	currentSource := randSource
+	randSource = strings.NewReader("")
+
+	token, err = GenerateSignedToken(edKey, 16)
+	assert.Error(t, err)
+	assert.Nil(t, token)
+
+	assert.Panics(t, func() {
+		MustGenerateSignedToken(edKey, 16)
+	})
+
+	randSource = currentSource
+}
@@ -0,0 +1,131 @@
+package oauth2
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// GrantStats contains the statistics of the token requests for a single grant
+// type. The percentiles are approximated using exponential histogram buckets
+// and represent the upper bound of the bucket.
+type GrantStats struct {
+	Count  int
+	Errors int
+
+	P50 time.Duration
+	P90 time.Duration
+	P99 time.Duration
+}
+
+const statsBuckets = 32
+
+type grantHistogram struct {
+	count   int
+	errors  int
+	buckets [statsBuckets]int
+}
+
+type statsCollector struct {
+	grants map[string]*grantHistogram
+	mutex  sync.Mutex
+}
+
+func (c *statsCollector) record(grantType string, latency time.Duration, failed bool) {
+	// acquire mutex
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// prepare map
+	if c.grants == nil {
+		c.grants = map[string]*grantHistogram{}
+	}
+
+	// get histogram
+	histogram, ok := c.grants[grantType]
+	if !ok {
+		histogram = &grantHistogram{}
+		c.grants[grantType] = histogram
+	}
+
+	// update counters
+	histogram.count++
+	if failed {
+		histogram.errors++
+	}
+
+	// find bucket
+	bucket := 0
+	for bucket < statsBuckets-1 && latency > statsBucketBound(bucket) {
+		bucket++
+	}
+
+	// update bucket
+	histogram.buckets[bucket]++
+}
+
+func (c *statsCollector) snapshot() map[string]GrantStats {
+	// acquire mutex
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// collect stats
+	stats := make(map[string]GrantStats, len(c.grants))
+	for grantType, histogram := range c.grants {
+		stats[grantType] = GrantStats{
+			Count:  histogram.count,
+			Errors: histogram.errors,
+			P50:    histogram.percentile(0.5),
+			P90:    histogram.percentile(0.9),
+			P99:    histogram.percentile(0.99),
+		}
+	}
+
+	return stats
+}
+
+func (h *grantHistogram) percentile(p float64) time.Duration {
+	// get rank
+	rank := int(p*float64(h.count) + 0.5)
+	if rank < 1 {
+		rank = 1
+	}
+
+	// find bucket
+	seen := 0
+	for i, n := range h.buckets {
+		seen += n
+		if seen >= rank {
+			return statsBucketBound(i)
+		}
+	}
+
+	return 0
+}
+
+func statsBucketBound(bucket int) time.Duration {
+	return time.Microsecond << uint(bucket)
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	// keep first status
+	if r.status == 0 {
+		r.status = status
+	}
+
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(data []byte) (int, error) {
+	// set implicit status
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+
+	return r.ResponseWriter.Write(data)
+}
@@ -0,0 +1,32 @@
+package oauth2
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatsCollector(t *testing.T) {
+	var c statsCollector
+	assert.Empty(t, c.snapshot())
+
+	for i := 0; i < 100; i++ {
+		c.record(PasswordGrantType, time.Duration(i+1)*time.Millisecond, i%10 == 0)
+	}
+	c.record(RefreshTokenGrantType, time.Hour, false)
+
+	stats := c.snapshot()
+	assert.Len(t, stats, 2)
+
+	password := stats[PasswordGrantType]
+	assert.Equal(t, 100, password.Count)
+	assert.Equal(t, 10, password.Errors)
+	assert.Equal(t, 65536*time.Microsecond, password.P50)
+	assert.Equal(t, 131072*time.Microsecond, password.P90)
+	assert.Equal(t, 131072*time.Microsecond, password.P99)
+
+	refresh := stats[RefreshTokenGrantType]
+	assert.Equal(t, 1, refresh.Count)
+	assert.Equal(t, statsBucketBound(statsBuckets-1), refresh.P50)
+}
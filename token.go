@@ -15,22 +15,32 @@ import (
 // A TokenRequest is typically returned by ParseTokenRequest and holds all
 // information necessary to handle a token request.
 type TokenRequest struct {
-	GrantType    string
-	Scope        Scope
-	ClientID     string
-	ClientSecret string
-	Username     string
-	Password     string
-	RefreshToken string
-	RedirectURI  string
-	Code         string
+	GrantType     string
+	Scope         Scope
+	ClientID      string
+	ClientSecret  string
+	Username      string
+	Password      string
+	RefreshToken  string
+	RedirectURI   string
+	Code          string
+	CodeVerifier  string
+	Assertion     string
+	InstanceProof string
+	AuthMethod    ClientAuthMethod
+
+	// The pre-authorized code and the optional transaction code (PIN) of the
+	// pre-authorized code grant.
+	PreAuthorizedCode string
+	TxCode            string
+
+	// The requested fine-grained permissions (RFC 9396).
+	AuthorizationDetails []AuthorizationDetail
 }
 
 // ParseTokenRequest parses an incoming request and returns a TokenRequest.
 // The functions validates basic constraints given by the OAuth2 spec.
-//
-// Note: Obtaining the client id and secret from the request body (form data)
-// is not implemented by default due to security considerations.
+// The client credentials are obtained using ParseClientCredentials.
 func ParseTokenRequest(r *http.Request) (*TokenRequest, error) {
 	// check method
 	if r.Method != "POST" {
@@ -53,11 +63,7 @@ func ParseTokenRequest(r *http.Request) (*TokenRequest, error) {
 	scope := ParseScope(r.PostForm.Get("scope"))
 
 	// get client id and secret
-	clientID, clientSecret, ok := r.BasicAuth()
-	if !ok {
-		clientID = r.PostForm.Get("client_id")
-		clientSecret = r.PostForm.Get("client_secret")
-	}
+	clientID, clientSecret, authMethod := ParseClientCredentials(r)
 
 	// check client id
 	if clientID == "" {
@@ -88,19 +94,88 @@ func ParseTokenRequest(r *http.Request) (*TokenRequest, error) {
 	// get code
 	code := r.PostForm.Get("code")
 
+	// get code verifier
+	codeVerifier := r.PostForm.Get("code_verifier")
+
+	// get assertion
+	assertion := r.PostForm.Get("assertion")
+
+	// get instance proof
+	instanceProof := r.Header.Get(InstanceProofHeader)
+
+	// get pre-authorized and transaction code
+	preAuthorizedCode := r.PostForm.Get("pre-authorized_code")
+	txCode := r.PostForm.Get("tx_code")
+
+	// get authorization details
+	authorizationDetails, err := ParseAuthorizationDetails(r.PostForm.Get("authorization_details"))
+	if err != nil {
+		return nil, err
+	}
+
 	return &TokenRequest{
-		GrantType:    grantType,
-		Scope:        scope,
-		ClientID:     clientID,
-		ClientSecret: clientSecret,
-		Username:     username,
-		Password:     password,
-		RefreshToken: refreshToken,
-		RedirectURI:  redirectURIString,
-		Code:         code,
+		GrantType:            grantType,
+		Scope:                scope,
+		ClientID:             clientID,
+		ClientSecret:         clientSecret,
+		Username:             username,
+		Password:             password,
+		RefreshToken:         refreshToken,
+		RedirectURI:          redirectURIString,
+		Code:                 code,
+		CodeVerifier:         codeVerifier,
+		Assertion:            assertion,
+		InstanceProof:        instanceProof,
+		AuthMethod:           authMethod,
+		PreAuthorizedCode:    preAuthorizedCode,
+		TxCode:               txCode,
+		AuthorizationDetails: authorizationDetails,
 	}, nil
 }
 
+// ParseMultipartTokenRequest parses an incoming request with a
+// multipart/form-data body and returns a TokenRequest. The values of the body
+// are parsed using the specified maximum memory and then processed like an
+// url encoded body using ParseTokenRequest.
+//
+// Note: The OAuth2 spec requires token requests to be url encoded. This
+// function should only be used to support legacy clients.
+func ParseMultipartTokenRequest(r *http.Request, maxMemory int64) (*TokenRequest, error) {
+	// check method
+	if r.Method != "POST" {
+		return nil, InvalidRequest("invalid HTTP method")
+	}
+
+	// check content type
+	if !IsMultipartRequest(r) {
+		return nil, InvalidRequest("expected multipart form data")
+	}
+
+	// parse multipart form
+	err := r.ParseMultipartForm(maxMemory)
+	if err != nil {
+		return nil, InvalidRequest("malformed multipart form data")
+	}
+
+	// remove temporary files
+	_ = r.MultipartForm.RemoveAll()
+
+	// set post form
+	r.PostForm = make(url.Values)
+	for key, values := range r.MultipartForm.Value {
+		r.PostForm[key] = values
+	}
+
+	return ParseTokenRequest(r)
+}
+
+// IsMultipartRequest returns whether the request has a multipart/form-data
+// body.
+func IsMultipartRequest(r *http.Request) bool {
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return contentType == "multipart/form-data"
+}
+
 // A TokenResponse is typically constructed after a token request has been
 // authenticated and authorized to return an access token, a potential refresh
 // token and more detailed information.
@@ -111,8 +186,16 @@ type TokenResponse struct {
 	RefreshToken string `json:"refresh_token,omitempty"`
 	Scope        Scope  `json:"scope,omitempty"`
 	State        string `json:"state,omitempty"`
+	IssuedAt     int64  `json:"issued_at,omitempty"`
+
+	// The granted fine-grained permissions (RFC 9396).
+	AuthorizationDetails []AuthorizationDetail `json:"authorization_details,omitempty"`
 
 	RedirectURI string `json:"-"`
+
+	// The status used if the response is redirected. Defaults to 303 See
+	// Other (see WriteRedirectStatus).
+	RedirectStatus int `json:"-"`
 }
 
 // NewTokenResponse constructs a TokenResponse.
@@ -163,6 +246,16 @@ func (r *TokenResponse) Map() map[string]string {
 		m["state"] = r.State
 	}
 
+	// add issued at if present
+	if r.IssuedAt != 0 {
+		m["issued_at"] = strconv.FormatInt(r.IssuedAt, 10)
+	}
+
+	// add authorization details if present
+	if len(r.AuthorizationDetails) > 0 {
+		m["authorization_details"] = encodeAuthorizationDetails(r.AuthorizationDetails)
+	}
+
 	return m
 }
 
@@ -172,12 +265,79 @@ func (r *TokenResponse) Map() map[string]string {
 func WriteTokenResponse(w http.ResponseWriter, r *TokenResponse) error {
 	// write redirect if requested
 	if r.RedirectURI != "" {
-		return WriteRedirect(w, r.RedirectURI, r.Map(), true)
+		return WriteRedirectStatus(w, r.RedirectURI, r.Map(), true, r.RedirectStatus)
 	}
 
 	return Write(w, r, http.StatusOK)
 }
 
+// FormContentType is the content type of url encoded form bodies.
+const FormContentType = "application/x-www-form-urlencoded"
+
+// WriteFormTokenResponse will write the specified response as an url encoded
+// form body. If the RedirectURI field is present on the response a regular
+// redirection is written instead.
+//
+// Note: The OAuth2 spec requires token responses to be JSON encoded. This
+// function should only be used to support legacy clients.
+func WriteFormTokenResponse(w http.ResponseWriter, r *TokenResponse) error {
+	return WriteFormTokenResponseToSink(HTTPSink{w}, r)
+}
+
+// WriteFormTokenResponseToSink will write a form response like
+// WriteFormTokenResponse to the specified sink.
+func WriteFormTokenResponseToSink(sink ResponseSink, r *TokenResponse) error {
+	// write redirect if requested
+	if r.RedirectURI != "" {
+		return WriteRedirectToSink(sink, r.RedirectURI, r.Map(), true, r.RedirectStatus)
+	}
+
+	// prepare values
+	values := url.Values{}
+	for key, value := range r.Map() {
+		values.Set(key, value)
+	}
+
+	// set required headers
+	sink.Header().Set("Content-Type", FormContentType)
+	sink.Header().Set("Cache-Control", "no-store")
+	sink.Header().Set("Pragma", "no-cache")
+
+	// write form
+	return sink.WriteResponse(http.StatusOK, []byte(values.Encode()))
+}
+
+// WriteSignedTokenResponse will write the specified response as a JWS signed
+// with the specified key (see SignJWS) and the "application/jwt" content type.
+// If the RedirectURI field is present on the response a regular redirection is
+// written instead.
+func WriteSignedTokenResponse(w http.ResponseWriter, r *TokenResponse, key []byte) error {
+	return WriteSignedTokenResponseToSink(HTTPSink{w}, r, key)
+}
+
+// WriteSignedTokenResponseToSink will write a signed response like
+// WriteSignedTokenResponse to the specified sink.
+func WriteSignedTokenResponseToSink(sink ResponseSink, r *TokenResponse, key []byte) error {
+	// write redirect if requested
+	if r.RedirectURI != "" {
+		return WriteRedirectToSink(sink, r.RedirectURI, r.Map(), true, r.RedirectStatus)
+	}
+
+	// sign response
+	jws, err := SignJWS(key, r)
+	if err != nil {
+		return err
+	}
+
+	// set required headers
+	sink.Header().Set("Content-Type", JWTContentType)
+	sink.Header().Set("Cache-Control", "no-store")
+	sink.Header().Set("Pragma", "no-cache")
+
+	// write jws
+	return sink.WriteResponse(http.StatusOK, []byte(jws))
+}
+
 // TokenRequestValues will return the form values for the provided request.
 func TokenRequestValues(r TokenRequest) url.Values {
 	// prepare slice
@@ -189,6 +349,11 @@ func TokenRequestValues(r TokenRequest) url.Values {
 		r.RefreshToken,
 		url.QueryEscape(r.RedirectURI),
 		r.Code,
+		r.CodeVerifier,
+		r.Assertion,
+		r.PreAuthorizedCode,
+		r.TxCode,
+		encodeAuthorizationDetails(r.AuthorizationDetails),
 	}
 
 	// prepare values
@@ -229,6 +394,34 @@ func TokenRequestValues(r TokenRequest) url.Values {
 		values["code"] = slice[6:7]
 	}
 
+	// set code verifier if available
+	if r.CodeVerifier != "" {
+		values["code_verifier"] = slice[7:8]
+	}
+
+	// set assertion if available
+	if r.Assertion != "" {
+		values["assertion"] = slice[8:9]
+	}
+
+	// set pre-authorized code if available
+	if r.PreAuthorizedCode != "" {
+		values["pre-authorized_code"] = slice[9:10]
+	}
+
+	// set transaction code if available
+	if r.TxCode != "" {
+		values["tx_code"] = slice[10:11]
+	}
+
+	// set authorization details if available
+	if len(r.AuthorizationDetails) > 0 {
+		values["authorization_details"] = slice[11:12]
+	}
+
+	// add client credentials if sent in the body
+	addClientCredentials(values, r.ClientID, r.ClientSecret, r.AuthMethod)
+
 	return values
 }
 
@@ -244,10 +437,15 @@ func BuildTokenRequest(uri string, r TokenRequest) (*http.Request, error) {
 	}
 
 	// set basic auth if available
-	if r.ClientID != "" || r.ClientSecret != "" {
+	if useBasicAuth(r.ClientID, r.ClientSecret, r.AuthMethod) {
 		req.SetBasicAuth(r.ClientID, r.ClientSecret)
 	}
 
+	// set instance proof if available
+	if r.InstanceProof != "" {
+		req.Header.Set(InstanceProofHeader, r.InstanceProof)
+	}
+
 	// set content type
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
@@ -268,6 +466,11 @@ func ParseTokenResponse(res *http.Response, limit int64) (*TokenResponse, error)
 		return nil, err
 	}
 
+	// decode form encoded responses
+	if contentType == FormContentType {
+		return decodeFormTokenResponse(data)
+	}
+
 	// check content type
 	if contentType != "application/json" {
 		return nil, fmt.Errorf("unexpected content type: %q", contentType)
@@ -282,3 +485,101 @@ func ParseTokenResponse(res *http.Response, limit int64) (*TokenResponse, error)
 
 	return &trs, nil
 }
+
+// ParseFormTokenResponse will parse the provided response that carries an url
+// encoded form token response (see WriteFormTokenResponse).
+func ParseFormTokenResponse(res *http.Response, limit int64) (*TokenResponse, error) {
+	// read response
+	data, err := ioutil.ReadAll(io.LimitReader(res.Body, limit))
+	if err != nil {
+		return nil, err
+	}
+
+	// parse content type
+	contentType, _, err := mime.ParseMediaType(res.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, err
+	}
+
+	// check content type
+	if contentType != FormContentType {
+		return nil, fmt.Errorf("unexpected content type: %q", contentType)
+	}
+
+	return decodeFormTokenResponse(data)
+}
+
+func decodeFormTokenResponse(data []byte) (*TokenResponse, error) {
+	// parse form
+	values, err := url.ParseQuery(string(data))
+	if err != nil {
+		return nil, err
+	}
+
+	// prepare response
+	trs := &TokenResponse{
+		TokenType:    values.Get("token_type"),
+		AccessToken:  values.Get("access_token"),
+		RefreshToken: values.Get("refresh_token"),
+		State:        values.Get("state"),
+	}
+
+	// parse expires in
+	if str := values.Get("expires_in"); str != "" {
+		trs.ExpiresIn, err = strconv.Atoi(str)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// parse scope
+	if _, ok := values["scope"]; ok {
+		trs.Scope = ParseScope(values.Get("scope"))
+	}
+
+	// parse issued at
+	if str := values.Get("issued_at"); str != "" {
+		trs.IssuedAt, err = strconv.ParseInt(str, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// parse authorization details
+	trs.AuthorizationDetails, err = ParseAuthorizationDetails(values.Get("authorization_details"))
+	if err != nil {
+		return nil, err
+	}
+
+	return trs, nil
+}
+
+// ParseSignedTokenResponse will parse the provided response that carries a JWS
+// signed token response and verify it using the specified key.
+func ParseSignedTokenResponse(res *http.Response, limit int64, key []byte) (*TokenResponse, error) {
+	// read response
+	data, err := ioutil.ReadAll(io.LimitReader(res.Body, limit))
+	if err != nil {
+		return nil, err
+	}
+
+	// parse content type
+	contentType, _, err := mime.ParseMediaType(res.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, err
+	}
+
+	// check content type
+	if contentType != JWTContentType {
+		return nil, fmt.Errorf("unexpected content type: %q", contentType)
+	}
+
+	// verify and decode token response
+	var trs TokenResponse
+	err = VerifyJWS(key, string(data), &trs)
+	if err != nil {
+		return nil, err
+	}
+
+	return &trs, nil
+}
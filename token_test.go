@@ -1,6 +1,8 @@
 package oauth2
 
 import (
+	"bytes"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -9,6 +11,53 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+func newMultipartRequest(values map[string]string) *http.Request {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for key, value := range values {
+		_ = writer.WriteField(key, value)
+	}
+	_ = writer.Close()
+
+	req := httptest.NewRequest("POST", "/token", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	return req
+}
+
+func TestParseMultipartTokenRequest(t *testing.T) {
+	r := newMultipartRequest(map[string]string{
+		"grant_type":    PasswordGrantType,
+		"client_id":     "foo",
+		"client_secret": "bar",
+		"username":      "baz",
+		"password":      "qux",
+		"scope":         "foo bar",
+	})
+	assert.True(t, IsMultipartRequest(r))
+
+	req, err := ParseMultipartTokenRequest(r, 1024)
+	assert.NoError(t, err)
+	assert.Equal(t, &TokenRequest{
+		GrantType:    PasswordGrantType,
+		Scope:        Scope{"foo", "bar"},
+		ClientID:     "foo",
+		ClientSecret: "bar",
+		Username:     "baz",
+		Password:     "qux",
+		AuthMethod:   ClientSecretPost,
+	}, req)
+
+	r = newRequestWithAuth("foo", "", map[string]string{
+		"grant_type": PasswordGrantType,
+	})
+	assert.False(t, IsMultipartRequest(r))
+
+	req, err = ParseMultipartTokenRequest(r, 1024)
+	assert.Nil(t, req)
+	assert.EqualError(t, err, "invalid_request: expected multipart form data")
+}
+
 func TestParseTokenRequestMinimal(t *testing.T) {
 	r := newRequestWithAuth("foo", "", map[string]string{
 		"grant_type": PasswordGrantType,
@@ -136,6 +185,7 @@ func TestTokenResponseMap(t *testing.T) {
 	r.RefreshToken = "baz"
 	r.Scope = Scope{"qux"}
 	r.State = "quuz"
+	r.IssuedAt = 42
 
 	assert.Equal(t, map[string]string{
 		"token_type":    "foo",
@@ -144,6 +194,7 @@ func TestTokenResponseMap(t *testing.T) {
 		"refresh_token": "baz",
 		"scope":         "qux",
 		"state":         "quuz",
+		"issued_at":     "42",
 	}, r.Map())
 }
 
@@ -175,6 +226,98 @@ func TestRedirectTokenResponse(t *testing.T) {
 	)
 }
 
+func TestWriteSignedTokenResponse(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := NewTokenResponse("foo", "bar", 1)
+	r.Scope = Scope{"baz", "qux"}
+
+	err := WriteSignedTokenResponse(w, r, testSecret)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/jwt", w.Header().Get("Content-Type"))
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+
+	r2, err := ParseSignedTokenResponse(w.Result(), 2048, testSecret)
+	assert.NoError(t, err)
+	assert.Equal(t, r, r2)
+
+	w = httptest.NewRecorder()
+	err = WriteSignedTokenResponse(w, r, testSecret)
+	assert.NoError(t, err)
+
+	r2, err = ParseSignedTokenResponse(w.Result(), 2048, []byte("foo"))
+	assert.Error(t, err)
+	assert.Nil(t, r2)
+
+	w = httptest.NewRecorder()
+	err = WriteTokenResponse(w, r)
+	assert.NoError(t, err)
+
+	r2, err = ParseSignedTokenResponse(w.Result(), 2048, testSecret)
+	assert.Error(t, err)
+	assert.Nil(t, r2)
+
+	buf := NewResponseBuffer()
+	err = WriteSignedTokenResponseToSink(buf, r, testSecret)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, buf.Status)
+	assert.Equal(t, "application/jwt", buf.Header().Get("Content-Type"))
+
+	var r3 TokenResponse
+	assert.NoError(t, VerifyJWS(testSecret, buf.Body.String(), &r3))
+	assert.Equal(t, r, &r3)
+}
+
+func TestWriteFormTokenResponse(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := NewTokenResponse("foo", "bar", 1)
+	r.RefreshToken = "baz"
+	r.Scope = Scope{"baz", "qux"}
+	r.IssuedAt = 42
+
+	err := WriteFormTokenResponse(w, r)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/x-www-form-urlencoded", w.Header().Get("Content-Type"))
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	assert.Equal(t, "access_token=bar&expires_in=1&issued_at=42&refresh_token=baz&scope=baz+qux&token_type=foo", w.Body.String())
+
+	r2, err := ParseFormTokenResponse(w.Result(), 2048)
+	assert.NoError(t, err)
+	assert.Equal(t, r, r2)
+
+	buf := NewResponseBuffer()
+	err = WriteFormTokenResponseToSink(buf, r)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, buf.Status)
+	assert.Equal(t, "application/x-www-form-urlencoded", buf.Header().Get("Content-Type"))
+	assert.Equal(t, w.Body.String(), buf.Body.String())
+
+	w = httptest.NewRecorder()
+	err = WriteFormTokenResponse(w, r)
+	assert.NoError(t, err)
+
+	r2, err = ParseTokenResponse(w.Result(), 2048)
+	assert.NoError(t, err)
+	assert.Equal(t, r, r2)
+
+	w = httptest.NewRecorder()
+	err = WriteTokenResponse(w, r)
+	assert.NoError(t, err)
+
+	r2, err = ParseFormTokenResponse(w.Result(), 2048)
+	assert.Error(t, err)
+	assert.Nil(t, r2)
+
+	w = httptest.NewRecorder()
+	w.Header().Set("Content-Type", FormContentType)
+	_, _ = w.WriteString("access_token=bar&expires_in=foo")
+
+	r2, err = ParseFormTokenResponse(w.Result(), 2048)
+	assert.Error(t, err)
+	assert.Nil(t, r2)
+}
+
 func TestTokenRequestValues(t *testing.T) {
 	tr := TokenRequest{}
 	assert.Equal(t, url.Values{}, TokenRequestValues(tr))
@@ -189,6 +332,7 @@ func TestTokenRequestValues(t *testing.T) {
 		RefreshToken: "refresh-token",
 		RedirectURI:  "http://redirect.uri",
 		Code:         "code",
+		CodeVerifier: "code-verifier",
 	}
 	assert.Equal(t, url.Values{
 		"grant_type":    []string{"password"},
@@ -198,6 +342,7 @@ func TestTokenRequestValues(t *testing.T) {
 		"refresh_token": []string{"refresh-token"},
 		"redirect_uri":  []string{"http%3A%2F%2Fredirect.uri"},
 		"code":          []string{"code"},
+		"code_verifier": []string{"code-verifier"},
 	}, TokenRequestValues(tr))
 }
 
@@ -212,6 +357,8 @@ func TestTokenRequestBuild(t *testing.T) {
 		RefreshToken: "refresh-token",
 		RedirectURI:  "http://redirect.uri",
 		Code:         "code",
+		CodeVerifier: "code-verifier",
+		AuthMethod:   ClientSecretBasic,
 	}
 	req, err := BuildTokenRequest("http://auth.server/token", tr1)
 	assert.NoError(t, err)
@@ -221,3 +368,79 @@ func TestTokenRequestBuild(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, tr1, *tr2)
 }
+
+func TestTokenRequestBuildPreAuthorizedCode(t *testing.T) {
+	tr1 := TokenRequest{
+		GrantType:         PreAuthorizedCodeGrantType,
+		ClientID:          "client-id",
+		PreAuthorizedCode: "code",
+		TxCode:            "1234",
+		AuthMethod:        NoClientAuth,
+	}
+	req, err := BuildTokenRequest("http://auth.server/token", tr1)
+	assert.NoError(t, err)
+
+	tr2, err := ParseTokenRequest(req)
+	assert.NoError(t, err)
+	assert.Equal(t, tr1, *tr2)
+}
+
+func TestTokenRequestBuildAuthorizationDetails(t *testing.T) {
+	tr1 := TokenRequest{
+		GrantType:  ClientCredentialsGrantType,
+		ClientID:   "client-id",
+		AuthMethod: NoClientAuth,
+		AuthorizationDetails: []AuthorizationDetail{
+			{Type: "foo", Actions: []string{"read"}},
+		},
+	}
+	req, err := BuildTokenRequest("http://auth.server/token", tr1)
+	assert.NoError(t, err)
+
+	tr2, err := ParseTokenRequest(req)
+	assert.NoError(t, err)
+	assert.Equal(t, tr1, *tr2)
+}
+
+func TestTokenRequestBuildPost(t *testing.T) {
+	tr1 := TokenRequest{
+		GrantType:    "client_credentials",
+		ClientID:     "client-id",
+		ClientSecret: "client-secret",
+		AuthMethod:   ClientSecretPost,
+	}
+	req, err := BuildTokenRequest("http://auth.server/token", tr1)
+	assert.NoError(t, err)
+	assert.Empty(t, req.Header.Get("Authorization"))
+
+	tr2, err := ParseTokenRequest(req)
+	assert.NoError(t, err)
+	assert.Equal(t, tr1, *tr2)
+
+	tr1 = TokenRequest{
+		GrantType:  "refresh_token",
+		ClientID:   "client-id",
+		AuthMethod: NoClientAuth,
+	}
+	req, err = BuildTokenRequest("http://auth.server/token", tr1)
+	assert.NoError(t, err)
+	assert.Empty(t, req.Header.Get("Authorization"))
+
+	tr2, err = ParseTokenRequest(req)
+	assert.NoError(t, err)
+	assert.Equal(t, tr1, *tr2)
+
+	tr1 = TokenRequest{
+		GrantType:  "client_credentials",
+		ClientID:   "client-id",
+		AuthMethod: TLSClientAuth,
+	}
+	req, err = BuildTokenRequest("http://auth.server/token", tr1)
+	assert.NoError(t, err)
+	assert.Empty(t, req.Header.Get("Authorization"))
+
+	tr2, err = ParseTokenRequest(req)
+	assert.NoError(t, err)
+	assert.Equal(t, "client-id", tr2.ClientID)
+	assert.Equal(t, NoClientAuth, tr2.AuthMethod)
+}
@@ -1,8 +1,10 @@
 package oauth2
 
 import (
+	"encoding/json"
 	"net"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"strings"
 	"time"
@@ -62,3 +64,26 @@ func withServer(cb func(string, *Server)) {
 
 	time.Sleep(time.Millisecond)
 }
+
+func locationQuery(r *httptest.ResponseRecorder, key string) string {
+	u, err := url.Parse(r.Header().Get("Location"))
+	if err != nil {
+		panic(err)
+	}
+
+	return u.Query().Get(key)
+}
+
+func jsonFieldString(r *httptest.ResponseRecorder, field string) string {
+	var m map[string]interface{}
+	_ = json.Unmarshal(r.Body.Bytes(), &m)
+	str, _ := m[field].(string)
+	return str
+}
+
+func jsonFieldFloat(r *httptest.ResponseRecorder, field string) float64 {
+	var m map[string]interface{}
+	_ = json.Unmarshal(r.Body.Bytes(), &m)
+	num, _ := m[field].(float64)
+	return num
+}
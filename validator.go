@@ -0,0 +1,617 @@
+package oauth2
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	_ "crypto/sha512" // register hashes
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInvalidToken is matched by errors returned by validators and verifiers if
+// the token itself has been rejected. Other errors indicate that the token
+// could not be checked, e.g. because the issuer is unreachable.
+var ErrInvalidToken = errors.New("invalid token")
+
+type tokenError string
+
+func (e tokenError) Error() string {
+	return string(e)
+}
+
+func (e tokenError) Is(target error) bool {
+	return target == ErrInvalidToken
+}
+
+// ValidatorConfig is used to configure a validator.
+type ValidatorConfig struct {
+	// The issuer of the tokens. The OIDC discovery document is fetched from
+	// the well-known location below the issuer.
+	Issuer string
+
+	// The audience that must be present in the tokens.
+	Audience string
+
+	// The accepted signing algorithms. Defaults to RS256.
+	Algorithms []string
+
+	// The required type header of the tokens, e.g. "at+jwt" for JWT access
+	// tokens as defined by RFC 9068. The comparison is case-insensitive and
+	// ignores an "application/" prefix. If empty, the type is not checked.
+	Type string
+
+	// The tolerated clock skew when checking the expiry.
+	Leeway time.Duration
+
+	// The interval after which the keys are fetched again. Unknown key IDs
+	// trigger a refresh as well, but at most once per minimum interval. Failed
+	// refreshes are retried after the minimum interval while the current keys
+	// remain in use. Defaults to one hour and one minute.
+	RefreshInterval    time.Duration
+	MinRefreshInterval time.Duration
+
+	// The maximum size of fetched documents. Defaults to 1 MiB.
+	ResponseLimit int64
+}
+
+// Validator validates JWT access tokens issued by a third party authorization
+// server using the keys published through OIDC discovery and JWKS.
+type Validator struct {
+	config     ValidatorConfig
+	client     *http.Client
+	jwks       []JSONWebKey
+	keys       map[string]crypto.PublicKey
+	fetched    time.Time
+	retryAt    time.Time
+	refreshing *validatorRefresh
+	cache      map[string]cachedDocument
+	mutex      sync.Mutex
+}
+
+type validatorRefresh struct {
+	done chan struct{}
+	err  error
+}
+
+type cachedDocument struct {
+	etag string
+	data []byte
+}
+
+// NewValidator creates and returns a new validator.
+func NewValidator(config ValidatorConfig) *Validator {
+	return NewValidatorWithClient(config, new(http.Client))
+}
+
+// NewValidatorWithClient creates and returns a new validator using the
+// provided client.
+func NewValidatorWithClient(config ValidatorConfig, client *http.Client) *Validator {
+	// set default algorithms
+	if len(config.Algorithms) == 0 {
+		config.Algorithms = []string{"RS256"}
+	}
+
+	// set default intervals
+	if config.RefreshInterval == 0 {
+		config.RefreshInterval = time.Hour
+	}
+	if config.MinRefreshInterval == 0 {
+		config.MinRefreshInterval = time.Minute
+	}
+
+	// set default response limit
+	if config.ResponseLimit == 0 {
+		config.ResponseLimit = 1 << 20
+	}
+
+	return &Validator{
+		config: config,
+		client: client,
+	}
+}
+
+// Validate will verify the signature of the specified token and validate the
+// issuer, audience, expiry and not before claims. The claims are returned if
+// the token is valid.
+func (v *Validator) Validate(ctx context.Context, token string) (Claims, error) {
+	// split segments
+	s := strings.Split(token, ".")
+	if len(s) != 3 {
+		return nil, tokenError("a JWT must have three segments separated by a dot")
+	}
+
+	// decode header
+	data, err := b64.DecodeString(s[0])
+	if err != nil {
+		return nil, tokenError("JWT header is not base64 encoded")
+	}
+
+	// parse header
+	var header jwsHeader
+	err = json.Unmarshal(data, &header)
+	if err != nil {
+		return nil, tokenError("JWT header is not valid json")
+	}
+
+	// check algorithm
+	if !v.allowed(header.Algorithm) {
+		return nil, tokenError("JWT algorithm not allowed")
+	}
+
+	// check type
+	if v.config.Type != "" && normalizeType(header.Type) != normalizeType(v.config.Type) {
+		return nil, tokenError("JWT type mismatch")
+	}
+
+	// get key
+	key, err := v.key(ctx, header.KeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	// decode signature
+	signature, err := b64.DecodeString(s[2])
+	if err != nil {
+		return nil, tokenError("JWT signature is not base64 encoded")
+	}
+
+	// verify signature
+	err = verifySignature(header.Algorithm, key, []byte(s[0]+"."+s[1]), signature)
+	if err != nil {
+		return nil, err
+	}
+
+	// decode payload
+	payload, err := b64.DecodeString(s[1])
+	if err != nil {
+		return nil, tokenError("JWT payload is not base64 encoded")
+	}
+
+	// parse claims
+	var claims Claims
+	err = json.Unmarshal(payload, &claims)
+	if err != nil {
+		return nil, tokenError("JWT payload is not valid json")
+	}
+
+	// validate claims
+	err = v.validateClaims(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// Authenticate will validate the bearer token of the request and require the
+// specified scope. An error has already been written to the client if false is
+// returned.
+func (v *Validator) Authenticate(w http.ResponseWriter, r *http.Request, required Scope) (Claims, bool) {
+	// parse bearer token
+	token, err := ParseBearerToken(r)
+	if err != nil {
+		_ = WriteBearerError(w, err)
+		return nil, false
+	}
+
+	// validate token
+	claims, err := v.Validate(r.Context(), token)
+	if errors.Is(err, ErrInvalidToken) {
+		_ = WriteBearerError(w, InvalidToken(err.Error()))
+		return nil, false
+	} else if err != nil {
+		_ = WriteBearerError(w, ServerError("").SetCause(err))
+		return nil, false
+	}
+
+	// validate scope
+	if !claims.GetScope().Includes(required) {
+		_ = WriteBearerError(w, InsufficientScope(required.String()))
+		return nil, false
+	}
+
+	return claims, true
+}
+
+func (v *Validator) allowed(alg string) bool {
+	for _, item := range v.config.Algorithms {
+		if item == alg {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (v *Validator) validateClaims(claims Claims) error {
+	// get time
+	now := time.Now()
+
+	// check issuer
+	if claims.GetString("iss") != v.config.Issuer {
+		return tokenError("JWT issuer mismatch")
+	}
+
+	// check audience
+	if v.config.Audience != "" && !claims.GetAudience().Contains(v.config.Audience) {
+		return tokenError("JWT audience mismatch")
+	}
+
+	// check expiry
+	exp := claims.GetTime("exp")
+	if exp.IsZero() || !exp.Add(v.config.Leeway).After(now) {
+		return tokenError("JWT expired")
+	}
+
+	// check not before
+	nbf := claims.GetTime("nbf")
+	if !nbf.IsZero() && nbf.Add(-v.config.Leeway).After(now) {
+		return tokenError("JWT not yet valid")
+	}
+
+	return nil
+}
+
+func normalizeType(typ string) string {
+	return strings.TrimPrefix(strings.ToLower(typ), "application/")
+}
+
+func (v *Validator) key(ctx context.Context, id string) (crypto.PublicKey, error) {
+	// get time
+	now := time.Now()
+
+	// check keys
+	v.mutex.Lock()
+	missing := v.keys == nil
+	stale := now.Sub(v.fetched) >= v.config.RefreshInterval && !now.Before(v.retryAt)
+	v.mutex.Unlock()
+
+	// refresh keys if missing or stale, current keys are kept on errors
+	if missing || stale {
+		err := v.refresh(ctx, now)
+		if err != nil && missing {
+			return nil, err
+		}
+	}
+
+	// get key
+	v.mutex.Lock()
+	key, ok := v.keys[id]
+	recheck := now.Sub(v.fetched) >= v.config.MinRefreshInterval && !now.Before(v.retryAt)
+	v.mutex.Unlock()
+	if ok {
+		return key, nil
+	}
+
+	// refresh keys to pick up rotated keys
+	if recheck {
+		err := v.refresh(ctx, now)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// get key again
+	v.mutex.Lock()
+	key, ok = v.keys[id]
+	v.mutex.Unlock()
+	if !ok {
+		return nil, tokenError("unknown JWT key")
+	}
+
+	return key, nil
+}
+
+// refresh will fetch the keys without holding the mutex. Concurrent callers
+// await the result of the running refresh instead of fetching again. Failed
+// refreshes are retried after the minimum refresh interval.
+func (v *Validator) refresh(ctx context.Context, now time.Time) error {
+	// acquire mutex
+	v.mutex.Lock()
+
+	// await running refresh
+	if running := v.refreshing; running != nil {
+		v.mutex.Unlock()
+		select {
+		case <-running.done:
+			return running.err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	// start refresh
+	running := &validatorRefresh{done: make(chan struct{})}
+	v.refreshing = running
+	v.mutex.Unlock()
+
+	// fetch keys
+	jwks, keys, err := v.fetchKeys(ctx)
+
+	// acquire mutex
+	v.mutex.Lock()
+
+	// set keys or schedule retry
+	if err == nil {
+		v.jwks, v.keys = jwks, keys
+		v.fetched = now
+		v.retryAt = time.Time{}
+	} else {
+		v.retryAt = now.Add(v.config.MinRefreshInterval)
+	}
+
+	// finish refresh
+	v.refreshing = nil
+	running.err = err
+	close(running.done)
+
+	// release mutex
+	v.mutex.Unlock()
+
+	return err
+}
+
+func (v *Validator) fetchKeys(ctx context.Context) ([]JSONWebKey, map[string]crypto.PublicKey, error) {
+	// fetch discovery document
+	var discovery struct {
+		Issuer  string `json:"issuer"`
+		JWKSURI string `json:"jwks_uri"`
+	}
+	err := v.fetch(ctx, strings.TrimSuffix(v.config.Issuer, "/")+"/.well-known/openid-configuration", &discovery)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// check discovery document
+	if discovery.Issuer != v.config.Issuer {
+		return nil, nil, errors.New("discovery issuer mismatch")
+	} else if discovery.JWKSURI == "" {
+		return nil, nil, errors.New("discovery is missing JWKS URI")
+	}
+
+	// fetch key set
+	var set struct {
+		Keys []JSONWebKey `json:"keys"`
+	}
+	err = v.fetch(ctx, discovery.JWKSURI, &set)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// parse keys
+	jwks, keys := parseKeySet(set.Keys)
+
+	return jwks, keys, nil
+}
+
+func parseKeySet(set []JSONWebKey) ([]JSONWebKey, map[string]crypto.PublicKey) {
+	// parse keys
+	var jwks []JSONWebKey
+	keys := map[string]crypto.PublicKey{}
+	for _, jwk := range set {
+		// skip keys not used for signatures
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		// parse key, unsupported keys are skipped
+		key, err := jwk.publicKey()
+		if err != nil {
+			continue
+		}
+
+		jwks = append(jwks, jwk)
+		keys[jwk.KeyID] = key
+	}
+
+	return jwks, keys
+}
+
+func (v *Validator) fetch(ctx context.Context, uri string, obj interface{}) error {
+	// create request
+	req, err := http.NewRequest("GET", uri, nil)
+	if err != nil {
+		return err
+	}
+
+	// make request conditional if cached
+	cached, ok := v.cache[uri]
+	if ok {
+		req.Header.Set("If-None-Match", cached.etag)
+	}
+
+	// perform request
+	res, err := v.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+
+	// ensure body is closed
+	defer res.Body.Close()
+
+	// use cached document if not modified
+	if ok && res.StatusCode == http.StatusNotModified {
+		return json.Unmarshal(cached.data, obj)
+	}
+
+	// check status
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", res.StatusCode, uri)
+	}
+
+	// read body
+	data, err := ioutil.ReadAll(io.LimitReader(res.Body, v.config.ResponseLimit))
+	if err != nil {
+		return err
+	}
+
+	// cache document if it carries an etag
+	if etag := res.Header.Get("ETag"); etag != "" {
+		if v.cache == nil {
+			v.cache = map[string]cachedDocument{}
+		}
+		v.cache[uri] = cachedDocument{etag: etag, data: data}
+	}
+
+	return json.Unmarshal(data, obj)
+}
+
+// JSONWebKey is a public key in the JSON Web Key format.
+type JSONWebKey struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Use     string `json:"use"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+	Curve   string `json:"crv"`
+	X       string `json:"x"`
+	Y       string `json:"y"`
+}
+
+func (k JSONWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.KeyType {
+	case "RSA":
+		// decode modulus and exponent
+		n, err := b64.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := b64.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+
+		// check exponent, it must fit in an int on all platforms
+		exponent := new(big.Int).SetBytes(e)
+		if exponent.BitLen() > 31 || exponent.Int64() < 2 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(exponent.Int64()),
+		}, nil
+	case "EC":
+		// get curve
+		var curve elliptic.Curve
+		switch k.Curve {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, errors.New("unsupported curve")
+		}
+
+		// decode coordinates
+		x, err := b64.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := b64.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		// check point
+		pub := &ecdsa.PublicKey{
+			Curve: curve,
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+		if !curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, errors.New("invalid EC point")
+		}
+
+		return pub, nil
+	}
+
+	return nil, errors.New("unsupported key type")
+}
+
+func verifySignature(alg string, key crypto.PublicKey, input, signature []byte) error {
+	// check algorithm
+	if len(alg) != 5 {
+		return tokenError("unsupported JWT algorithm")
+	}
+
+	// get hash and curve
+	var hash crypto.Hash
+	var curve string
+	switch alg[2:] {
+	case "256":
+		hash = crypto.SHA256
+		curve = "P-256"
+	case "384":
+		hash = crypto.SHA384
+		curve = "P-384"
+	case "512":
+		hash = crypto.SHA512
+		curve = "P-521"
+	default:
+		return tokenError("unsupported JWT algorithm")
+	}
+
+	// compute digest
+	h := hash.New()
+	_, _ = h.Write(input)
+	digest := h.Sum(nil)
+
+	switch alg[:2] {
+	case "RS":
+		// check key
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return tokenError("JWT key type mismatch")
+		}
+
+		// verify signature
+		err := rsa.VerifyPKCS1v15(rsaKey, hash, digest, signature)
+		if err != nil {
+			return tokenError("invalid JWT signature")
+		}
+
+		return nil
+	case "ES":
+		// check key
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return tokenError("JWT key type mismatch")
+		}
+
+		// check curve
+		if ecKey.Curve.Params().Name != curve {
+			return tokenError("JWT key curve mismatch")
+		}
+
+		// check signature length
+		size := (ecKey.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return tokenError("invalid JWT signature")
+		}
+
+		// verify signature
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(ecKey, digest, r, s) {
+			return tokenError("invalid JWT signature")
+		}
+
+		return nil
+	}
+
+	return tokenError("unsupported JWT algorithm")
+}
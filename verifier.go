@@ -0,0 +1,198 @@
+package oauth2
+
+import (
+	"crypto/sha256"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// IntrospectionVerifierConfig is used to configure an introspection verifier.
+type IntrospectionVerifierConfig struct {
+	// The endpoints of the authorization server.
+	ClientConfig
+
+	// The credentials used to authenticate with the introspection endpoint.
+	ClientID     string
+	ClientSecret string
+	AuthMethod   ClientAuthMethod
+
+	// The duration for which active tokens are cached. The duration is capped
+	// at the expiry of the token. If zero, active tokens are not cached.
+	CacheTTL time.Duration
+
+	// The duration for which inactive tokens are cached. If zero, inactive
+	// tokens are not cached.
+	NegativeCacheTTL time.Duration
+
+	// The maximum number of cached tokens. Defaults to 1024.
+	MaxEntries int
+
+	// The clock used to check expiries. Defaults to the system clock.
+	Clock Clock
+}
+
+// IntrospectionVerifier verifies bearer tokens by calling the introspection
+// endpoint of a remote authorization server (RFC 7662). Unlike the Validator
+// it also supports opaque tokens and does not require a shared secret.
+// Responses are cached as configured to reduce the number of requests.
+type IntrospectionVerifier struct {
+	config IntrospectionVerifierConfig
+	client *Client
+	cache  map[[32]byte]cachedIntrospection
+	mutex  sync.Mutex
+}
+
+type cachedIntrospection struct {
+	claims  Claims
+	expires time.Time
+}
+
+// NewIntrospectionVerifier creates and returns a new introspection verifier.
+func NewIntrospectionVerifier(config IntrospectionVerifierConfig) *IntrospectionVerifier {
+	return NewIntrospectionVerifierWithClient(config, new(http.Client))
+}
+
+// NewIntrospectionVerifierWithClient creates and returns a new introspection
+// verifier using the provided client.
+func NewIntrospectionVerifierWithClient(config IntrospectionVerifierConfig, client *http.Client) *IntrospectionVerifier {
+	// set default max entries
+	if config.MaxEntries == 0 {
+		config.MaxEntries = 1024
+	}
+
+	return &IntrospectionVerifier{
+		config: config,
+		client: NewClientWithClient(config.ClientConfig, client),
+		cache:  map[[32]byte]cachedIntrospection{},
+	}
+}
+
+// Verify will introspect the specified token and return its claims if the
+// token is active. Cached responses are used if available.
+func (v *IntrospectionVerifier) Verify(token string) (Claims, error) {
+	// get key and time
+	key := sha256.Sum256([]byte(token))
+	now := v.now()
+
+	// check cache
+	v.mutex.Lock()
+	entry, ok := v.cache[key]
+	v.mutex.Unlock()
+	if ok && now.Before(entry.expires) {
+		if entry.claims == nil {
+			return nil, tokenError("inactive token")
+		}
+		return entry.claims, nil
+	}
+
+	// introspect token
+	irs, err := v.client.Introspect(IntrospectionRequest{
+		Token:         token,
+		TokenTypeHint: AccessToken,
+		ClientID:      v.config.ClientID,
+		ClientSecret:  v.config.ClientSecret,
+		AuthMethod:    v.config.AuthMethod,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// check response
+	active := irs.Active && irs.TokenType != RefreshToken
+	if active && irs.ExpiresAt != 0 && !now.Before(time.Unix(irs.ExpiresAt, 0)) {
+		active = false
+	}
+
+	// handle inactive token
+	if !active {
+		v.store(key, nil, now.Add(v.config.NegativeCacheTTL), now)
+		return nil, tokenError("inactive token")
+	}
+
+	// get claims
+	claims := irs.Claims()
+
+	// cache claims until the token expires
+	expires := now.Add(v.config.CacheTTL)
+	if irs.ExpiresAt != 0 && time.Unix(irs.ExpiresAt, 0).Before(expires) {
+		expires = time.Unix(irs.ExpiresAt, 0)
+	}
+	v.store(key, claims, expires, now)
+
+	return claims, nil
+}
+
+// Authenticate will verify the bearer token of the request and require the
+// specified scope. An error has already been written to the client if false is
+// returned.
+func (v *IntrospectionVerifier) Authenticate(w http.ResponseWriter, r *http.Request, required Scope) (Claims, bool) {
+	// parse bearer token
+	token, err := ParseBearerToken(r)
+	if err != nil {
+		_ = WriteBearerError(w, err)
+		return nil, false
+	}
+
+	// verify token
+	claims, err := v.Verify(token)
+	if errors.Is(err, ErrInvalidToken) {
+		_ = WriteBearerError(w, InvalidToken(err.Error()))
+		return nil, false
+	} else if err != nil {
+		_ = WriteBearerError(w, ServerError("").SetCause(err))
+		return nil, false
+	}
+
+	// validate scope
+	if !claims.GetScope().Includes(required) {
+		_ = WriteBearerError(w, InsufficientScope(required.String()))
+		return nil, false
+	}
+
+	return claims, true
+}
+
+func (v *IntrospectionVerifier) store(key [32]byte, claims Claims, expires, now time.Time) {
+	// check expiry
+	if !now.Before(expires) {
+		return
+	}
+
+	// acquire mutex
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	// remove expired entries if full
+	if len(v.cache) >= v.config.MaxEntries {
+		for k, entry := range v.cache {
+			if !now.Before(entry.expires) {
+				delete(v.cache, k)
+			}
+		}
+	}
+
+	// remove arbitrary entries if still full
+	for k := range v.cache {
+		if len(v.cache) < v.config.MaxEntries {
+			break
+		}
+		delete(v.cache, k)
+	}
+
+	// add entry
+	v.cache[key] = cachedIntrospection{
+		claims:  claims,
+		expires: expires,
+	}
+}
+
+func (v *IntrospectionVerifier) now() time.Time {
+	// use clock if available
+	if v.config.Clock != nil {
+		return v.config.Clock.Now()
+	}
+
+	return time.Now()
+}
@@ -0,0 +1,148 @@
+package oauth2
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIntrospectionVerifier(t *testing.T) {
+	server := newTestServer()
+
+	var requests int
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		server.ServeHTTP(w, r)
+	}))
+	defer api.Close()
+
+	now := time.Now()
+
+	verifier := NewIntrospectionVerifier(IntrospectionVerifierConfig{
+		ClientConfig:     Default(api.URL),
+		ClientID:         "client1",
+		ClientSecret:     "foo",
+		CacheTTL:         time.Minute,
+		NegativeCacheTTL: time.Second,
+		Clock: ClockFunc(func() time.Time {
+			return now
+		}),
+	})
+
+	res, err := server.issueTokens(true, Scope{"foo"}, "client1", "user1", "")
+	assert.NoError(t, err)
+	other, err := NewServer(server.Config).issueTokens(false, Scope{"foo"}, "client1", "user1", "")
+	assert.NoError(t, err)
+	unknown := other.AccessToken
+
+	claims, err := verifier.Verify(res.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, "client1", claims.GetString("client_id"))
+	assert.Equal(t, "user1", claims.GetString("sub"))
+	assert.Equal(t, Scope{"foo"}, claims.GetScope())
+	assert.Equal(t, 1, requests)
+
+	claims, err = verifier.Verify(res.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, "client1", claims.GetString("client_id"))
+	assert.Equal(t, 1, requests)
+
+	now = now.Add(2 * time.Minute)
+
+	_, err = verifier.Verify(res.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, requests)
+
+	_, err = verifier.Verify(res.RefreshToken)
+	assert.EqualError(t, err, "inactive token")
+	assert.Equal(t, 3, requests)
+
+	_, err = verifier.Verify(unknown)
+	assert.EqualError(t, err, "inactive token")
+	assert.Equal(t, 4, requests)
+
+	_, err = verifier.Verify(unknown)
+	assert.EqualError(t, err, "inactive token")
+	assert.Equal(t, 4, requests)
+
+	now = now.Add(2 * time.Second)
+
+	_, err = verifier.Verify(unknown)
+	assert.EqualError(t, err, "inactive token")
+	assert.Equal(t, 5, requests)
+
+	req := httptest.NewRequest("GET", "/api", nil)
+	req.Header.Set("Authorization", "Bearer "+res.AccessToken)
+
+	rec := httptest.NewRecorder()
+	claims, ok := verifier.Authenticate(rec, req, Scope{"foo"})
+	assert.True(t, ok)
+	assert.Equal(t, "user1", claims.GetString("sub"))
+
+	rec = httptest.NewRecorder()
+	_, ok = verifier.Authenticate(rec, req, Scope{"bar"})
+	assert.False(t, ok)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	req.Header.Set("Authorization", "Bearer "+unknown)
+
+	rec = httptest.NewRecorder()
+	_, ok = verifier.Authenticate(rec, req, nil)
+	assert.False(t, ok)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	api.Close()
+	now = now.Add(2 * time.Minute)
+
+	_, err = verifier.Verify(res.AccessToken)
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, ErrInvalidToken))
+
+	req.Header.Set("Authorization", "Bearer "+res.AccessToken)
+
+	rec = httptest.NewRecorder()
+	_, ok = verifier.Authenticate(rec, req, nil)
+	assert.False(t, ok)
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Empty(t, rec.Header().Get("WWW-Authenticate"))
+	assert.NotContains(t, rec.Body.String(), api.URL)
+}
+
+func TestIntrospectionVerifierMaxEntries(t *testing.T) {
+	verifier := NewIntrospectionVerifier(IntrospectionVerifierConfig{
+		MaxEntries: 2,
+	})
+
+	now := time.Now()
+	verifier.store([32]byte{1}, nil, now.Add(time.Minute), now)
+	verifier.store([32]byte{2}, nil, now.Add(time.Minute), now)
+	verifier.store([32]byte{3}, nil, now.Add(time.Minute), now)
+	assert.Len(t, verifier.cache, 2)
+
+	verifier.store([32]byte{4}, nil, now, now)
+	assert.Len(t, verifier.cache, 2)
+}
+
+func TestIntrospectionResponseClaims(t *testing.T) {
+	irs := &IntrospectionResponse{
+		Active:    true,
+		Scope:     "foo bar",
+		ClientID:  "client1",
+		Username:  "user1",
+		ExpiresAt: 42,
+		Audience:  Audience{"api"},
+		Extra:     Claims{"foo": "bar", "sub": "baz"},
+	}
+
+	claims := irs.Claims()
+	assert.Equal(t, "client1", claims.GetString("client_id"))
+	assert.Equal(t, "user1", claims.GetString("sub"))
+	assert.Equal(t, "bar", claims.GetString("foo"))
+	assert.Equal(t, Scope{"foo", "bar"}, claims.GetScope())
+	assert.Equal(t, int64(42), claims.GetInt64("exp"))
+	assert.Equal(t, Audience{"api"}, claims.GetAudience())
+}
@@ -0,0 +1,125 @@
+package oauth2
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WebhookSignatureHeader is the header that carries the signature of a webhook
+// request, e.g. a pushed security event.
+const WebhookSignatureHeader = "Webhook-Signature"
+
+// DefaultWebhookTolerance is the default maximum age of webhook signatures.
+const DefaultWebhookTolerance = 5 * time.Minute
+
+// SignWebhook will return the signature header value for the specified body
+// and time. The signature has the form "t=<timestamp>,v1=<signature>" where
+// the signature is the hex encoded HMAC-SHA256 of the timestamp and body
+// joined by a dot.
+func SignWebhook(secret, body []byte, now time.Time) string {
+	// get timestamp
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	return "t=" + timestamp + ",v1=" + webhookSignature(secret, timestamp, body)
+}
+
+// VerifyWebhook will verify the specified signature header value of a webhook
+// body. The signature must have been created with the secret within the
+// tolerance window around the current time. A zero tolerance defaults to
+// DefaultWebhookTolerance. Receivers should additionally discard duplicate
+// deliveries within the window, e.g. using the "jti" claim of security events.
+func VerifyWebhook(secret, body []byte, signature string, now time.Time, tolerance time.Duration) error {
+	// get tolerance
+	if tolerance == 0 {
+		tolerance = DefaultWebhookTolerance
+	}
+
+	// parse signature
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(signature, ",") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "t":
+			timestamp = kv[1]
+		case "v1":
+			signatures = append(signatures, kv[1])
+		}
+	}
+
+	// check timestamp
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.New("missing or invalid webhook timestamp")
+	}
+
+	// check signatures
+	if len(signatures) == 0 {
+		return errors.New("missing webhook signature")
+	}
+
+	// check tolerance
+	diff := now.Sub(time.Unix(unix, 0))
+	if diff > tolerance || diff < -tolerance {
+		return errors.New("webhook timestamp outside of tolerance")
+	}
+
+	// compute expected signature
+	expected := webhookSignature(secret, timestamp, body)
+
+	// compare signatures
+	for _, sig := range signatures {
+		if hmac.Equal([]byte(sig), []byte(expected)) {
+			return nil
+		}
+	}
+
+	return errors.New("invalid webhook signature")
+}
+
+// VerifyWebhookRequest will read the body of the specified request up to the
+// specified limit and verify it using the signature header (see
+// VerifyWebhook). The body is returned if it is valid.
+func VerifyWebhookRequest(r *http.Request, secret []byte, tolerance time.Duration, limit int64) ([]byte, error) {
+	// read body
+	body, err := ioutil.ReadAll(http.MaxBytesReader(nil, r.Body, limit))
+	if err != nil {
+		return nil, err
+	}
+
+	// verify signature
+	err = VerifyWebhook(secret, body, r.Header.Get(WebhookSignatureHeader), time.Now(), tolerance)
+	if err != nil {
+		return nil, err
+	}
+
+	return body, nil
+}
+
+// PushSignedSecurityEvent will deliver the specified security event token like
+// PushSecurityEvent and sign the request using the specified secret (see
+// SignWebhook).
+func PushSignedSecurityEvent(ctx context.Context, client *http.Client, uri, set string, secret []byte) error {
+	return pushSecurityEvent(ctx, client, uri, set, secret)
+}
+
+func webhookSignature(secret []byte, timestamp string, body []byte) string {
+	// compute hmac
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
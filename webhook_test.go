@@ -0,0 +1,77 @@
+package oauth2
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookSignature(t *testing.T) {
+	secret := []byte("secret")
+	body := []byte("foo")
+	now := time.Unix(1600000000, 0)
+
+	signature := SignWebhook(secret, body, now)
+	assert.True(t, strings.HasPrefix(signature, "t=1600000000,v1="))
+
+	err := VerifyWebhook(secret, body, signature, now, 0)
+	assert.NoError(t, err)
+
+	err = VerifyWebhook(secret, body, signature, now.Add(4*time.Minute), 0)
+	assert.NoError(t, err)
+
+	err = VerifyWebhook(secret, body, signature, now.Add(6*time.Minute), 0)
+	assert.EqualError(t, err, "webhook timestamp outside of tolerance")
+
+	err = VerifyWebhook(secret, body, signature, now.Add(-6*time.Minute), 0)
+	assert.EqualError(t, err, "webhook timestamp outside of tolerance")
+
+	err = VerifyWebhook(secret, body, signature, now.Add(time.Minute), time.Second)
+	assert.EqualError(t, err, "webhook timestamp outside of tolerance")
+
+	err = VerifyWebhook(secret, []byte("bar"), signature, now, 0)
+	assert.EqualError(t, err, "invalid webhook signature")
+
+	err = VerifyWebhook([]byte("other"), body, signature, now, 0)
+	assert.EqualError(t, err, "invalid webhook signature")
+
+	err = VerifyWebhook(secret, body, "t=1600000000,v1=foo,"+signature[strings.Index(signature, "v1="):], now, 0)
+	assert.NoError(t, err)
+
+	err = VerifyWebhook(secret, body, "v1=foo", now, 0)
+	assert.EqualError(t, err, "missing or invalid webhook timestamp")
+
+	err = VerifyWebhook(secret, body, "t=1600000000", now, 0)
+	assert.EqualError(t, err, "missing webhook signature")
+}
+
+func TestPushSignedSecurityEvent(t *testing.T) {
+	secret := []byte("secret")
+
+	var body []byte
+	var verifyErr error
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, verifyErr = VerifyWebhookRequest(r, secret, 0, 1024)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer receiver.Close()
+
+	err := PushSignedSecurityEvent(context.Background(), http.DefaultClient, receiver.URL, "foo.bar.baz", secret)
+	assert.NoError(t, err)
+	assert.NoError(t, verifyErr)
+	assert.Equal(t, "foo.bar.baz", string(body))
+
+	err = PushSecurityEvent(context.Background(), http.DefaultClient, receiver.URL, "foo.bar.baz")
+	assert.NoError(t, err)
+	assert.EqualError(t, verifyErr, "missing or invalid webhook timestamp")
+	assert.Nil(t, body)
+
+	err = PushSignedSecurityEvent(context.Background(), http.DefaultClient, receiver.URL, "foo.bar.baz", []byte("other"))
+	assert.NoError(t, err)
+	assert.EqualError(t, verifyErr, "invalid webhook signature")
+}